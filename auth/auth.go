@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/labstack/echo/v4"
 
@@ -31,6 +32,7 @@ var (
 	// different types of lookup tables
 	tokenToAuth map[string]*settings.DialSettings
 	idToAuth    map[string]*settings.DialSettings
+	authMu      sync.RWMutex // authorizations change at runtime, e.g. when a show is transferred
 )
 
 func init() {
@@ -39,11 +41,26 @@ func init() {
 }
 
 func RegisterAuthorization(cfg *settings.DialSettings) {
+	authMu.Lock()
+	defer authMu.Unlock()
+
 	tokenToAuth[cfg.Credentials.Token] = cfg
 	idToAuth[namedKey(cfg.Credentials.ProjectID, cfg.Credentials.UserID)] = cfg
 }
 
+// UnregisterAuthorization revokes the token of an authorization
+func UnregisterAuthorization(cfg *settings.DialSettings) {
+	authMu.Lock()
+	defer authMu.Unlock()
+
+	delete(tokenToAuth, cfg.Credentials.Token)
+	delete(idToAuth, namedKey(cfg.Credentials.ProjectID, cfg.Credentials.UserID))
+}
+
 func LookupAuthorization(ctx context.Context, realm, userid string) (*settings.DialSettings, error) {
+	authMu.RLock()
+	defer authMu.RUnlock()
+
 	if a, ok := idToAuth[namedKey(realm, userid)]; ok {
 		return a, nil
 	}
@@ -55,6 +72,10 @@ func FindAuthorizationByToken(ctx context.Context, token string) (*settings.Dial
 	if token == "" {
 		return nil, ErrNoToken
 	}
+
+	authMu.RLock()
+	defer authMu.RUnlock()
+
 	if a, ok := tokenToAuth[token]; ok {
 		return a, nil
 	}
@@ -85,6 +106,11 @@ func CheckAuthorization(ctx context.Context, c echo.Context, scope string) (*set
 	return auth, nil
 }
 
+// HasScope returns true if the authorization grants scope, or is an admin authorization
+func HasScope(auth *settings.DialSettings, scope string) bool {
+	return hasScope(auth.Scopes, ScopeAdmin) || hasScope(auth.Scopes, scope)
+}
+
 // GetClientID extracts the ClientID from the token
 func GetClientID(ctx context.Context, r *http.Request) (string, error) {
 	token, err := GetBearerToken(r)
@@ -1,9 +1,14 @@
 package auth
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/txsvc/stdlib/v2/settings"
 )
 
 const (
@@ -21,3 +26,24 @@ func TestScope(t *testing.T) {
 	assert.True(t, hasScope([]string{scopeProductionWrite, scopeProductionRead, scopeResourceRead}, scopeProductionRead+","+scopeProductionWrite))
 	assert.False(t, hasScope([]string{scopeProductionWrite, scopeProductionRead, scopeResourceRead}, scopeProductionRead+","+scopeResourceWrite))
 }
+
+func TestConcurrentAuthorizations(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			cfg := &settings.DialSettings{Credentials: &settings.Credentials{ProjectID: "podops", UserID: fmt.Sprintf("user%d", i), Token: fmt.Sprintf("token%d", i)}}
+			RegisterAuthorization(cfg)
+			UnregisterAuthorization(cfg)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := FindAuthorizationByToken(context.TODO(), fmt.Sprintf("token%d", i))
+			assert.NoError(t, err)
+			_, err = LookupAuthorization(context.TODO(), "podops", fmt.Sprintf("user%d", i))
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -1,28 +1,31 @@
 package client
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/txsvc/stdlib/v2/settings"
 
 	"github.com/podops/podops"
-	"github.com/podops/podops/config"
 	"github.com/podops/podops/internal/api"
-	"github.com/podops/podops/internal/builder"
+	"github.com/podops/podops/internal/cdn"
 	"github.com/podops/podops/internal/metadata"
+	"github.com/podops/podops/internal/notify"
 )
 
 const (
-	initRoute  = "/init"
-	assetRoute = "/asset"
+	initRoute    = "/init"
+	assetRoute   = "/asset"
+	buildRoute   = "/build"
+	webhookRoute = "/webhook"
+	notifyRoute  = "/notify"
 )
 
-func Init(userid, parent string) (*settings.DialSettings, error) {
+// Init creates a new show namespace on the CDN and returns the show's credentials.
+// If name is not empty, it is reserved as the show's canonical name.
+func (c *Client) Init(ctx context.Context, userid, parent, name string) (*settings.DialSettings, error) {
 	if userid == "" {
 		return nil, podops.ErrInvalidParameters
 	}
@@ -32,8 +35,10 @@ func Init(userid, parent string) (*settings.DialSettings, error) {
 
 	cfg := settings.DialSettings{}
 	cmd := fmt.Sprintf("%s%s/%s/%s", api.NamespacePrefix, initRoute, userid, parent)
-	_, err := Put(config.Settings().Endpoint, cmd, config.Settings().Credentials.Token, nil, &cfg)
-	if err != nil {
+	if name != "" {
+		cmd = fmt.Sprintf("%s?name=%s", cmd, url.QueryEscape(name))
+	}
+	if err := c.put(ctx, cmd, nil, &cfg); err != nil {
 		return nil, err
 	}
 
@@ -43,7 +48,7 @@ func Init(userid, parent string) (*settings.DialSettings, error) {
 // Upload moves a file from the local file system to the CDN. The file is placed in
 // a location specified by parent. The location has to exist beforehand otherwise the
 // API endpoint will return an error.
-func Upload(parent, path string) error {
+func (c *Client) Upload(ctx context.Context, parent, path string) error {
 	if parent == "" {
 		return podops.ErrInvalidGUID
 	}
@@ -51,144 +56,122 @@ func Upload(parent, path string) error {
 		return podops.ErrResourceNotFound
 	}
 
-	url := fmt.Sprintf("%s%s%s/%s", config.Settings().Endpoint, api.NamespacePrefix, assetRoute, parent)
-	req, err := UploadRequest(url, config.Settings().Credentials.Token, api.UploadFormName, path)
-	if err != nil {
-		return err
+	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, assetRoute, parent)
+	if err := c.upload(ctx, cmd, api.UploadFormName, path); err != nil {
+		return fmt.Errorf(podops.MsgResourceUploadError+": %w", path, err)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	return nil
+}
+
+// Delete removes a media resource from the CDN
+func (c *Client) Delete(ctx context.Context, parent, asset string) error {
+	if parent == "" {
+		return podops.ErrInvalidGUID
 	}
-	body := &bytes.Buffer{}
-	_, err = body.ReadFrom(resp.Body)
+
+	cmd := fmt.Sprintf("%s%s/%s/%s", api.NamespacePrefix, assetRoute, parent, asset)
+	return c.del(ctx, cmd)
+}
+
+// List returns a list of media resources on the remote content endpoint.
+func (c *Client) List(ctx context.Context, parent string) ([]metadata.Metadata, error) {
+	list, err := c.Assets(ctx, parent)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	resp.Body.Close()
+	return list.Assets, nil
+}
+
+// Assets returns the media resources on the remote content endpoint together with the show's quota and usage.
+func (c *Client) Assets(ctx context.Context, parent string) (*api.AssetList, error) {
+	var list api.AssetList
 
-	if resp.StatusCode > http.StatusNoContent {
-		return fmt.Errorf(podops.MsgResourceUploadError, fmt.Sprintf("%s:%s", path, resp.Status))
+	if parent == "" {
+		return nil, podops.ErrInvalidGUID
 	}
 
-	return nil
+	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, assetRoute, parent)
+	if err := c.get(ctx, cmd, &list); err != nil {
+		return nil, err
+	}
+
+	return &list, nil
 }
 
-func Delete(parent, asset string) error {
+// Builds returns the recent server-side builds of a show, latest first
+func (c *Client) Builds(ctx context.Context, parent string) ([]cdn.BuildStatus, error) {
+	var builds []cdn.BuildStatus
+
 	if parent == "" {
-		return podops.ErrInvalidGUID
+		return nil, podops.ErrInvalidGUID
 	}
-	cmd := fmt.Sprintf("%s%s/%s/%s", api.NamespacePrefix, assetRoute, parent, asset)
-	_, err := Del(config.Settings().Endpoint, cmd, config.Settings().Credentials.Token, nil)
-	if err != nil {
-		return err
+
+	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, buildRoute, parent)
+	if err := c.get(ctx, cmd, &builds); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return builds, nil
 }
 
-// List returns a list of media resources on the remote content endpoint.
-func List(parent string) (*[]metadata.Metadata, error) {
-	var rsrc []metadata.Metadata
+// Build returns the status and log of a server-side build
+func (c *Client) Build(ctx context.Context, parent, id string) (*cdn.BuildStatus, error) {
+	var build cdn.BuildStatus
 
 	if parent == "" {
 		return nil, podops.ErrInvalidGUID
 	}
+	if id == "" {
+		return nil, podops.ErrInvalidParameters
+	}
 
-	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, assetRoute, parent)
-
-	_, err := Get(config.Settings().Endpoint, cmd, config.Settings().Credentials.Token, &rsrc)
-	if err != nil {
+	cmd := fmt.Sprintf("%s%s/%s/%s", api.NamespacePrefix, buildRoute, parent, id)
+	if err := c.get(ctx, cmd, &build); err != nil {
 		return nil, err
 	}
 
-	return &rsrc, nil
+	return &build, nil
 }
 
-// Sync synchronizes a local repository against the remote content endpoint. All local resources that
-// are missing on the remote site will be uploaded, already existing ones are ignored.
-// Only media files (e.g. .mp3, .png) are synchronized.
-func Sync(parent, root string, purge bool) error {
+// RotateWebhookSecret creates a new secret used to verify the show's webhook payloads.
+// The old secret becomes invalid immediately.
+func (c *Client) RotateWebhookSecret(ctx context.Context, parent string) (string, error) {
 	if parent == "" {
-		return podops.ErrInvalidGUID
+		return "", podops.ErrInvalidGUID
 	}
 
-	// check that the cache dir exists
-	assetPath := filepath.Join(root, config.BuildLocation)
-	if _, err := os.Stat(assetPath); os.IsNotExist(err) {
-		return podops.ErrResourceNotFound
+	secret := api.WebhookSecret{}
+	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, webhookRoute, parent)
+	if err := c.put(ctx, cmd, nil, &secret); err != nil {
+		return "", err
 	}
 
-	// get a list of resources and build a look-up table
-	r, err := List(parent)
-	if err != nil {
-		return err
-	}
-
-	rsrc := make(map[string]metadata.Metadata)
-	for _, m := range *r {
-		rsrc[m.ETag] = m
-	}
-
-	// now iterate over all the resource definitions and upload missing ones
-	err = filepath.Walk(assetPath, func(path string, info os.FileInfo, e error) error {
-		fi, err := os.Stat(path)
-		if err != nil {
-			return err
-		}
-		if fi.IsDir() {
-			return nil
-		}
-
-		ext := filepath.Ext(path)
-		if ext != ".yaml" {
-			return nil // skip e.g feed.xml
-		}
-
-		ar, err := builder.LoadAssetRef(path)
-		if err != nil {
-			return err
-		}
-
-		_, ok := rsrc[ar.ETag]
-		if !ok {
-			parts := strings.Split(ar.URI, ".")
-			if len(parts) < 2 {
-				return podops.ErrInvalidResourceName
-			}
-			assetFileName := fmt.Sprintf("%s.%s", ar.ETag, parts[len(parts)-1])
-			assetPath := filepath.Join(root, config.BuildLocation, assetFileName)
-
-			return Upload(parent, assetPath)
-		} else {
-			// remove the processed asset from the map, for purgeing assets later
-			delete(rsrc, ar.ETag)
-		}
-
-		return nil
-	})
+	return secret.Secret, nil
+}
 
-	if err != nil {
-		return err
+// Subscriptions returns the webhooks that are called after publishing new episodes
+func (c *Client) Subscriptions(ctx context.Context, parent string) ([]notify.Subscription, error) {
+	var subs []notify.Subscription
+
+	if parent == "" {
+		return nil, podops.ErrInvalidGUID
 	}
 
-	// upload feed.xml last
-	feedFilePath := filepath.Join(root, config.BuildLocation, config.DefaultFeedName)
-	err = Upload(parent, feedFilePath)
-	if err != nil {
-		return err
+	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, notifyRoute, parent)
+	if err := c.get(ctx, cmd, &subs); err != nil {
+		return nil, err
 	}
 
-	// purge obsolete assets
-	if purge {
-		for _, r := range rsrc {
-			if err := Delete(parent, r.Name); err != nil {
-				return err
-			}
-		}
+	return subs, nil
+}
+
+// UpdateSubscriptions replaces the webhooks that are called after publishing new episodes
+func (c *Client) UpdateSubscriptions(ctx context.Context, parent string, subs []notify.Subscription) error {
+	if parent == "" {
+		return podops.ErrInvalidGUID
 	}
 
-	return nil
+	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, notifyRoute, parent)
+	return c.put(ctx, cmd, subs, nil)
 }
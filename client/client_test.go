@@ -1,11 +1,15 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/podops/podops"
 	"github.com/podops/podops/config"
 	"github.com/stretchr/testify/assert"
 )
@@ -39,7 +43,7 @@ func TestInit(t *testing.T) {
 	assert.NotEmpty(t, config.Settings().Endpoint)
 	assert.Equal(t, "http://localhost:8080", config.Settings().Endpoint)
 
-	tmp, err := Init(cfg.Credentials.UserID, guid)
+	tmp, err := New(cfg).Init(context.TODO(), cfg.Credentials.UserID, guid, "")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, tmp)
@@ -50,59 +54,81 @@ func TestInit(t *testing.T) {
 }
 
 func TestUpload(t *testing.T) {
-	config.UpdateClientSettings(tmpCredentialLocation)
+	c := New(config.UpdateClientSettings(tmpCredentialLocation))
 
-	err := Upload(guid, testMP3FilePath)
+	err := c.Upload(context.TODO(), guid, testMP3FilePath)
 	assert.NoError(t, err)
 
-	err = Upload(guid, testPNGFilePath)
+	err = c.Upload(context.TODO(), guid, testPNGFilePath)
 	assert.NoError(t, err)
 }
 
 func TestFailedUpload(t *testing.T) {
-	config.UpdateClientSettings(tmpCredentialLocation)
+	c := New(config.UpdateClientSettings(tmpCredentialLocation))
 
-	err := Upload(invalidGuid, testMP3FilePath)
+	err := c.Upload(context.TODO(), invalidGuid, testMP3FilePath)
 	assert.Error(t, err)
 }
 
 func TestList(t *testing.T) {
-	config.UpdateClientSettings(tmpCredentialLocation)
+	c := New(config.UpdateClientSettings(tmpCredentialLocation))
 
-	metadata, err := List(guid)
+	metadata, err := c.List(context.TODO(), guid)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, metadata)
-	assert.Equal(t, 2, len(*metadata))
+	assert.Equal(t, 2, len(metadata))
 }
 
 func TestDelete(t *testing.T) {
-	config.UpdateClientSettings(tmpCredentialLocation)
+	c := New(config.UpdateClientSettings(tmpCredentialLocation))
 
-	err := Delete(guid, testPNGFile)
+	err := c.Delete(context.TODO(), guid, testPNGFile)
 	assert.NoError(t, err)
 
-	metadata, err := List(guid)
+	metadata, err := c.List(context.TODO(), guid)
 	assert.NoError(t, err)
 	assert.NotNil(t, metadata)
-	assert.Equal(t, 1, len(*metadata))
+	assert.Equal(t, 1, len(metadata))
 }
 
 func TestSync(t *testing.T) {
-	config.UpdateClientSettings(tmpCredentialLocation)
+	c := New(config.UpdateClientSettings(tmpCredentialLocation))
 
-	err := Sync(guid, rootDir, false)
+	err := c.Sync(context.TODO(), guid, rootDir, false)
 	assert.NoError(t, err)
 }
 
+func TestPlan(t *testing.T) {
+	c := New(config.UpdateClientSettings(tmpCredentialLocation))
+
+	plan, err := c.Plan(context.TODO(), guid, rootDir, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, plan)
+	assert.Empty(t, plan.Uploads)
+	assert.Empty(t, plan.Deletions)
+	assert.NotEmpty(t, plan.Skips)
+	assert.Equal(t, config.DefaultFeedName, plan.Feed.Name)
+}
+
 func TestSyncWithPurge(t *testing.T) {
-	config.UpdateClientSettings(tmpCredentialLocation)
+	c := New(config.UpdateClientSettings(tmpCredentialLocation))
 
-	err := Sync(guid, rootDir, true)
+	err := c.Sync(context.TODO(), guid, rootDir, true)
 	assert.NoError(t, err)
 
-	metadata, err := List(guid)
+	metadata, err := c.List(context.TODO(), guid)
 	assert.NoError(t, err)
 	assert.NotNil(t, metadata)
-	assert.Equal(t, 5, len(*metadata))
+	assert.Equal(t, 5, len(metadata))
+}
+
+func TestError(t *testing.T) {
+	err := newError(http.StatusBadRequest, podops.ErrInvalidGUID.Error())
+	assert.True(t, errors.Is(err, podops.ErrInvalidGUID))
+	assert.Equal(t, http.StatusBadRequest, err.Status)
+
+	err = newError(http.StatusBadGateway, "")
+	assert.True(t, errors.Is(err, podops.ErrApiError))
+	assert.Equal(t, "status: 502", err.Error())
 }
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/auth"
+)
+
+type (
+	// Error is returned by API calls that fail with a HTTP status other than 2xx.
+	// It wraps the matching podops error, if the API returned a known error message,
+	// so that errors.Is(err, podops.ErrInvalidGUID) etc. works as expected.
+	Error struct {
+		Status  int    // HTTP status code
+		Message string // the error message returned by the API, if any
+		Err     error  // the matching podops error or podops.ErrApiError
+
+		RetryAfter time.Duration // the value of the Retry-After header, if any
+	}
+)
+
+var (
+	// errors the API returns, see internal/api/openapi.yaml
+	apiErrors = []error{
+		podops.ErrInternalError,
+		podops.ErrInvalidRoute,
+		podops.ErrUnsupportedWebhookEvent,
+		podops.ErrInvalidResourceName,
+		podops.ErrResourceNotFound,
+		podops.ErrInvalidGUID,
+		podops.ErrInvalidParameters,
+		podops.ErrRateLimitExceeded,
+		podops.ErrQuotaExceeded,
+		podops.ErrMissingContentLength,
+		podops.ErrMissingPayloadSecret,
+		podops.ErrInvalidPayloadSignature,
+		podops.ErrNameExists,
+		podops.ErrShowDeleted,
+		auth.ErrNotAuthorized,
+		auth.ErrNoToken,
+	}
+)
+
+func newError(status int, msg string) *Error {
+	e := Error{
+		Status:  status,
+		Message: msg,
+		Err:     podops.ErrApiError,
+	}
+	for _, err := range apiErrors {
+		if err.Error() == msg {
+			e.Err = err
+			break
+		}
+	}
+	return &e
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf(podops.MsgStatus, e.Status)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"io"
+	"time"
+)
+
+// EventType describes what happened in an Event
+type EventType int
+
+const (
+	// SyncPlanned is sent once Sync or Pull know which files they have to transfer. Count and Total are set.
+	SyncPlanned EventType = iota
+	// UploadStarted is sent before the first byte of a file is sent
+	UploadStarted
+	// UploadProgress is sent while the file is being sent
+	UploadProgress
+	// UploadCompleted is sent after the API accepted the file
+	UploadCompleted
+	// UploadFailed is sent after the last attempt to upload a file failed
+	UploadFailed
+	// DownloadStarted is sent before the first byte of a file is received
+	DownloadStarted
+	// DownloadProgress is sent while the file is being received
+	DownloadProgress
+	// DownloadCompleted is sent after the file was received and verified
+	DownloadCompleted
+	// DownloadFailed is sent after the last attempt to download a file failed
+	DownloadFailed
+	// RequestRetried is sent before a failed request is repeated. Attempt, Wait and Err are set.
+	RequestRetried
+)
+
+type (
+	// Event reports the progress of uploads and retries to a ProgressFunc
+	Event struct {
+		Type    EventType
+		Name    string        // the file or API route the event refers to
+		Bytes   int64         // bytes sent so far
+		Total   int64         // size of the file, or of all files for SyncPlanned
+		Count   int           // number of files, SyncPlanned only
+		Attempt int           // the attempt that failed, RequestRetried only
+		Wait    time.Duration // wait time before the next attempt, RequestRetried only
+		Err     error
+	}
+
+	// ProgressFunc receives progress events. It is called from the goroutine
+	// performing the request and must not block.
+	ProgressFunc func(Event)
+
+	// progressReader reports the bytes read from r
+	progressReader struct {
+		r      io.Reader
+		name   string
+		read   int64
+		total  int64
+		event  EventType
+		report func(Event)
+	}
+)
+
+func (c *Client) report(e Event) {
+	if c.Progress != nil {
+		c.Progress(e)
+	}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.read += int64(n)
+		p.report(Event{Type: p.event, Name: p.name, Bytes: p.read, Total: p.total})
+	}
+	return n, err
+}
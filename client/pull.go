@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/api"
+)
+
+const (
+	exportRoute = "/export"
+)
+
+// Pull downloads the feed and all media assets of a show from the CDN into the local build location.
+// Assets that already exist locally with the expected size are skipped, interrupted downloads are resumed
+// as long as the asset did not change on the CDN.
+func (c *Client) Pull(ctx context.Context, parent, root string) error {
+	if parent == "" {
+		return podops.ErrInvalidGUID
+	}
+
+	location := filepath.Join(root, config.BuildLocation)
+	if err := os.MkdirAll(location, os.ModePerm); err != nil {
+		return err
+	}
+
+	assets, err := c.List(ctx, parent)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	var missing []int
+	for i, m := range assets {
+		if fi, err := os.Stat(filepath.Join(location, m.Name)); err == nil && fi.Size() == m.Size {
+			continue // already there
+		}
+		missing = append(missing, i)
+		total += m.Size
+	}
+	c.report(Event{Type: SyncPlanned, Count: len(missing) + 1, Total: total})
+
+	for _, i := range missing {
+		m := assets[i]
+		url := fmt.Sprintf("%s/%s/%s", c.ContentEndpoint, parent, m.Name)
+		if err := c.download(ctx, url, filepath.Join(location, m.Name), m.Size, m.ContentETag); err != nil {
+			return err
+		}
+	}
+
+	// the feed changes with every publish, always get a fresh copy
+	url := fmt.Sprintf("%s/%s/%s", c.ContentEndpoint, parent, config.DefaultFeedName)
+	feedPath := filepath.Join(location, config.DefaultFeedName)
+	os.Remove(feedPath + ".part")
+
+	return c.download(ctx, url, feedPath, -1, "")
+}
+
+// Export downloads a tar.gz archive of the show's storage location on the CDN to path.
+// The archive contains the feed and the media assets, but no keys, subscriptions or other server state.
+func (c *Client) Export(ctx context.Context, parent, path string) error {
+	if parent == "" {
+		return podops.ErrInvalidGUID
+	}
+
+	// the archive is created on the fly and can not be resumed
+	os.Remove(path + ".part")
+
+	url := fmt.Sprintf("%s%s%s/%s", c.Endpoint, api.NamespacePrefix, exportRoute, parent)
+	return c.download(ctx, url, path, -1, "")
+}
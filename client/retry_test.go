@@ -0,0 +1,164 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+)
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	var retries int
+	c := Client{Endpoint: srv.URL, HTTPClient: srv.Client(), Retries: 3, Backoff: time.Millisecond}
+	c.Progress = func(e Event) {
+		if e.Type == RequestRetried {
+			retries++
+		}
+	}
+
+	subs, err := c.Subscriptions(context.TODO(), guid)
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+	assert.Equal(t, int32(3), calls)
+	assert.Equal(t, 2, retries)
+}
+
+func TestNoRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":400,"message":"invalid GUID"}`))
+	}))
+	defer srv.Close()
+
+	c := Client{Endpoint: srv.URL, HTTPClient: srv.Client(), Retries: 3, Backoff: time.Millisecond}
+
+	_, err := c.Subscriptions(context.TODO(), guid)
+	assert.True(t, errors.Is(err, podops.ErrInvalidGUID))
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestNoRetryAction(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := Client{Endpoint: srv.URL, HTTPClient: srv.Client(), Retries: 3, Backoff: time.Millisecond}
+
+	// the server might have rotated the secret before the response got lost
+	_, err := c.RotateWebhookSecret(context.TODO(), guid)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls)
+
+	assert.Error(t, c.Delete(context.TODO(), guid, "episode.mp3"))
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-1"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+	assert.True(t, parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)) > 30*time.Second)
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := []byte("0123456789")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "asset.mp3", time.Now(), bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "asset.mp3")
+	assert.NoError(t, os.WriteFile(path+".part", content[:4], 0644))
+
+	c := Client{Endpoint: srv.URL, HTTPClient: srv.Client(), Retries: 0}
+	err := c.download(context.TODO(), srv.URL+"/asset.mp3", path, int64(len(content)), "")
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// a wrong size fails the verification
+	err = c.download(context.TODO(), srv.URL+"/asset.mp3", path, 42, "")
+	assert.True(t, errors.Is(err, podops.ErrVerificationFailed))
+}
+
+func TestDownloadChanged(t *testing.T) {
+	content := []byte("0123456789")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("etag", `"v2"`)
+		http.ServeContent(w, r, "asset.mp3", time.Now(), bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "asset.mp3")
+	c := Client{Endpoint: srv.URL, HTTPClient: srv.Client(), Retries: 0}
+
+	// the part of an older version is not resumed, the one of the current version is
+	assert.NoError(t, os.WriteFile(path+".v1.part", []byte("abcd"), 0644))
+	assert.NoError(t, os.WriteFile(path+".v2.part", content[:4], 0644))
+	err := c.download(context.TODO(), srv.URL+"/asset.mp3", path, int64(len(content)), "v2")
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+	_, err = os.Stat(path + ".v1.part")
+	assert.True(t, os.IsNotExist(err))
+
+	// the file changed since it was listed
+	err = c.download(context.TODO(), srv.URL+"/asset.mp3", path, int64(len(content)), "v3")
+	assert.True(t, errors.Is(err, podops.ErrVerificationFailed))
+	_, err = os.Stat(path + ".v3.part")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDownloadDigest(t *testing.T) {
+	digest := "sha-256=" + base64.StdEncoding.EncodeToString([]byte("not the digest"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Digest")
+		w.Write([]byte("archive"))
+		w.Header().Set("Digest", digest)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "export.tar.gz")
+	c := Client{Endpoint: srv.URL, HTTPClient: srv.Client(), Retries: 0}
+
+	err := c.download(context.TODO(), srv.URL+"/export", path, -1, "")
+	assert.True(t, errors.Is(err, podops.ErrVerificationFailed))
+
+	sum := sha256.Sum256([]byte("archive"))
+	digest = "sha-256=" + base64.StdEncoding.EncodeToString(sum[:])
+	assert.NoError(t, c.download(context.TODO(), srv.URL+"/export", path, -1, ""))
+}
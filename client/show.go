@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/txsvc/stdlib/v2/settings"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/internal/api"
+	"github.com/podops/podops/internal/cdn"
+)
+
+const (
+	showRoute     = "/show"
+	conflictRoute = "/show/conflicts"
+	redirectRoute = "/redirect"
+)
+
+// Shows returns the shows visible to the client's token. Admins see all shows, or only
+// the shows of owner if it is not empty.
+func (c *Client) Shows(ctx context.Context, owner string) ([]cdn.ShowInfo, error) {
+	var shows []cdn.ShowInfo
+
+	cmd := fmt.Sprintf("%s%s", api.NamespacePrefix, showRoute)
+	if owner != "" {
+		cmd = fmt.Sprintf("%s?owner=%s", cmd, url.QueryEscape(owner))
+	}
+	if err := c.get(ctx, cmd, &shows); err != nil {
+		return nil, err
+	}
+
+	return shows, nil
+}
+
+// Conflicts returns the names that are claimed by more than one show. Admins only.
+func (c *Client) Conflicts(ctx context.Context) ([]cdn.Conflict, error) {
+	var conflicts []cdn.Conflict
+
+	cmd := fmt.Sprintf("%s%s", api.NamespacePrefix, conflictRoute)
+	if err := c.get(ctx, cmd, &conflicts); err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+// DeleteShow soft-deletes a show. It can be restored until the server's retention period is over.
+func (c *Client) DeleteShow(ctx context.Context, parent string) error {
+	if parent == "" {
+		return podops.ErrInvalidGUID
+	}
+
+	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, showRoute, parent)
+	return c.del(ctx, cmd)
+}
+
+// RestoreShow reverts the soft-delete of a show
+func (c *Client) RestoreShow(ctx context.Context, parent string) error {
+	if parent == "" {
+		return podops.ErrInvalidGUID
+	}
+
+	cmd := fmt.Sprintf("%s%s/%s/restore", api.NamespacePrefix, showRoute, parent)
+	return c.put(ctx, cmd, nil, nil)
+}
+
+// TransferShow makes userid the owner of a show and returns the show's new credentials.
+// The show's old token becomes invalid immediately.
+func (c *Client) TransferShow(ctx context.Context, parent, userid string) (*settings.DialSettings, error) {
+	if parent == "" {
+		return nil, podops.ErrInvalidGUID
+	}
+	if userid == "" {
+		return nil, podops.ErrInvalidParameters
+	}
+
+	cfg := settings.DialSettings{}
+	cmd := fmt.Sprintf("%s%s/%s/owner/%s", api.NamespacePrefix, showRoute, parent, userid)
+	if err := c.put(ctx, cmd, nil, &cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// RenameShow changes the canonical name of a show. The old name keeps working.
+func (c *Client) RenameShow(ctx context.Context, parent, name string) error {
+	if parent == "" {
+		return podops.ErrInvalidGUID
+	}
+	if !podops.ValidName(name) {
+		return podops.ErrInvalidResourceName
+	}
+
+	cmd := fmt.Sprintf("%s%s/%s/name/%s", api.NamespacePrefix, showRoute, parent, name)
+	return c.put(ctx, cmd, nil, nil)
+}
+
+// Redirects returns the feed redirects of a show
+func (c *Client) Redirects(ctx context.Context, parent string) ([]cdn.Redirect, error) {
+	var redirects []cdn.Redirect
+
+	if parent == "" {
+		return nil, podops.ErrInvalidGUID
+	}
+
+	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, redirectRoute, parent)
+	if err := c.get(ctx, cmd, &redirects); err != nil {
+		return nil, err
+	}
+
+	return redirects, nil
+}
+
+// UpdateRedirects replaces the feed redirects of a show
+func (c *Client) UpdateRedirects(ctx context.Context, parent string, redirects []cdn.Redirect) error {
+	if parent == "" {
+		return podops.ErrInvalidGUID
+	}
+	if err := cdn.ValidateRedirects(redirects); err != nil {
+		return err
+	}
+
+	cmd := fmt.Sprintf("%s%s/%s", api.NamespacePrefix, redirectRoute, parent)
+	return c.put(ctx, cmd, redirects, nil)
+}
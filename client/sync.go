@@ -0,0 +1,213 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/builder"
+	"github.com/podops/podops/internal/metadata"
+)
+
+const (
+	// DefaultConcurrency is the number of parallel uploads
+	DefaultConcurrency = 4
+)
+
+type (
+	// SyncPlan lists what Apply will do to bring the CDN in line with the local repository
+	SyncPlan struct {
+		Parent    string     `json:"parent"`
+		Uploads   []SyncItem `json:"uploads"`   // local assets missing on the CDN
+		Skips     []SyncItem `json:"skips"`     // assets already on the CDN
+		Deletions []SyncItem `json:"deletions"` // assets on the CDN no longer referenced, if purging
+		Feed      SyncItem   `json:"feed"`      // feed.xml, always uploaded last
+	}
+
+	// SyncItem is a single asset in a SyncPlan
+	SyncItem struct {
+		Name string `json:"name"`
+		Path string `json:"path,omitempty"`
+		Size int64  `json:"size"`
+	}
+)
+
+// Plan compares a local repository with the remote content endpoint and returns the uploads,
+// skips and, if purge is true, deletions needed to synchronize them. Nothing is changed.
+func (c *Client) Plan(ctx context.Context, parent, root string, purge bool) (*SyncPlan, error) {
+	if parent == "" {
+		return nil, podops.ErrInvalidGUID
+	}
+
+	// check that the cache dir exists
+	assetPath := filepath.Join(root, config.BuildLocation)
+	if _, err := os.Stat(assetPath); os.IsNotExist(err) {
+		return nil, podops.ErrResourceNotFound
+	}
+
+	// get a list of resources and build a look-up table
+	r, err := c.List(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	rsrc := make(map[string]metadata.Metadata)
+	for _, m := range r {
+		rsrc[m.ETag] = m
+	}
+
+	plan := SyncPlan{
+		Parent:    parent,
+		Uploads:   make([]SyncItem, 0),
+		Skips:     make([]SyncItem, 0),
+		Deletions: make([]SyncItem, 0),
+	}
+
+	// now iterate over all the resource definitions and sort them into uploads and skips
+	err = filepath.Walk(assetPath, func(path string, info os.FileInfo, e error) error {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if ext != ".yaml" {
+			return nil // skip e.g feed.xml
+		}
+
+		ar, err := builder.LoadAssetRef(path)
+		if err != nil {
+			return err
+		}
+
+		if m, ok := rsrc[ar.ETag]; ok {
+			plan.Skips = append(plan.Skips, SyncItem{Name: m.Name, Size: m.Size})
+			// remove the processed asset from the map, for purgeing assets later
+			delete(rsrc, ar.ETag)
+			return nil
+		}
+
+		parts := strings.Split(ar.URI, ".")
+		if len(parts) < 2 {
+			return podops.ErrInvalidResourceName
+		}
+		assetFileName := fmt.Sprintf("%s.%s", ar.ETag, parts[len(parts)-1])
+		assetPath := filepath.Join(root, config.BuildLocation, assetFileName)
+
+		fi, err = os.Stat(assetPath)
+		if err != nil {
+			return podops.ErrResourceNotFound
+		}
+		plan.Uploads = append(plan.Uploads, SyncItem{Name: assetFileName, Path: assetPath, Size: fi.Size()})
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// feed.xml goes last
+	feedFilePath := filepath.Join(root, config.BuildLocation, config.DefaultFeedName)
+	fi, err := os.Stat(feedFilePath)
+	if err != nil {
+		return nil, podops.ErrResourceNotFound
+	}
+	plan.Feed = SyncItem{Name: config.DefaultFeedName, Path: feedFilePath, Size: fi.Size()}
+
+	// everything left over is no longer referenced
+	if purge {
+		for _, m := range rsrc {
+			plan.Deletions = append(plan.Deletions, SyncItem{Name: m.Name, Size: m.Size})
+		}
+		sort.Slice(plan.Deletions, func(i, j int) bool { return plan.Deletions[i].Name < plan.Deletions[j].Name })
+	}
+
+	return &plan, nil
+}
+
+// Apply executes a plan. The uploads run concurrently, with at most concurrency uploads at a time.
+//
+// A failed upload does not stop the sync, the remaining files are uploaded nevertheless. The feed
+// is only published and obsolete assets are only deleted if all uploads succeeded.
+func (c *Client) Apply(ctx context.Context, plan *SyncPlan, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	total := plan.Feed.Size
+	for _, u := range plan.Uploads {
+		total += u.Size
+	}
+	c.report(Event{Type: SyncPlanned, Count: len(plan.Uploads) + 1, Total: total})
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var failed []error
+
+	queue := make(chan SyncItem)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				if err := c.Upload(ctx, plan.Parent, item.Path); err != nil {
+					mu.Lock()
+					failed = append(failed, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+enqueue:
+	for _, item := range plan.Uploads {
+		select {
+		case queue <- item:
+		case <-ctx.Done():
+			break enqueue
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: "+podops.MsgSyncFailed, podops.ErrSyncFailed, len(failed), len(plan.Uploads), failed[0])
+	}
+
+	if err := c.Upload(ctx, plan.Parent, plan.Feed.Path); err != nil {
+		return err
+	}
+
+	// purge obsolete assets
+	for _, d := range plan.Deletions {
+		if err := c.Delete(ctx, plan.Parent, d.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sync synchronizes a local repository against the remote content endpoint. All local resources that
+// are missing on the remote site will be uploaded, already existing ones are ignored.
+// Only media files (e.g. .mp3, .png) are synchronized.
+func (c *Client) Sync(ctx context.Context, parent, root string, purge bool) error {
+	plan, err := c.Plan(ctx, parent, root, purge)
+	if err != nil {
+		return err
+	}
+	return c.Apply(ctx, plan, DefaultConcurrency)
+}
@@ -2,177 +2,368 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/txsvc/httpservice/pkg/api"
+	httpapi "github.com/txsvc/httpservice/pkg/api"
+	"github.com/txsvc/stdlib/v2/settings"
 
 	"github.com/podops/podops"
 	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/api"
 )
 
-// Get is used to request data from the API. No payload, only queries!
-func Get(url, cmd, token string, response interface{}) (int, error) {
-	uri := url + cmd
+type (
+	// Client is a typed client for the podops API, see internal/api/openapi.yaml
+	Client struct {
+		Endpoint        string
+		ContentEndpoint string
+		Token           string
+		HTTPClient      *http.Client
+		// Retries is the number of times a failed query or upload is repeated. Only
+		// network errors and 5xx/429 responses are retried.
+		Retries int
+		// Backoff is the wait time before the first retry, it doubles with every attempt
+		Backoff time.Duration
+		// Progress receives upload progress and retry events, if set
+		Progress ProgressFunc
+	}
 
-	req, err := http.NewRequest("GET", uri, nil)
-	if err != nil {
-		return http.StatusBadRequest, err
+	// requestFunc creates a new request for every attempt, as the body can only be read once
+	requestFunc func() (*http.Request, error)
+
+	// responseFunc consumes the body of a successful response
+	responseFunc func(*http.Response) error
+)
+
+// New creates a client for the API endpoint and credentials in the settings
+func New(cfg *settings.DialSettings) *Client {
+	c := Client{
+		Endpoint:        cfg.Endpoint,
+		ContentEndpoint: cfg.GetOption(config.PodopsContentEndpointEnv),
+		HTTPClient:      newHTTPClient(),
+		Retries:         DefaultRetries,
+		Backoff:         DefaultBackoff,
 	}
+	if cfg.Credentials != nil {
+		c.Token = cfg.Credentials.Token
+	}
+	return &c
+}
 
-	return invoke(token, req, response)
+// newHTTPClient returns a client that fails fast on unreachable or stalled
+// servers but does not limit the duration of large uploads
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConns:          10,
+		},
+	}
 }
 
-// Post is used to invoke an API method using http POST
-func Post(url, cmd, token string, request, response interface{}) (int, error) {
-	uri := url + cmd
+// get is used to request data from the API. No payload, only queries!
+func (c *Client) get(ctx context.Context, cmd string, response interface{}) error {
+	return c.invoke(ctx, http.MethodGet, cmd, nil, response)
+}
 
-	payload, err := json.Marshal(&request)
+// put is used to invoke an API method using http PUT
+func (c *Client) put(ctx context.Context, cmd string, request, response interface{}) error {
+	return c.invoke(ctx, http.MethodPut, cmd, request, response)
+}
+
+// del is used to request the deletion of a resource. No payload, no response!
+func (c *Client) del(ctx context.Context, cmd string) error {
+	return c.invoke(ctx, http.MethodDelete, cmd, nil, nil)
+}
+
+// upload streams a file as multipart form to the API. Assets are named by their
+// ETag, uploading one twice is harmless, so uploads are retried like idempotent requests.
+func (c *Client) upload(ctx context.Context, cmd, form, path string) error {
+	fi, err := os.Stat(path)
 	if err != nil {
-		return http.StatusInternalServerError, err
+		return err
 	}
+	name := filepath.Base(path)
 
-	req, err := http.NewRequest("POST", uri, bytes.NewBuffer(payload))
-	if err != nil {
-		return http.StatusBadRequest, err
+	// render the multipart envelope once, the file is streamed in between
+	envelope := bytes.Buffer{}
+	writer := multipart.NewWriter(&envelope)
+	if _, err := writer.CreateFormFile(form, name); err != nil {
+		return err
+	}
+	head := envelope.Len()
+	if err := writer.Close(); err != nil {
+		return err
 	}
+	prefix := envelope.Bytes()[:head]
+	suffix := envelope.Bytes()[head:]
 
-	return invoke(token, req, response)
-}
+	var file *os.File
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
 
-// Put is used to invoke an API method using http PUT
-func Put(url, cmd, token string, request, response interface{}) (int, error) {
-	var req *http.Request
-	uri := url + cmd
+	c.report(Event{Type: UploadStarted, Name: name, Total: fi.Size()})
 
-	if request != nil {
-		p, err := json.Marshal(&request)
-		if err != nil {
-			return http.StatusInternalServerError, err
+	err = c.retry(ctx, name, func() (*http.Request, error) {
+		if file != nil {
+			file.Close()
 		}
-
-		req, err = http.NewRequest("PUT", uri, bytes.NewBuffer(p))
+		f, err := os.Open(path)
 		if err != nil {
-			return http.StatusBadRequest, err
+			return nil, err
 		}
-	} else {
-		var err error
-		req, err = http.NewRequest("PUT", uri, nil)
+		file = f
+
+		body := io.MultiReader(
+			bytes.NewReader(prefix),
+			&progressReader{r: file, name: name, total: fi.Size(), event: UploadProgress, report: c.report},
+			bytes.NewReader(suffix),
+		)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint+cmd, body)
 		if err != nil {
-			return http.StatusBadRequest, err
+			return nil, err
 		}
-	}
+		req.ContentLength = int64(len(prefix)+len(suffix)) + fi.Size()
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req, nil
+	}, decodeJSON(nil))
 
-	return invoke(token, req, response)
+	if err != nil {
+		c.report(Event{Type: UploadFailed, Name: name, Total: fi.Size(), Err: err})
+		return err
+	}
+	c.report(Event{Type: UploadCompleted, Name: name, Bytes: fi.Size(), Total: fi.Size()})
+	return nil
 }
 
-// DELETE is used to request the deletion of a resource. Maybe apayload, no response!
-func Del(url, cmd, token string, request interface{}) (int, error) {
-	var req *http.Request
-	uri := url + cmd
+func (c *Client) invoke(ctx context.Context, method, cmd string, request, response interface{}) error {
+	var payload []byte
 
 	if request != nil {
 		p, err := json.Marshal(&request)
 		if err != nil {
-			return http.StatusInternalServerError, err
+			return err
 		}
+		payload = p
+	}
 
-		req, err = http.NewRequest("DELETE", uri, bytes.NewBuffer(p))
+	newRequest := func() (*http.Request, error) {
+		var body io.Reader
+		if payload != nil {
+			body = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.Endpoint+cmd, body)
 		if err != nil {
-			return http.StatusBadRequest, err
+			return nil, err
 		}
-	} else {
-		var err error
-		req, err = http.NewRequest("DELETE", uri, nil)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		return req, nil
+	}
+
+	// only queries are retried. PUT and DELETE trigger actions, e.g. rotating the
+	// webhook secret, and a retry after a lost response would run them twice.
+	if !idempotent(method) {
+		req, err := newRequest()
 		if err != nil {
-			return http.StatusBadRequest, err
+			return err
 		}
+		return c.do(req, decodeJSON(response))
 	}
+	return c.retry(ctx, cmd, newRequest, decodeJSON(response))
+}
 
-	return invoke(token, req, nil)
+// idempotent reports if a request with the method can safely be sent again
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
 }
 
-// Creates a new file upload http request with optional extra params
-func UploadRequest(url, token, form, path string) (*http.Request, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+// retry performs the request until it succeeds, fails with a final error, the
+// retries are exhausted or the context is done
+func (c *Client) retry(ctx context.Context, name string, newRequest requestFunc, handle responseFunc) error {
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return err
+		}
 
-	body := bytes.Buffer{}
-	writer := multipart.NewWriter(&body)
-	part, err := writer.CreateFormFile(form, filepath.Base(path))
-	if err != nil {
-		return nil, err
+		err = c.do(req, handle)
+		if attempt > c.Retries || !retryable(ctx, err) {
+			return err
+		}
+
+		wait := c.backoff(attempt, err)
+		c.report(Event{Type: RequestRetried, Name: name, Attempt: attempt, Wait: wait, Err: err})
+
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
 	}
+}
 
-	_, err = io.Copy(part, file)
-	if err != nil {
-		return nil, err
+func (c *Client) do(req *http.Request, handle responseFunc) error {
+	req.Header.Set("User-Agent", config.UserAgentString)
+	// only the API gets to see the token, not the CDN
+	if c.Token != "" && strings.HasPrefix(req.URL.String(), c.Endpoint) {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
 	}
 
-	err = writer.Close()
+	// perform the request
+	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer resp.Body.Close()
 
-	req, err := http.NewRequest("POST", url, &body)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("User-Agent", config.UserAgentString)
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
+	// anything other than OK, Created, Accepted, NoContent, Partial Content is treated as an error
+	if resp.StatusCode > http.StatusPartialContent {
+		var e *Error
+
+		// there might be a StatusObject
+		status := httpapi.StatusObject{}
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			e = newError(resp.StatusCode, "")
+		} else {
+			e = newError(resp.StatusCode, status.Message)
+		}
+		e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return e
 	}
 
-	return req, err
+	return handle(resp)
 }
 
-func invoke(token string, req *http.Request, response interface{}) (int, error) {
+// decodeJSON unmarshals the response, if one is expected
+func decodeJSON(response interface{}) responseFunc {
+	return func(resp *http.Response) error {
+		if response == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(response)
+	}
+}
 
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	req.Header.Set("User-Agent", config.UserAgentString)
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
+// download fetches url into path. The file is received into path.part first, an interrupted
+// download is resumed from there. If size is known, i.e. >= 0, the received file has to match it.
+// If etag is known, only that version of the file is accepted. Its part is kept in
+// path.<etag>.part, so a part of a different version is never resumed. A digest sent by the
+// server is verified as well.
+func (c *Client) download(ctx context.Context, url, path string, size int64, etag string) error {
+	name := filepath.Base(path)
+	part := path + ".part"
+	if etag != "" {
+		part = fmt.Sprintf("%s.%s.part", path, etag)
+
+		// parts of other versions can not be resumed
+		os.Remove(path + ".part")
+		if parts, err := filepath.Glob(path + ".*.part"); err == nil {
+			for _, p := range parts {
+				if p != part {
+					os.Remove(p)
+				}
+			}
+		}
 	}
 
-	// perform the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		if resp == nil {
-			return http.StatusInternalServerError, err
+	c.report(Event{Type: DownloadStarted, Name: name, Total: size})
+
+	// the last run might have stopped right before the rename
+	if fi, err := os.Stat(part); err == nil && size >= 0 && fi.Size() == size {
+		if err := os.Rename(part, path); err != nil {
+			return err
 		}
-		return resp.StatusCode, err
+		c.report(Event{Type: DownloadCompleted, Name: name, Bytes: size, Total: size})
+		return nil
 	}
 
-	defer resp.Body.Close()
+	var received int64
+	err := c.retry(ctx, name, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		// resume where the last attempt or run stopped, unless the file changed in between
+		if fi, err := os.Stat(part); err == nil && fi.Size() > 0 && size >= 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", fi.Size()))
+			if etag != "" {
+				req.Header.Set("If-Range", fmt.Sprintf("\"%s\"", etag))
+			}
+		}
+		return req, nil
+	}, func(resp *http.Response) error {
+		if etag != "" && strings.Trim(resp.Header.Get("etag"), "\"") != etag {
+			os.Remove(part) // the file changed since it was listed
+			return podops.ErrVerificationFailed
+		}
 
-	// anything other than OK, Created, Accepted, NoContent is treated as an error
-	if resp.StatusCode > http.StatusNoContent {
-		if response != nil {
-			// as we expect a response, there might be a StatusObject
-			status := api.StatusObject{}
-			err = json.NewDecoder(resp.Body).Decode(&status)
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		var offset int64
+		if resp.StatusCode == http.StatusPartialContent {
+			fi, err := os.Stat(part)
 			if err != nil {
-				return resp.StatusCode, fmt.Errorf(podops.MsgStatus, resp.StatusCode)
+				return err
 			}
-			return status.Status, fmt.Errorf(status.Message)
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+			offset = fi.Size()
 		}
-		return resp.StatusCode, podops.ErrApiError
-	}
 
-	// unmarshal the response if one is expected
-	if response != nil {
-		err = json.NewDecoder(resp.Body).Decode(response)
+		out, err := os.OpenFile(part, flags, 0644)
 		if err != nil {
-			return http.StatusInternalServerError, err
+			return err
 		}
+		defer out.Close()
+
+		h := sha256.New()
+		pr := progressReader{r: resp.Body, name: name, read: offset, total: size, event: DownloadProgress, report: c.report}
+		n, err := io.Copy(io.MultiWriter(out, h), &pr)
+		received = offset + n
+		if err != nil {
+			return err
+		}
+
+		// the digest is sent as trailer, i.e. it is known once the body was read
+		digest := resp.Trailer.Get(api.HeaderDigest)
+		if offset == 0 && strings.HasPrefix(digest, api.DigestPrefix) && digest[len(api.DigestPrefix):] != base64.StdEncoding.EncodeToString(h.Sum(nil)) {
+			os.Remove(part)
+			return podops.ErrVerificationFailed
+		}
+		return nil
+	})
+
+	if err == nil && size >= 0 && received != size {
+		os.Remove(part) // start from scratch next time
+		err = podops.ErrVerificationFailed
+	}
+	if err == nil {
+		err = os.Rename(part, path)
+	}
+	if err != nil {
+		c.report(Event{Type: DownloadFailed, Name: name, Total: size, Err: err})
+		return fmt.Errorf(podops.MsgResourceDownloadError+": %w", url, err)
 	}
 
-	return resp.StatusCode, nil
+	c.report(Event{Type: DownloadCompleted, Name: name, Bytes: received, Total: received})
+	return nil
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -21,12 +22,33 @@ func init() {
 	cfg, _ := config.LoadClientSettings("")
 	auth.RegisterAuthorization(cfg)
 	// register all client crdentials
-	if err := cdn.CreateCredentialsMappings(context.TODO(), config.StorageLocation); err != nil {
+	if err := cdn.CreateCredentialsMappings(context.TODO()); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// expireDeletedShows removes soft-deleted shows once their retention period is over
+// and old events from the change journal
+func expireDeletedShows() {
+	retention := time.Duration(config.DeleteRetention) * 24 * time.Hour
+	for {
+		if err := cdn.ExpireDeletedShows(context.Background(), retention); err != nil {
+			log.Println(err)
+		}
+		if err := cdn.ExpireChanges(context.Background(), cdn.ChangeRetention); err != nil {
+			log.Println(err)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
 func setup() *echo.Echo {
+	// the API needs the name mappings to detect renames and redirects of names that are taken
+	if err := cdn.WatchInventory(context.Background(), 10*time.Second, func(err error) { log.Println(err) }); err != nil {
+		log.Fatal(err)
+	}
+	go expireDeletedShows()
+
 	// create a new router instance
 	e := echo.New()
 
@@ -38,15 +60,40 @@ func setup() *echo.Echo {
 
 	// API endpoint namespace
 	apiEndpoints := e.Group(api.NamespacePrefix)
+	apiEndpoints.Use(api.RateLimitMiddleware)
 
 	// asset related routes
 	apiEndpoints.POST(api.AssetRoute, api.AssetUploadEndpoint)
 	apiEndpoints.GET(api.AssetRoute, api.AssetListEndpoint)
 	apiEndpoints.DELETE(api.AssetDeleteRoute, api.AssetDeleteEndpoint)
+	apiEndpoints.GET(api.ExportRoute, api.ExportEndpoint)
 
 	// admin endpoints
 	apiEndpoints.PUT(api.InitRoute, api.InitEndpoint)
-	apiEndpoints.POST(api.WebhookRoute, api.WebhookGithubEndpoint)
+	apiEndpoints.POST(api.WebhookRoute, api.WebhookEndpoint)
+	apiEndpoints.POST(api.WebhookProviderRoute, api.WebhookEndpoint)
+	apiEndpoints.PUT(api.WebhookSecretRoute, api.WebhookSecretEndpoint)
+
+	// show lifecycle routes
+	apiEndpoints.GET(api.ShowRoute, api.ShowListEndpoint)
+	apiEndpoints.GET(api.ShowConflictRoute, api.ShowConflictEndpoint)
+	apiEndpoints.DELETE(api.ShowDeleteRoute, api.ShowDeleteEndpoint)
+	apiEndpoints.PUT(api.ShowRestoreRoute, api.ShowRestoreEndpoint)
+	apiEndpoints.PUT(api.ShowTransferRoute, api.ShowTransferEndpoint)
+	apiEndpoints.PUT(api.ShowRenameRoute, api.ShowRenameEndpoint)
+	apiEndpoints.GET(api.RedirectRoute, api.RedirectListEndpoint)
+	apiEndpoints.PUT(api.RedirectRoute, api.RedirectUpdateEndpoint)
+
+	// build related routes
+	apiEndpoints.GET(api.BuildRoute, api.BuildListEndpoint)
+	apiEndpoints.GET(api.BuildStatusRoute, api.BuildStatusEndpoint)
+
+	// notification related routes
+	apiEndpoints.GET(api.NotifyRoute, api.NotifyListEndpoint)
+	apiEndpoints.PUT(api.NotifyRoute, api.NotifyUpdateEndpoint)
+
+	// documentation
+	apiEndpoints.GET(api.OpenAPIRoute, api.OpenAPIEndpoint)
 
 	// default endpoint to catch random requests
 	e.GET("/", httpapi.DefaultEndpoint)
@@ -8,6 +8,7 @@ import (
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/podops/podops/client"
 	"github.com/podops/podops/config"
 	cmd "github.com/podops/podops/internal/cli"
 )
@@ -82,6 +83,14 @@ func setupCommands() []*cli.Command {
 			Action:    cmd.SyncCommand,
 			Flags:     syncFlags(),
 		},
+		{
+			Name:      "pull",
+			Usage:     "Download a podcast from the CDN",
+			UsageText: "pull [parent]",
+			Category:  basicCommandsGroup,
+			Action:    cmd.PullCommand,
+			Flags:     pullFlags(),
+		},
 		{
 			Name:      "init",
 			Usage:     "Initialize the CDN",
@@ -100,12 +109,36 @@ func setupCommands() []*cli.Command {
 		},
 		{
 			Name:      "import",
-			Usage:     "Import a podcast from its RSS feed",
-			UsageText: "import [feed]",
+			Usage:     "Import podcasts from their RSS feeds or local feed files",
+			UsageText: "import [feed ...]",
 			Category:  contentCommandsGroup,
 			Action:    cmd.ImportCommand,
 			Flags:     importFlags(),
 		},
+		{
+			Name:      "lint",
+			Usage:     "Check all resources for unknown attributes, wrong types and validation errors",
+			UsageText: "lint [path]",
+			Category:  contentCommandsGroup,
+			Action:    cmd.LintCommand,
+			Flags:     lintFlags(),
+		},
+		{
+			Name:      "migrate",
+			Usage:     "Rewrite all resources in the latest apiVersion",
+			UsageText: "migrate [path]",
+			Category:  contentCommandsGroup,
+			Action:    cmd.MigrateCommand,
+			Flags:     migrateFlags(),
+		},
+		{
+			Name:      "export",
+			Usage:     "Export the podcast for other hosting platforms",
+			UsageText: "export [path]",
+			Category:  contentCommandsGroup,
+			Action:    cmd.ExportCommand,
+			Flags:     exportFlags(),
+		},
 		{
 			Name:      "template",
 			Usage:     "Create resource templates with default example values",
@@ -123,6 +156,84 @@ func setupCommands() []*cli.Command {
 			Category:  adminCommandsGroup,
 			Action:    cmd.InfoCommand,
 		},
+		{
+			Name:      "webhook",
+			Usage:     "Create a new secret for the podcast's webhooks",
+			UsageText: "webhook [path]",
+			Category:  adminCommandsGroup,
+			Action:    cmd.WebhookCommand,
+		},
+		{
+			Name:     "show",
+			Usage:    "Manage the podcasts on the CDN",
+			Category: adminCommandsGroup,
+			Subcommands: []*cli.Command{
+				{
+					Name:      "list",
+					Usage:     "List the podcasts visible to the current credentials",
+					UsageText: "show list",
+					Action:    cmd.ShowListCommand,
+					Flags:     showListFlags(),
+				},
+				{
+					Name:      "delete",
+					Usage:     "Delete a podcast, it can be restored until the retention period is over",
+					UsageText: "show delete [parent]",
+					Action:    cmd.ShowDeleteCommand,
+					Flags:     showDeleteFlags(),
+				},
+				{
+					Name:      "restore",
+					Usage:     "Restore a deleted podcast",
+					UsageText: "show restore [parent]",
+					Action:    cmd.ShowRestoreCommand,
+				},
+				{
+					Name:      "transfer",
+					Usage:     "Make another user the owner of a podcast",
+					UsageText: "show transfer USERID [parent]",
+					Action:    cmd.ShowTransferCommand,
+				},
+				{
+					Name:      "rename",
+					Usage:     "Change the canonical name of a podcast",
+					UsageText: "show rename NAME [parent]",
+					Action:    cmd.ShowRenameCommand,
+				},
+				{
+					Name:      "conflicts",
+					Usage:     "List the names that are claimed by more than one podcast (admins only)",
+					UsageText: "show conflicts",
+					Action:    cmd.ShowConflictsCommand,
+					Flags:     showConflictsFlags(),
+				},
+			},
+		},
+		{
+			Name:     "redirect",
+			Usage:    "Manage the permanent redirects of the podcast's feed",
+			Category: adminCommandsGroup,
+			Subcommands: []*cli.Command{
+				{
+					Name:      "list",
+					Usage:     "List the redirects",
+					UsageText: "redirect list",
+					Action:    cmd.RedirectListCommand,
+				},
+				{
+					Name:      "add",
+					Usage:     "Redirect the feed of a podcast name to another name or URL",
+					UsageText: "redirect add FROM NAME|URL",
+					Action:    cmd.RedirectAddCommand,
+				},
+				{
+					Name:      "remove",
+					Usage:     "Remove a redirect",
+					UsageText: "redirect remove FROM",
+					Action:    cmd.RedirectRemoveCommand,
+				},
+			},
+		},
 		{
 			Name:      "config",
 			Usage:     "Create a default config for the CDN and API services",
@@ -156,12 +267,79 @@ func importFlags() []cli.Flag {
 		},
 		&cli.BoolFlag{
 			Name:  "rewrite",
-			Usage: "Rewrite asset references during the import",
+			Usage: "Import the media files as well and rewrite their references",
 		},
 		&cli.BoolFlag{
 			Name:  "single-file",
 			Usage: "Write all resources as one file",
 		},
+		&cli.BoolFlag{
+			Name:  "update",
+			Usage: "Update a previous import, local edits are kept",
+		},
+		&cli.StringFlag{
+			Name:  "opml",
+			Usage: "Import all feeds listed in an OPML file, one repository per podcast",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the import report as JSON",
+		},
+		&cli.BoolFlag{
+			Name:  "strict",
+			Usage: "Only import feeds that need no changes",
+		},
+	}
+	return f
+}
+
+func lintFlags() []cli.Flag {
+	f := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "format",
+			Usage:       "The report `FORMAT`: text, json, sarif or junit",
+			DefaultText: "text",
+			Aliases:     []string{"f"},
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Usage:   "Write the report to a `FILE`",
+			Aliases: []string{"o"},
+		},
+		&cli.StringFlag{
+			Name:  "schema",
+			Usage: "Print the JSON Schema of a resource `KIND` (show or episode) instead",
+		},
+	}
+	return f
+}
+
+func migrateFlags() []cli.Flag {
+	f := []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only list the files that would be migrated",
+		},
+	}
+	return f
+}
+
+func exportFlags() []cli.Flag {
+	f := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "output",
+			Usage:       "The export `LOCATION`, a directory or a .tar.gz archive",
+			DefaultText: config.DefaultExportName,
+			Aliases:     []string{"o"},
+		},
+		&cli.StringFlag{
+			Name:  "base-url",
+			Usage: "The `URL` the exported media will be uploaded to",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the export manifest as JSON",
+		},
 	}
 	return f
 }
@@ -233,6 +411,81 @@ func syncFlags() []cli.Flag {
 			Usage:   "Purge unused resources from the CDN",
 			Aliases: []string{"p"},
 		},
+		&cli.BoolFlag{
+			Name:    "force",
+			Usage:   "Purge without asking for confirmation",
+			Aliases: []string{"f"},
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Show what would be uploaded and deleted, without changing anything",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the sync plan as JSON",
+		},
+		&cli.IntFlag{
+			Name:    "concurrency",
+			Usage:   "Number of parallel uploads",
+			Aliases: []string{"j"},
+			Value:   client.DefaultConcurrency,
+		},
+		&cli.BoolFlag{
+			Name:    "quiet",
+			Usage:   "Do not show the sync plan and upload progress",
+			Aliases: []string{"q"},
+		},
+	}
+	return f
+}
+
+func pullFlags() []cli.Flag {
+	f := []cli.Flag{
+		&cli.StringFlag{
+			Name:    "archive",
+			Usage:   "Download a tar.gz archive of the podcast's CDN storage to `FILE`",
+			Aliases: []string{"a"},
+		},
+		&cli.BoolFlag{
+			Name:    "quiet",
+			Usage:   "Do not show the download progress",
+			Aliases: []string{"q"},
+		},
+	}
+	return f
+}
+
+func showListFlags() []cli.Flag {
+	f := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "owner",
+			Usage: "Only list the podcasts of user `USERID` (admins only)",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the list as JSON",
+		},
+	}
+	return f
+}
+
+func showConflictsFlags() []cli.Flag {
+	f := []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the list as JSON",
+		},
+	}
+	return f
+}
+
+func showDeleteFlags() []cli.Flag {
+	f := []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "force",
+			Usage:   "Delete without asking for confirmation",
+			Aliases: []string{"f"},
+		},
 	}
 	return f
 }
@@ -18,6 +18,19 @@ const (
 	PodopsServiceEndpointEnv = "PODOPS_SERVICE_ENDPOINT"
 	PodopsAPIEndpointEnv     = "PODOPS_API_ENDPOINT"
 	PodopsContentEndpointEnv = "PODOPS_CONTENT_ENDPOINT"
+	PodopsRateLimitEnv       = "PODOPS_RATE_LIMIT"
+	PodopsRateBurstEnv       = "PODOPS_RATE_BURST"
+	PodopsQuotaBytesEnv      = "PODOPS_QUOTA_BYTES"
+	PodopsQuotaFilesEnv      = "PODOPS_QUOTA_FILES"
+	PodopsPodpingEndpointEnv = "PODOPS_PODPING_ENDPOINT"
+	PodopsPodpingTokenEnv    = "PODOPS_PODPING_TOKEN"
+	PodopsDeleteRetentionEnv = "PODOPS_DELETE_RETENTION"
+	PodopsStorageBackendEnv  = "PODOPS_STORAGE_BACKEND"
+	PodopsS3EndpointEnv      = "PODOPS_S3_ENDPOINT"
+	PodopsS3BucketEnv        = "PODOPS_S3_BUCKET"
+	PodopsS3RegionEnv        = "PODOPS_S3_REGION"
+	PodopsS3AccessKeyEnv     = "PODOPS_S3_ACCESS_KEY"
+	PodopsS3SecretKeyEnv     = "PODOPS_S3_SECRET_KEY"
 
 	// default scopes
 	ScopeContentAdmin  = "content:admin"
@@ -26,8 +39,10 @@ const (
 	ScopeContentWrite  = "content:write"
 
 	// other constants
-	DefaultFeedName = "feed.xml"
-	DefaultShowName = "show.yaml"
+	DefaultFeedName            = "feed.xml"
+	DefaultShowName            = "show.yaml"
+	DefaultEpisodeDefaultsName = "defaults.yaml"
+	DefaultExportName          = "export.tar.gz"
 )
 
 var (
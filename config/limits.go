@@ -0,0 +1,38 @@
+package config
+
+import (
+	"strconv"
+
+	"github.com/txsvc/stdlib/v2/env"
+)
+
+const (
+	defaultRateLimit  = 10 // requests per second and token
+	defaultRateBurst  = 20 // requests
+	defaultQuotaBytes = 0  // unlimited
+	defaultQuotaFiles = 0  // unlimited
+
+	defaultDeleteRetention = 30 // days
+)
+
+var (
+	// RateLimit is the number of API requests per second a token can make. 0 disables rate limiting.
+	RateLimit = envInt(PodopsRateLimitEnv, defaultRateLimit)
+	// RateBurst is the number of API requests a token can make in a short burst
+	RateBurst = envInt(PodopsRateBurstEnv, defaultRateBurst)
+	// QuotaBytes is the maximum size of all files of a show on the CDN. 0 means unlimited.
+	QuotaBytes = envInt(PodopsQuotaBytesEnv, defaultQuotaBytes)
+	// QuotaFiles is the maximum number of files of a show on the CDN. 0 means unlimited.
+	QuotaFiles = envInt(PodopsQuotaFilesEnv, defaultQuotaFiles)
+	// DeleteRetention is the number of days a deleted show is kept before it is removed for good
+	DeleteRetention = envInt(PodopsDeleteRetentionEnv, defaultDeleteRetention)
+)
+
+// envInt returns the ENV variable as int64 or def if it is not set or not a number
+func envInt(key string, def int64) int64 {
+	i, err := strconv.ParseInt(env.GetString(key, ""), 10, 64)
+	if err != nil {
+		return def
+	}
+	return i
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"github.com/txsvc/stdlib/v2/env"
+)
+
+var (
+	// PodpingEndpoint receives a Podping notification after publishing, e.g. https://podping.cloud/
+	PodpingEndpoint = optionalString(PodopsPodpingEndpointEnv, "")
+	// PodpingToken authorizes the Podping notifications
+	PodpingToken = env.GetString(PodopsPodpingTokenEnv, "")
+)
+
+// optionalString returns the ENV variable or def if it is not set. 'none' turns the option off.
+func optionalString(key, def string) string {
+	s := env.GetString(key, def)
+	if s == "none" {
+		return ""
+	}
+	return s
+}
@@ -13,9 +13,14 @@ const (
 	defaultStorageLocation = "/data/storage"
 	defaultStaticLocation  = "/data/public/default"
 
-	DefaultTouchFile             = ".updated"
-	DefaultConfigFileLocation    = ".podops/config"
-	DefaultMasterKeyFileLocation = "master.key"
+	DefaultConfigFileLocation     = ".podops/config"
+	DefaultMasterKeyFileLocation  = "master.key"
+	DefaultWebhookKeyFileLocation = "webhook.key"
+	DefaultNotifyFileLocation     = "notify.yaml"
+	DefaultShowMetaFileLocation   = "meta.yaml"
+	DefaultRedirectFileLocation   = "redirects.yaml"
+	DefaultImportStateLocation    = ".podops/import.json"
+	DefaultMediaManifestLocation  = ".podops/media.json"
 )
 
 var (
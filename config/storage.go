@@ -0,0 +1,25 @@
+package config
+
+import (
+	"github.com/txsvc/stdlib/v2/env"
+)
+
+const (
+	defaultStorageBackend = "local"
+	defaultS3Region       = "us-east-1"
+)
+
+var (
+	// StorageBackend selects where the cdn keeps its content: 'local' (StorageLocation) or 's3'
+	StorageBackend = env.GetString(PodopsStorageBackendEnv, defaultStorageBackend)
+	// S3Endpoint is the URL of an S3 compatible object storage, e.g. http://localhost:9000 for MinIO
+	S3Endpoint = env.GetString(PodopsS3EndpointEnv, "")
+	// S3Bucket is the bucket that holds the content of the cdn
+	S3Bucket = env.GetString(PodopsS3BucketEnv, "")
+	// S3Region is the region used to sign requests to the object storage
+	S3Region = env.GetString(PodopsS3RegionEnv, defaultS3Region)
+	// S3AccessKey is the access key ID of the object storage
+	S3AccessKey = env.GetString(PodopsS3AccessKeyEnv, "")
+	// S3SecretKey is the secret access key of the object storage
+	S3SecretKey = env.GetString(PodopsS3SecretKeyEnv, "")
+)
@@ -13,22 +13,78 @@ const (
 	MsgInvalidEmail             = "invalid email '%s'"
 	MsgMissingCategory          = "missing categories"
 
-	MsgResourceUnsupportedKind = "unsupported kind '%s'"
-	MsgResourceImportError     = "error transfering '%s'"
-	MsgResourceUploadError     = "error uploading '%s'"
+	MsgResourceUnsupportedKind    = "unsupported kind '%s'"
+	MsgResourceUnsupportedFormat  = "unsupported format '%s'"
+	MsgResourceInvalidTemplate    = "episode '%s': invalid default: %v"
+	MsgResourceUnsupportedVersion = "unsupported apiVersion '%s'"
+	MsgResourceImportError        = "error transfering '%s'"
+	MsgResourceUploadError        = "error uploading '%s'"
+	MsgResourceDownloadError      = "error downloading '%s'"
+
+	// messages used by the linter
+	MsgLintUnknownField = "unknown field '%s'"
+	MsgLintSuggestion   = "did you mean '%s'?"
+	MsgLintType         = "'%s' must be %s"
+	MsgLintNoKind       = "missing kind, expected 'show' or 'episode'"
 
 	// CLI messages
 	//MsgArgumentMissing       = "missing argument '%s'"
 	//MsgTooManyArguments      = "too many arguments"
 	//MsgArgumentCountMismatch = "argument mismatch: expected %d, got %d"
 
-	MsgBuildSuccess    = "Sucessfully built podcast '%s'"
-	MsgAssembleSuccess = "Sucessfully collected all resources"
-	MsgGenerateSuccess = "Sucessfully generated markdown resources"
-	MsgSyncSuccess     = "Sucessfully synced all resources"
+	MsgBuildSuccess      = "Sucessfully built podcast '%s'"
+	MsgAssembleSuccess   = "Sucessfully collected all resources"
+	MsgGenerateSuccess   = "Sucessfully generated markdown resources"
+	MsgSyncSuccess       = "Sucessfully synced all resources"
+	MsgSyncFailed        = "%d of %d uploads failed, first error: %v"
+	MsgSyncPlan          = "%d to upload (%s), %d unchanged, %d to delete"
+	MsgSyncPlanUpload    = "  upload  %s (%s)"
+	MsgSyncPlanDelete    = "  delete  %s"
+	MsgSyncPlanPublish   = "  publish %s"
+	MsgPurgeConfirm      = "Delete %d assets of podcast '%s' from the CDN? [y/N] "
+	MsgPullSuccess       = "Sucessfully pulled podcast '%s'"
+	MsgShowDeleted       = "Deleted podcast '%s', it can be restored with 'po show restore'"
+	MsgShowRestored      = "Restored podcast '%s'"
+	MsgShowTransferred   = "Transferred podcast '%s' to user '%s', the new token is '%s'"
+	MsgShowRenamed       = "Renamed podcast '%s' to '%s'"
+	MsgShowDeleteConfirm = "Delete podcast '%s' from the CDN? [y/N] "
+	MsgRedirectAdded     = "Redirected '%s' to '%s'"
+	MsgRedirectRemoved   = "Removed the redirect of '%s'"
+	MsgNewFeedLinkSet    = "Set the newFeedLink of '%s' to '%s', the feed announces the move after the next build"
+	MsgNewFeedLinkRemove = "Removed the newFeedLink of '%s'"
+	MsgExportSuccess     = "Sucessfully exported podcast '%s' to '%s'"
+	MsgExportSummary     = "Exported %d episodes and %s of media"
+	MsgExportMissing     = "Warning: '%s' has no local copy, the feed refers to its URL"
+	MsgLintSummary       = "%d files checked, %d errors and %d warnings"
+	MsgMigrateFile       = "Migrated '%s'"
+	MsgMigrateReformat   = "Migrated '%s', its formatting was not kept"
+	MsgMigrateSuccess    = "Sucessfully migrated %d files to apiVersion '%s'"
+	MsgMigrateDryRun     = "%d files would be migrated to apiVersion '%s'"
+	MsgImportAdded       = "Added '%s'"
+	MsgImportUpdated     = "Updated '%s'"
+	MsgImportRemoved     = "Episode '%s' is no longer in the feed, it was kept"
+	MsgImportSummary     = "%d new, %d changed and %d unchanged episodes"
+	MsgImportSuccess     = "Imported podcast '%s' with %d episodes to '%s'"
+	MsgImportWarning     = "Warning: %s: %s"
+	MsgImportFailed      = "Failed to import '%s': %v"
+	MsgImportBatch       = "%d of %d podcasts imported, %d with warnings, %d failed"
+	MsgImportMedia       = "%d of %d media files imported (%s), %d failed"
+	MsgImportMediaFile   = "  import  %s (%s)"
+	MsgImportNoEnclosure = "the episode has no enclosure, it was skipped"
+	MsgImportNoEpisodes  = "the feed has no episodes"
+	MsgImportNumbered    = "the feed has no episode numbers, they were generated"
+	MsgImportDuplicate   = "the guid '%s' is used by another episode, it was skipped"
+	MsgImportLanguage    = "the feed has no language, 'en_US' is assumed"
+	MsgImportLocale      = "the language '%s' was converted to '%s'"
+	MsgImportSubCategory = "subcategory '%s' has no category, it is used as a category"
+	MsgImportShowImage   = "the episode has no image, the show's image is used"
+	MsgImportDate        = "the date '%s' was converted to '%s'"
+	MsgImportLength      = "the enclosure has an invalid length '%s'"
+	MsgImportEpisode     = "episode '%s': %s"
+	MsgImportStrict      = "%d items skipped, %d fields repaired and %d required fields missing"
 
 	MsgConfigInit = "Created new config for client id '%s' with token '%s'"
-	//MsgSecret     = "Refreshed the payload secret for podcast '%s': %s"
+	MsgSecret     = "Refreshed the webhook secret for podcast '%s': %s"
 )
 
 var (
@@ -41,9 +97,13 @@ var (
 	// ErrInvalidRoute indicates that the route and/or its parameters are not valid
 	ErrInvalidRoute = errors.New("invalid route")
 	// ErrMissingPayloadSecret indicates that no secret was provided
-	//ErrMissingPayloadSecret = errors.New("missing payload secret")
+	ErrMissingPayloadSecret = errors.New("missing payload secret")
+	// ErrInvalidPayloadSignature indicates that the webhook payload could not be verified
+	ErrInvalidPayloadSignature = errors.New("invalid payload signature")
 	// ErrUnsupportedWebhookEvent indicates that the wrong type of webhook was received
 	ErrUnsupportedWebhookEvent = errors.New("unsupported webhook")
+	// ErrMissingWebhookSecret indicates that the show has no webhook secret yet, see 'po webhook'
+	ErrMissingWebhookSecret = errors.New("missing webhook secret")
 
 	// ErrInvalidResourceName indicates that the resource name is invalid
 	ErrInvalidResourceName = errors.New("invalid resource name")
@@ -51,8 +111,12 @@ var (
 	ErrMissingResourceName = errors.New("missing resource type")
 	// ErrResourceNotFound indicates that the resource does not exist
 	ErrResourceNotFound = errors.New("resource does not exist")
-	// ErrResourceExists indicates that the resource does not exist
-	//ErrResourceExists = errors.New("resource already exists")
+	// ErrResourceExists indicates that the resource already exists
+	ErrResourceExists = errors.New("resource already exists")
+	// ErrNameExists indicates that the name is already used by another show
+	ErrNameExists = errors.New("name already exists")
+	// ErrShowDeleted indicates that the show was deleted
+	ErrShowDeleted = errors.New("show deleted")
 	// ErrInvalidGUID indicates that the GUID is invalid
 	ErrInvalidGUID = errors.New("invalid GUID")
 	// ErrInvalidParameters indicates that parameters used in an API call are not valid
@@ -69,6 +133,36 @@ var (
 	// ErrBuildNoEpisodes indicates that no episodes could be found
 	ErrBuildNoEpisodes = errors.New("missing episodes")
 
+	// ErrLintFailed indicates that a resource has errors
+	ErrLintFailed = errors.New("lint failed")
+
+	// ErrImportFailed indicates that not all feeds could be imported
+	ErrImportFailed = errors.New("import failed")
+	// ErrImportNotStrict indicates that a feed could not be imported without changes
+	ErrImportNotStrict = errors.New("the feed can not be imported as is")
+	// ErrImportSingleFile indicates that a repository with all resources in show.yaml can not be updated
+	ErrImportSingleFile = errors.New("single-file repositories can not be updated, import the feed again without --single-file")
+
+	// ErrSyncFailed indicates that not all resources could be uploaded
+	ErrSyncFailed = errors.New("sync failed")
+	// ErrNotConfirmed indicates that the user did not confirm a destructive operation
+	ErrNotConfirmed = errors.New("not confirmed, use --force to skip the confirmation")
+
+	// ErrVerificationFailed indicates that a downloaded file does not match its metadata
+	ErrVerificationFailed = errors.New("verification failed")
+
 	// ErrAssembleNoResources indicates that no resources could be found
 	ErrAssembleNoResources = errors.New("missing resource cache")
+
+	// ErrRateLimitExceeded indicates that a token made too many requests
+	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+	// ErrQuotaExceeded indicates that an upload would exceed the show's storage quota
+	ErrQuotaExceeded = errors.New("storage quota exceeded")
+	// ErrMissingContentLength indicates that the size of an upload is unknown
+	ErrMissingContentLength = errors.New("missing content length")
+
+	// ErrUnknownStorageBackend indicates that the configured storage backend does not exist
+	ErrUnknownStorageBackend = errors.New("unknown storage backend")
+	// ErrMissingStorageConfig indicates that the storage backend is not fully configured
+	ErrMissingStorageConfig = errors.New("missing storage configuration")
 )
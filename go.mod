@@ -3,7 +3,9 @@ module github.com/podops/podops
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.1.0
 	github.com/Bytom/bytom v1.1.1
+	github.com/andybalholm/brotli v1.0.4
 	github.com/bytom/bytom v1.1.1 // indirect
 	github.com/caddyserver/caddy/v2 v2.5.2
 	github.com/go-git/go-git/v5 v5.4.2
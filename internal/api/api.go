@@ -2,11 +2,9 @@ package api
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"os"
-	"path/filepath"
 
-	"github.com/google/go-github/github"
 	"github.com/labstack/echo/v4"
 
 	"github.com/txsvc/httpservice/pkg/api"
@@ -17,6 +15,8 @@ import (
 	"github.com/podops/podops/config"
 	"github.com/podops/podops/internal"
 	"github.com/podops/podops/internal/cdn"
+	"github.com/podops/podops/internal/notify"
+	"github.com/podops/podops/internal/storage"
 )
 
 const (
@@ -30,8 +30,17 @@ const (
 	AssetRoute       = "/asset/:parent"
 	AssetDeleteRoute = "/asset/:parent/:asset"
 
-	// WebhookRoute route to recieve call-back notifications
-	WebhookRoute = "/static/:parent"
+	// WebhookRoute route to recieve call-back notifications: WebhookEndpoint, WebhookSecretEndpoint
+	WebhookRoute         = "/static/:parent"
+	WebhookProviderRoute = "/static/:parent/:provider"
+	WebhookSecretRoute   = "/webhook/:parent"
+
+	// BuildRoute route to build related endpoints: BuildListEndpoint, BuildStatusEndpoint
+	BuildRoute       = "/build/:parent"
+	BuildStatusRoute = "/build/:parent/:build"
+
+	// NotifyRoute route to NotifyListEndpoint, NotifyUpdateEndpoint
+	NotifyRoute = "/notify/:parent"
 
 	UploadFormName = "asset"
 )
@@ -44,7 +53,7 @@ func MeterAPIRequest(ctx context.Context, req *http.Request, parent, api string)
 	// FIXME observer.Meter(context.TODO(), api, "production", parent, "uri", req.RequestURI, "user-agent", req.UserAgent(), "remote_addr", req.RemoteAddr)
 }
 
-// InitEndpoints creates a new show namespace on the CDN
+// InitEndpoints creates a new show namespace on the CDN. The optional ?name= reserves the show's canonical name.
 func InitEndpoint(c echo.Context) error {
 	ctx := context.Background()
 
@@ -65,18 +74,18 @@ func InitEndpoint(c echo.Context) error {
 
 	MeterAPIRequest(ctx, c.Request(), parent, "api.show.init")
 
-	// validate the non-existence of the target location first
-	path := filepath.Join(config.StorageLocation, parent)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		// create the location
-		err = os.MkdirAll(path, os.ModePerm)
-		if err != nil {
-			return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	// the show's name is reserved, so that no other show can take it
+	name := c.QueryParam("name")
+	if name != "" {
+		if !podops.ValidName(name) {
+			return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidResourceName)
+		}
+		if guid, ok := cdn.ResolveName(name); ok && guid != parent {
+			return api.ErrorResponse(c, http.StatusConflict, podops.ErrNameExists)
 		}
 	}
 
 	// create a new secret token
-	secretPath := filepath.Join(path, config.DefaultMasterKeyFileLocation)
 	cfg := settings.DialSettings{
 		Endpoint:        config.Settings().Endpoint,
 		DefaultEndpoint: config.Settings().DefaultEndpoint,
@@ -91,11 +100,18 @@ func InitEndpoint(c echo.Context) error {
 	config.WithServiceEndpoint(config.Settings().GetOption(config.PodopsServiceEndpointEnv)).Apply(&cfg)
 	config.WithContentEndpoint(config.Settings().GetOption(config.PodopsContentEndpointEnv)).Apply(&cfg)
 
-	if err := cfg.WriteToFile(secretPath); err != nil {
+	if err := cdn.WriteCredentials(ctx, parent, &cfg); err != nil {
 		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
 	}
+	if name != "" {
+		if err := cdn.ReserveName(ctx, parent, name); err != nil {
+			if errors.Is(err, podops.ErrNameExists) {
+				return api.ErrorResponse(c, http.StatusConflict, err)
+			}
+			return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+		}
+	}
 
-	cdn.MarkStorageChanged() // make sure we earmark the content direcory as "changed"
 	auth.RegisterAuthorization(&cfg)
 
 	return api.StandardResponse(c, http.StatusOK, cfg)
@@ -122,17 +138,34 @@ func AssetUploadEndpoint(c echo.Context) error {
 	MeterAPIRequest(ctx, c.Request(), parent, "api.asset.upload")
 
 	// validate the existence of the target location first in order to prevent random uploads
-	contentRoot := filepath.Join(config.StorageLocation, parent)
-	if _, err := os.Stat(contentRoot); os.IsNotExist(err) {
+	if !cdn.ShowExists(ctx, parent) {
 		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidGUID)
 	}
+	if cdn.IsDeleted(parent) {
+		return api.ErrorResponse(c, http.StatusGone, podops.ErrShowDeleted)
+	}
+
+	// enforce the storage quota before anything is written to the storage
+	if status, err := checkQuota(ctx, parent, c.Request().ContentLength); err != nil {
+		return api.ErrorResponse(c, status, err)
+	}
+
+	// remember the published episodes before the feed might get replaced
+	notify.Prepare(ctx, parent)
 
-	_, err = cdn.ReceiveFileUpload(ctx, c.Request(), contentRoot, UploadFormName)
+	name, err := cdn.ReceiveFileUpload(ctx, c.Request(), parent, UploadFormName)
 	if err != nil {
 		return api.ErrorResponse(c, http.StatusInternalServerError, err)
 	}
 
-	cdn.MarkStorageChanged() // make sure we earmark the content direcory as "changed"
+	// the feed is uploaded last, i.e. the show was published
+	if name == config.DefaultFeedName {
+		// tell the CDN about the new feed
+		if err := cdn.PublishChange(ctx, parent); err != nil {
+			return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+		}
+		notify.Publish(ctx, parent) // notifications are best effort only, ignore errors
+	}
 
 	return api.StandardResponse(c, http.StatusOK, nil)
 }
@@ -158,17 +191,23 @@ func AssetListEndpoint(c echo.Context) error {
 	MeterAPIRequest(ctx, c.Request(), parent, "api.asset.list")
 
 	// validate the existence of the target location
-	contentRoot := filepath.Join(config.StorageLocation, parent)
-	if _, err := os.Stat(contentRoot); os.IsNotExist(err) {
+	if !cdn.ShowExists(ctx, parent) {
 		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidGUID)
 	}
 
-	r, err := cdn.ListResources(ctx, contentRoot)
+	r, err := cdn.ListResources(ctx, parent)
 	if err != nil {
 		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
 	}
 
-	return api.StandardResponse(c, http.StatusOK, r)
+	usage, err := cdn.StorageUsage(ctx, parent)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+	list := AssetList{Assets: *r, Quota: newQuota(usage)}
+	setQuotaHeaders(c, list.Quota)
+
+	return api.StandardResponse(c, http.StatusOK, &list)
 }
 
 // AssetDeleteEndpoint removes a media asset from the CDN
@@ -196,58 +235,77 @@ func AssetDeleteEndpoint(c echo.Context) error {
 	MeterAPIRequest(ctx, c.Request(), parent, "api.asset.delete")
 
 	// validate the existence of the target location
-	assetPath := filepath.Join(config.StorageLocation, parent, asset)
-	if _, err := os.Stat(assetPath); os.IsNotExist(err) {
+	key := storage.Key(parent, asset)
+	if !storage.Exists(ctx, storage.Default(), key) {
 		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidResourceName)
 	}
 
-	err = os.RemoveAll(assetPath)
+	err = storage.Default().Delete(ctx, key)
 	if err != nil {
 		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
 	}
 
+	// without a feed, the show is no longer served
+	if asset == config.DefaultFeedName {
+		if err := cdn.PublishChange(ctx, parent); err != nil {
+			return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+		}
+	}
+
 	return nil
 }
 
-func WebhookGithubEndpoint(c echo.Context) error {
+// BuildListEndpoint returns the recent server-side builds of a show
+func BuildListEndpoint(c echo.Context) error {
 	ctx := context.Background()
 
-	// no basic auth here, we use the github secret
+	// basic auth validation
+	cfg, err := auth.CheckAuthorization(ctx, c, config.ScopeContentRead)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusUnauthorized, err)
+	}
 
 	parent := c.Param("parent")
 	if parent == "" {
 		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidRoute)
 	}
+	if !canAccessContent(cfg.Credentials.ProjectID, parent, config.ScopeContentRead) {
+		return api.ErrorResponse(c, http.StatusUnauthorized, nil)
+	}
 
-	MeterAPIRequest(ctx, c.Request(), parent, "api.webhook.push")
+	MeterAPIRequest(ctx, c.Request(), parent, "api.build.list")
 
-	// find the secret key
-	cfg, err := settings.ReadSettingsFromFile(filepath.Join(config.StorageLocation, parent, config.DefaultMasterKeyFileLocation))
-	if err != nil {
-		return api.ErrorResponse(c, http.StatusInternalServerError, err)
-	}
+	return api.StandardResponse(c, http.StatusOK, cdn.ListBuilds(parent))
+}
 
-	// parse and validate the github payload
-	payload, err := github.ValidatePayload(c.Request(), []byte(cfg.Credentials.Token))
+// BuildStatusEndpoint returns the status and log of a server-side build
+func BuildStatusEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	// basic auth validation
+	cfg, err := auth.CheckAuthorization(ctx, c, config.ScopeContentRead)
 	if err != nil {
-		return api.ErrorResponse(c, http.StatusInternalServerError, err)
+		return api.ErrorResponse(c, http.StatusUnauthorized, err)
 	}
 
-	event, err := github.ParseWebHook(github.WebHookType(c.Request()), payload)
-	if err != nil {
-		return api.ErrorResponse(c, http.StatusInternalServerError, err)
+	parent := c.Param("parent")
+	if parent == "" {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidRoute)
+	}
+	build := c.Param("build")
+	if build == "" {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidRoute)
+	}
+	if !canAccessContent(cfg.Credentials.ProjectID, parent, config.ScopeContentRead) {
+		return api.ErrorResponse(c, http.StatusUnauthorized, nil)
 	}
 
-	switch e := event.(type) {
-	case *github.PushEvent:
-		repo := *e.Repo.URL
-		if err := cdn.CloneOrPullRepo(repo, parent); err != nil {
-			return api.ErrorResponse(c, http.StatusInternalServerError, err)
-		}
-	default:
-		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrUnsupportedWebhookEvent)
+	MeterAPIRequest(ctx, c.Request(), parent, "api.build.get")
+
+	status, ok := cdn.LookupBuild(parent, build)
+	if !ok {
+		return api.ErrorResponse(c, http.StatusNotFound, podops.ErrResourceNotFound)
 	}
 
-	// do something
-	return api.StandardResponse(c, http.StatusOK, nil)
+	return api.StandardResponse(c, http.StatusOK, status)
 }
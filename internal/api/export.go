@@ -0,0 +1,68 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/txsvc/httpservice/pkg/api"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/auth"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/cdn"
+)
+
+const (
+	// ExportRoute route to ExportEndpoint
+	ExportRoute = "/export/:parent"
+
+	// HeaderDigest is the trailer with the SHA-256 of an export archive, e.g. 'sha-256=X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE='
+	HeaderDigest = "Digest"
+	// DigestPrefix precedes the base64 encoded SHA-256 in HeaderDigest
+	DigestPrefix = "sha-256="
+)
+
+// ExportEndpoint streams a tar.gz archive of a show's storage
+func ExportEndpoint(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// basic auth validation
+	cfg, err := auth.CheckAuthorization(ctx, c, config.ScopeContentRead)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusUnauthorized, err)
+	}
+
+	parent := c.Param("parent")
+	if parent == "" {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidRoute)
+	}
+	if !canAccessContent(cfg.Credentials.ProjectID, parent, config.ScopeContentRead) {
+		return api.ErrorResponse(c, http.StatusUnauthorized, nil)
+	}
+
+	MeterAPIRequest(ctx, c.Request(), parent, "api.show.export")
+
+	// validate the existence of the target location
+	if !cdn.ShowExists(ctx, parent) {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidGUID)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/gzip")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%s.tar.gz", parent))
+	// the archive is created on the fly, its digest is only known at the end
+	c.Response().Header().Set("Trailer", HeaderDigest)
+	c.Response().WriteHeader(http.StatusOK)
+
+	// the status is already sent, an error can only abort the stream
+	h := sha256.New()
+	if err := cdn.ExportArchive(ctx, parent, io.MultiWriter(c.Response(), h)); err != nil {
+		return err
+	}
+	c.Response().Header().Set(HeaderDigest, DigestPrefix+base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	return nil
+}
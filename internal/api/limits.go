@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/txsvc/httpservice/pkg/api"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/auth"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/cdn"
+	"github.com/podops/podops/internal/metadata"
+)
+
+const (
+	// headers used to expose a show's quota and its current usage
+	HeaderQuotaBytes     = "X-Quota-Bytes"
+	HeaderQuotaBytesUsed = "X-Quota-Bytes-Used"
+	HeaderQuotaFiles     = "X-Quota-Files"
+	HeaderQuotaFilesUsed = "X-Quota-Files-Used"
+
+	maxIdleBuckets = 1024 // number of buckets before idle ones are evicted
+)
+
+type (
+	// AssetList is the response of AssetListEndpoint
+	AssetList struct {
+		Assets []metadata.Metadata `json:"assets"`
+		Quota  Quota               `json:"quota"`
+	}
+
+	// Quota describes a show's storage quota and its current usage. A limit of 0 means unlimited.
+	Quota struct {
+		Bytes     int64 `json:"bytes"`
+		BytesUsed int64 `json:"bytes_used"`
+		Files     int64 `json:"files"`
+		FilesUsed int64 `json:"files_used"`
+	}
+
+	// rateLimiter implements a simple token bucket per API token
+	rateLimiter struct {
+		rate    float64 // tokens per second
+		burst   float64
+		buckets map[string]*bucket
+		mu      sync.Mutex
+	}
+
+	bucket struct {
+		tokens float64
+		last   time.Time
+	}
+)
+
+var (
+	limiter *rateLimiter
+)
+
+func init() {
+	limiter = newRateLimiter(config.RateLimit, config.RateBurst)
+}
+
+func newRateLimiter(rate, burst int64) *rateLimiter {
+	if burst < rate {
+		burst = rate
+	}
+	return &rateLimiter{
+		rate:    float64(rate),
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes one token from the key's bucket. If the bucket is empty, the function
+// returns false and the time until the next token becomes available.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	if l.rate <= 0 {
+		return true, 0 // rate limiting is disabled
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxIdleBuckets {
+			l.evict(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	// refill the bucket
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+
+	return true, 0
+}
+
+// evict removes all buckets that would be full by now
+func (l *rateLimiter) evict(now time.Time) {
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// RateLimitMiddleware limits the number of requests per second a bearer token can make.
+// Requests without a token are passed on, the endpoints reject them anyways.
+func RateLimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, err := auth.GetBearerToken(c.Request())
+		if err != nil {
+			return next(c)
+		}
+
+		if ok, wait := limiter.allow(token, time.Now()); !ok {
+			c.Response().Header().Set("Retry-After", fmt.Sprintf("%d", int64(math.Ceil(wait.Seconds()))))
+			return api.ErrorResponse(c, http.StatusTooManyRequests, podops.ErrRateLimitExceeded)
+		}
+
+		return next(c)
+	}
+}
+
+// checkQuota verifies that an upload of size bytes fits into the show's storage quota
+func checkQuota(ctx context.Context, parent string, size int64) (int, error) {
+	if config.QuotaBytes <= 0 && config.QuotaFiles <= 0 {
+		return http.StatusOK, nil
+	}
+	if size < 0 {
+		return http.StatusLengthRequired, podops.ErrMissingContentLength
+	}
+
+	usage, err := cdn.StorageUsage(ctx, parent)
+	if err != nil {
+		return http.StatusInternalServerError, podops.ErrInternalError
+	}
+	if usage.Exceeds(size, config.QuotaBytes, config.QuotaFiles) {
+		return http.StatusRequestEntityTooLarge, podops.ErrQuotaExceeded
+	}
+
+	return http.StatusOK, nil
+}
+
+// newQuota returns the show's quota with its current usage
+func newQuota(usage *cdn.Usage) Quota {
+	return Quota{
+		Bytes:     config.QuotaBytes,
+		BytesUsed: usage.Bytes,
+		Files:     config.QuotaFiles,
+		FilesUsed: usage.Files,
+	}
+}
+
+// setQuotaHeaders adds the show's quota and its current usage to the response
+func setQuotaHeaders(c echo.Context, quota Quota) {
+	h := c.Response().Header()
+	h.Set(HeaderQuotaBytes, fmt.Sprintf("%d", quota.Bytes))
+	h.Set(HeaderQuotaBytesUsed, fmt.Sprintf("%d", quota.BytesUsed))
+	h.Set(HeaderQuotaFiles, fmt.Sprintf("%d", quota.Files))
+	h.Set(HeaderQuotaFilesUsed, fmt.Sprintf("%d", quota.FilesUsed))
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops/internal/cdn"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 4)
+	now := time.Now()
+
+	// the burst is available right away
+	for i := 0; i < 4; i++ {
+		ok, _ := l.allow("token", now)
+		assert.True(t, ok)
+	}
+	ok, wait := l.allow("token", now)
+	assert.False(t, ok)
+	assert.Greater(t, wait, time.Duration(0))
+
+	// other tokens are not affected
+	ok, _ = l.allow("other", now)
+	assert.True(t, ok)
+
+	// refill after 500ms with 2 tokens/s
+	ok, _ = l.allow("token", now.Add(500*time.Millisecond))
+	assert.True(t, ok)
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	l := newRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		ok, _ := l.allow("token", time.Now())
+		assert.True(t, ok)
+	}
+}
+
+func TestUsageExceeds(t *testing.T) {
+	u := cdn.Usage{Bytes: 100, Files: 2}
+
+	assert.False(t, u.Exceeds(1000, 0, 0))
+	assert.False(t, u.Exceeds(50, 150, 3))
+	assert.True(t, u.Exceeds(51, 150, 0))
+	assert.True(t, u.Exceeds(1, 0, 2))
+}
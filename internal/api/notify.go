@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/txsvc/httpservice/pkg/api"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/auth"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/cdn"
+	"github.com/podops/podops/internal/notify"
+)
+
+// NotifyListEndpoint returns the show's webhook subscriptions. Secrets are not included.
+func NotifyListEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	// basic auth validation
+	cfg, err := auth.CheckAuthorization(ctx, c, config.ScopeContentRead)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusUnauthorized, err)
+	}
+
+	parent := c.Param("parent")
+	if parent == "" {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidRoute)
+	}
+	if !canAccessContent(cfg.Credentials.ProjectID, parent, config.ScopeContentRead) {
+		return api.ErrorResponse(c, http.StatusUnauthorized, nil)
+	}
+
+	MeterAPIRequest(ctx, c.Request(), parent, "api.notify.list")
+
+	if !cdn.ShowExists(ctx, parent) {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidGUID)
+	}
+
+	subs, err := notify.LoadSubscriptions(ctx, parent)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+
+	return api.StandardResponse(c, http.StatusOK, subs)
+}
+
+// NotifyUpdateEndpoint replaces the show's webhook subscriptions
+func NotifyUpdateEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	// basic auth validation
+	cfg, err := auth.CheckAuthorization(ctx, c, config.ScopeContentWrite)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusUnauthorized, err)
+	}
+
+	parent := c.Param("parent")
+	if parent == "" {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidRoute)
+	}
+	if !canAccessContent(cfg.Credentials.ProjectID, parent, config.ScopeContentWrite) {
+		return api.ErrorResponse(c, http.StatusUnauthorized, nil)
+	}
+
+	MeterAPIRequest(ctx, c.Request(), parent, "api.notify.update")
+
+	if !cdn.ShowExists(ctx, parent) {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidGUID)
+	}
+
+	var subs []notify.Subscription
+	if err := c.Bind(&subs); err != nil {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidParameters)
+	}
+	for _, s := range subs {
+		if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidParameters)
+		}
+	}
+
+	if err := notify.WriteSubscriptions(ctx, parent, subs); err != nil {
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+
+	return api.StandardResponse(c, http.StatusOK, nil)
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// OpenAPIRoute route to OpenAPIEndpoint
+	OpenAPIRoute = "/openapi.yaml"
+)
+
+var (
+	//go:embed openapi.yaml
+	openAPISpec []byte
+)
+
+// OpenAPIEndpoint returns the OpenAPI specification of the API
+func OpenAPIEndpoint(c echo.Context) error {
+	return c.Blob(http.StatusOK, "application/yaml", openAPISpec)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/txsvc/httpservice/pkg/api"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/cdn"
+)
+
+const (
+	// RedirectRoute route to RedirectListEndpoint, RedirectUpdateEndpoint
+	RedirectRoute = "/redirect/:parent"
+)
+
+// RedirectListEndpoint returns the show's feed redirects
+func RedirectListEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	parent, status, err := authorizeShow(ctx, c, config.ScopeContentRead, "api.redirect.list")
+	if err != nil {
+		return api.ErrorResponse(c, status, err)
+	}
+
+	redirects, err := cdn.LoadRedirects(ctx, parent)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+
+	return api.StandardResponse(c, http.StatusOK, redirects)
+}
+
+// RedirectUpdateEndpoint replaces the show's feed redirects
+func RedirectUpdateEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	parent, status, err := authorizeShow(ctx, c, config.ScopeContentWrite, "api.redirect.update")
+	if err != nil {
+		return api.ErrorResponse(c, status, err)
+	}
+
+	var redirects []cdn.Redirect
+	if err := c.Bind(&redirects); err != nil {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidParameters)
+	}
+
+	// a show can only redirect its own names
+	for _, r := range redirects {
+		if guid, ok := cdn.ResolveName(r.From); ok && guid != parent {
+			return api.ErrorResponse(c, http.StatusConflict, podops.ErrNameExists)
+		}
+	}
+
+	if err := cdn.WriteRedirects(ctx, parent, redirects); err != nil {
+		if errors.Is(err, podops.ErrInvalidResourceName) || errors.Is(err, podops.ErrInvalidParameters) {
+			return api.ErrorResponse(c, http.StatusBadRequest, err)
+		}
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+
+	return api.StandardResponse(c, http.StatusOK, nil)
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/txsvc/httpservice/pkg/api"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/auth"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/cdn"
+)
+
+const (
+	// ShowRoute route to ShowListEndpoint
+	ShowRoute = "/show"
+	// ShowDeleteRoute route to ShowDeleteEndpoint
+	ShowDeleteRoute = "/show/:parent"
+	// ShowRestoreRoute route to ShowRestoreEndpoint
+	ShowRestoreRoute = "/show/:parent/restore"
+	// ShowTransferRoute route to ShowTransferEndpoint
+	ShowTransferRoute = "/show/:parent/owner/:userid"
+	// ShowRenameRoute route to ShowRenameEndpoint
+	ShowRenameRoute = "/show/:parent/name/:name"
+	// ShowConflictRoute route to ShowConflictEndpoint
+	ShowConflictRoute = "/show/conflicts"
+)
+
+// ShowListEndpoint returns the shows visible to the token. Admins see all shows,
+// optionally filtered by ?owner=, everybody else only sees their own show.
+func ShowListEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	// basic auth validation
+	cfg, err := auth.CheckAuthorization(ctx, c, config.ScopeContentRead)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusUnauthorized, err)
+	}
+
+	MeterAPIRequest(ctx, c.Request(), cfg.Credentials.ProjectID, "api.show.list")
+
+	if auth.HasScope(cfg, config.ScopeContentAdmin) {
+		shows, err := cdn.ListShows(ctx, c.QueryParam("owner"))
+		if err != nil {
+			return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+		}
+		return api.StandardResponse(c, http.StatusOK, shows)
+	}
+
+	shows := make([]cdn.ShowInfo, 0)
+	if info, err := cdn.LookupShow(ctx, cfg.Credentials.ProjectID); err == nil {
+		shows = append(shows, *info)
+	}
+	return api.StandardResponse(c, http.StatusOK, shows)
+}
+
+// ShowConflictEndpoint returns the names that are claimed by more than one show. Admins only.
+func ShowConflictEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	// basic auth validation
+	cfg, err := auth.CheckAuthorization(ctx, c, config.ScopeContentAdmin)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusUnauthorized, err)
+	}
+
+	MeterAPIRequest(ctx, c.Request(), cfg.Credentials.ProjectID, "api.show.conflicts")
+
+	return api.StandardResponse(c, http.StatusOK, cdn.ListConflicts())
+}
+
+// ShowDeleteEndpoint soft-deletes a show. It is removed for good after config.DeleteRetention days.
+func ShowDeleteEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	parent, status, err := authorizeShow(ctx, c, config.ScopeContentWrite, "api.show.delete")
+	if err != nil {
+		return api.ErrorResponse(c, status, err)
+	}
+
+	if err := cdn.DeleteShow(ctx, parent); err != nil {
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+
+	return api.StandardResponse(c, http.StatusOK, nil)
+}
+
+// ShowRestoreEndpoint reverts the soft-delete of a show
+func ShowRestoreEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	parent, status, err := authorizeShow(ctx, c, config.ScopeContentWrite, "api.show.restore")
+	if err != nil {
+		return api.ErrorResponse(c, status, err)
+	}
+
+	if err := cdn.RestoreShow(ctx, parent); err != nil {
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+
+	return api.StandardResponse(c, http.StatusOK, nil)
+}
+
+// ShowTransferEndpoint makes another user the owner of a show and returns the show's new credentials
+func ShowTransferEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	parent, status, err := authorizeShow(ctx, c, config.ScopeContentAdmin, "api.show.transfer")
+	if err != nil {
+		return api.ErrorResponse(c, status, err)
+	}
+
+	userid := c.Param("userid")
+	if userid == "" {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidRoute)
+	}
+
+	cfg, err := cdn.TransferShow(ctx, parent, userid)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+
+	return api.StandardResponse(c, http.StatusOK, cfg)
+}
+
+// ShowRenameEndpoint changes the canonical name of a show. The old name keeps working.
+func ShowRenameEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	parent, status, err := authorizeShow(ctx, c, config.ScopeContentWrite, "api.show.rename")
+	if err != nil {
+		return api.ErrorResponse(c, status, err)
+	}
+
+	if err := cdn.RenameShow(ctx, parent, c.Param("name")); err != nil {
+		if errors.Is(err, podops.ErrInvalidResourceName) {
+			return api.ErrorResponse(c, http.StatusBadRequest, err)
+		}
+		if errors.Is(err, podops.ErrNameExists) {
+			return api.ErrorResponse(c, http.StatusConflict, err)
+		}
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+
+	return api.StandardResponse(c, http.StatusOK, nil)
+}
+
+// authorizeShow validates the token, the access to the show in the route and the
+// existence of the show. Admins can manage all shows, everybody else only their own.
+// It returns the show's GUID or the status and error to respond with.
+func authorizeShow(ctx context.Context, c echo.Context, scope, metric string) (string, int, error) {
+	cfg, err := auth.CheckAuthorization(ctx, c, scope)
+	if err != nil {
+		return "", http.StatusUnauthorized, err
+	}
+
+	parent := c.Param("parent")
+	if parent == "" {
+		return "", http.StatusBadRequest, podops.ErrInvalidRoute
+	}
+	if !auth.HasScope(cfg, config.ScopeContentAdmin) && !canAccessContent(cfg.Credentials.ProjectID, parent, scope) {
+		return "", http.StatusUnauthorized, auth.ErrNotAuthorized
+	}
+
+	MeterAPIRequest(ctx, c.Request(), parent, metric)
+
+	if !cdn.ShowExists(ctx, parent) {
+		return "", http.StatusNotFound, podops.ErrResourceNotFound
+	}
+
+	return parent, http.StatusOK, nil
+}
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/labstack/echo/v4"
+
+	"github.com/txsvc/httpservice/pkg/api"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/auth"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal"
+	"github.com/podops/podops/internal/cdn"
+	"github.com/podops/podops/internal/notify"
+	"github.com/podops/podops/internal/storage"
+)
+
+const (
+	// supported webhook providers
+	WebhookProviderGithub  = "github"
+	WebhookProviderGitlab  = "gitlab"
+	WebhookProviderGitea   = "gitea"
+	WebhookProviderGeneric = "generic"
+
+	// HeaderWebhookSignature is the HMAC-SHA256 signature of a generic webhook payload, e.g. 'sha256=<hex>'
+	HeaderWebhookSignature = notify.HeaderSignature
+
+	maxWebhookPayload = 5 << 20 // 5MB
+)
+
+type (
+	// WebhookParserFunc verifies a webhook request and returns the url of the repository that
+	// was pushed to. An empty url indicates an event that can safely be ignored, e.g. a ping.
+	WebhookParserFunc func(req *http.Request, secret []byte) (string, error)
+
+	// WebhookSecret is the response of WebhookSecretEndpoint
+	WebhookSecret struct {
+		Secret string `json:"secret"`
+	}
+
+	// GenericPushEvent is the payload of a generic webhook
+	GenericPushEvent struct {
+		Repo string `json:"repo"`
+	}
+)
+
+var (
+	webhookProviders map[string]WebhookParserFunc
+)
+
+func init() {
+	webhookProviders = make(map[string]WebhookParserFunc)
+	webhookProviders[WebhookProviderGithub] = parseGithubWebhook
+	webhookProviders[WebhookProviderGitlab] = parseGitlabWebhook
+	webhookProviders[WebhookProviderGitea] = parseGiteaWebhook
+	webhookProviders[WebhookProviderGeneric] = parseGenericWebhook
+}
+
+// WebhookEndpoint receives push notifications and schedules a build of the show. The provider is
+// either part of the route or detected from the request headers.
+func WebhookEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	// no basic auth here, we use the webhook secret
+
+	parent := c.Param("parent")
+	if parent == "" {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidRoute)
+	}
+
+	provider := c.Param("provider")
+	if provider == "" {
+		provider = detectWebhookProvider(c.Request())
+	}
+	parser, ok := webhookProviders[provider]
+	if !ok {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrUnsupportedWebhookEvent)
+	}
+
+	MeterAPIRequest(ctx, c.Request(), parent, "api.webhook.push")
+
+	secret, err := webhookSecret(ctx, parent, provider)
+	if err != nil {
+		if err == podops.ErrMissingWebhookSecret {
+			return api.ErrorResponse(c, http.StatusUnauthorized, err)
+		}
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidGUID)
+	}
+
+	repo, err := parser(c.Request(), secret)
+	if err != nil {
+		if err == podops.ErrInvalidPayloadSignature || err == podops.ErrMissingPayloadSecret {
+			return api.ErrorResponse(c, http.StatusUnauthorized, err)
+		}
+		return api.ErrorResponse(c, http.StatusBadRequest, err)
+	}
+	if repo == "" {
+		return api.StandardResponse(c, http.StatusOK, nil)
+	}
+
+	// pull, build and publish the repository in the background
+	status := cdn.EnqueueBuild(repo, parent)
+	return api.StandardResponse(c, http.StatusAccepted, status)
+}
+
+// WebhookSecretEndpoint creates a new webhook secret for a show, replacing the old one
+func WebhookSecretEndpoint(c echo.Context) error {
+	ctx := context.Background()
+
+	// basic auth validation
+	cfg, err := auth.CheckAuthorization(ctx, c, config.ScopeContentWrite)
+	if err != nil {
+		return api.ErrorResponse(c, http.StatusUnauthorized, err)
+	}
+
+	parent := c.Param("parent")
+	if parent == "" {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidRoute)
+	}
+	if !canAccessContent(cfg.Credentials.ProjectID, parent, config.ScopeContentWrite) {
+		return api.ErrorResponse(c, http.StatusUnauthorized, nil)
+	}
+
+	MeterAPIRequest(ctx, c.Request(), parent, "api.webhook.secret")
+
+	if !cdn.ShowExists(ctx, parent) {
+		return api.ErrorResponse(c, http.StatusBadRequest, podops.ErrInvalidGUID)
+	}
+
+	secret := WebhookSecret{Secret: internal.CreateSimpleToken()}
+	if err := storage.WriteFile(ctx, storage.Default(), storage.Key(parent, config.DefaultWebhookKeyFileLocation), []byte(secret.Secret)); err != nil {
+		return api.ErrorResponse(c, http.StatusInternalServerError, podops.ErrInternalError)
+	}
+
+	return api.StandardResponse(c, http.StatusOK, secret)
+}
+
+// detectWebhookProvider guesses the webhook provider from the request headers
+func detectWebhookProvider(req *http.Request) string {
+	h := req.Header
+	switch {
+	case h.Get("X-GitHub-Event") != "":
+		return WebhookProviderGithub
+	case h.Get("X-Gitlab-Event") != "":
+		return WebhookProviderGitlab
+	case h.Get("X-Gitea-Event") != "", h.Get("X-Forgejo-Event") != "":
+		return WebhookProviderGitea
+	case h.Get(HeaderWebhookSignature) != "":
+		return WebhookProviderGeneric
+	}
+	return ""
+}
+
+// webhookSecret returns the show's webhook secret, see WebhookSecretEndpoint. Only GitHub, which never
+// sends the secret itself, falls back to the master token for shows without a webhook secret.
+func webhookSecret(ctx context.Context, parent, provider string) ([]byte, error) {
+	if secret, err := storage.ReadFile(ctx, storage.Default(), storage.Key(parent, config.DefaultWebhookKeyFileLocation)); err == nil {
+		return bytes.TrimSpace(secret), nil
+	}
+
+	cfg, err := cdn.ReadCredentials(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	if provider != WebhookProviderGithub {
+		return nil, podops.ErrMissingWebhookSecret
+	}
+	return []byte(cfg.Credentials.Token), nil
+}
+
+func parseGithubWebhook(req *http.Request, secret []byte) (string, error) {
+	payload, err := github.ValidatePayload(req, secret)
+	if err != nil {
+		return "", podops.ErrInvalidPayloadSignature
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(req), payload)
+	if err != nil {
+		return "", err
+	}
+
+	switch e := event.(type) {
+	case *github.PushEvent:
+		return *e.Repo.URL, nil
+	case *github.PingEvent:
+		return "", nil
+	}
+	return "", podops.ErrUnsupportedWebhookEvent
+}
+
+// parseGitlabWebhook handles GitLab push hooks. GitLab does not sign the payload
+// but sends the secret as-is in the X-Gitlab-Token header.
+func parseGitlabWebhook(req *http.Request, secret []byte) (string, error) {
+	token := req.Header.Get("X-Gitlab-Token")
+	if token == "" {
+		return "", podops.ErrMissingPayloadSecret
+	}
+	if subtle.ConstantTimeCompare([]byte(token), secret) != 1 {
+		return "", podops.ErrInvalidPayloadSignature
+	}
+	if req.Header.Get("X-Gitlab-Event") != "Push Hook" {
+		return "", podops.ErrUnsupportedWebhookEvent
+	}
+
+	var event struct {
+		Project struct {
+			GitHTTPURL string `json:"git_http_url"`
+		} `json:"project"`
+	}
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxWebhookPayload)).Decode(&event); err != nil {
+		return "", err
+	}
+	return event.Project.GitHTTPURL, nil
+}
+
+// parseGiteaWebhook handles Gitea and Forgejo push events, signed with a hex encoded HMAC-SHA256
+func parseGiteaWebhook(req *http.Request, secret []byte) (string, error) {
+	signature := req.Header.Get("X-Gitea-Signature")
+	event := req.Header.Get("X-Gitea-Event")
+	if signature == "" {
+		signature = req.Header.Get("X-Forgejo-Signature")
+		event = req.Header.Get("X-Forgejo-Event")
+	}
+
+	payload, err := validateSignature(req, secret, signature)
+	if err != nil {
+		return "", err
+	}
+	if event != "push" {
+		return "", podops.ErrUnsupportedWebhookEvent
+	}
+
+	var e struct {
+		Repository struct {
+			CloneURL string `json:"clone_url"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return "", err
+	}
+	return e.Repository.CloneURL, nil
+}
+
+// parseGenericWebhook handles a GenericPushEvent, signed with a hex encoded HMAC-SHA256 in the X-Podops-Signature header
+func parseGenericWebhook(req *http.Request, secret []byte) (string, error) {
+	payload, err := validateSignature(req, secret, strings.TrimPrefix(req.Header.Get(HeaderWebhookSignature), "sha256="))
+	if err != nil {
+		return "", err
+	}
+
+	var e GenericPushEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return "", err
+	}
+	if e.Repo == "" {
+		return "", podops.ErrInvalidParameters
+	}
+	return e.Repo, nil
+}
+
+// validateSignature reads the payload and compares its HMAC-SHA256 with the hex encoded signature
+func validateSignature(req *http.Request, secret []byte, signature string) ([]byte, error) {
+	if signature == "" {
+		return nil, podops.ErrMissingPayloadSecret
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, podops.ErrInvalidPayloadSignature
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(req.Body, maxWebhookPayload))
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, podops.ErrInvalidPayloadSignature
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/txsvc/stdlib/v2/settings"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/cdn"
+	"github.com/podops/podops/internal/storage"
+)
+
+const (
+	testSecret = "s3cr3t"
+	testRepo   = "https://git.example.com/podops/minimalpodcast.git"
+)
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestDetectWebhookProvider(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	assert.Empty(t, detectWebhookProvider(req))
+
+	req.Header.Set("X-Forgejo-Event", "push")
+	assert.Equal(t, WebhookProviderGitea, detectWebhookProvider(req))
+
+	req = httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Gitlab-Event", "Push Hook")
+	assert.Equal(t, WebhookProviderGitlab, detectWebhookProvider(req))
+}
+
+func TestGiteaWebhook(t *testing.T) {
+	payload := `{"repository":{"clone_url":"` + testRepo + `"}}`
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(payload))
+	req.Header.Set("X-Gitea-Event", "push")
+	req.Header.Set("X-Gitea-Signature", sign(payload))
+
+	repo, err := parseGiteaWebhook(req, []byte(testSecret))
+	assert.NoError(t, err)
+	assert.Equal(t, testRepo, repo)
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader(payload))
+	req.Header.Set("X-Gitea-Event", "push")
+	req.Header.Set("X-Gitea-Signature", sign("tampered"))
+
+	_, err = parseGiteaWebhook(req, []byte(testSecret))
+	assert.Equal(t, podops.ErrInvalidPayloadSignature, err)
+}
+
+func TestGitlabWebhook(t *testing.T) {
+	payload := `{"object_kind":"push","project":{"git_http_url":"` + testRepo + `"}}`
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(payload))
+	req.Header.Set("X-Gitlab-Event", "Push Hook")
+	req.Header.Set("X-Gitlab-Token", testSecret)
+
+	repo, err := parseGitlabWebhook(req, []byte(testSecret))
+	assert.NoError(t, err)
+	assert.Equal(t, testRepo, repo)
+
+	req.Header.Set("X-Gitlab-Token", "wrong")
+	_, err = parseGitlabWebhook(req, []byte(testSecret))
+	assert.Equal(t, podops.ErrInvalidPayloadSignature, err)
+}
+
+func TestGenericWebhook(t *testing.T) {
+	payload := `{"repo":"` + testRepo + `"}`
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(payload))
+	req.Header.Set(HeaderWebhookSignature, "sha256="+sign(payload))
+
+	repo, err := parseGenericWebhook(req, []byte(testSecret))
+	assert.NoError(t, err)
+	assert.Equal(t, testRepo, repo)
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader(payload))
+	_, err = parseGenericWebhook(req, []byte(testSecret))
+	assert.Equal(t, podops.ErrMissingPayloadSecret, err)
+}
+
+func TestWebhookSecret(t *testing.T) {
+	storage.SetDefault(storage.NewLocal(t.TempDir()))
+
+	parent := "aaa94297acfc"
+	cfg := settings.DialSettings{Credentials: &settings.Credentials{ProjectID: parent, UserID: "producer", Token: "master-token"}}
+	assert.NoError(t, cdn.WriteCredentials(context.TODO(), parent, &cfg))
+
+	// the master token is never sent to a provider in plaintext
+	for _, provider := range []string{WebhookProviderGitlab, WebhookProviderGitea, WebhookProviderGeneric} {
+		_, err := webhookSecret(context.TODO(), parent, provider)
+		assert.Equal(t, podops.ErrMissingWebhookSecret, err, provider)
+	}
+	secret, err := webhookSecret(context.TODO(), parent, WebhookProviderGithub)
+	assert.NoError(t, err)
+	assert.Equal(t, "master-token", string(secret))
+
+	assert.NoError(t, storage.WriteFile(context.TODO(), storage.Default(), storage.Key(parent, config.DefaultWebhookKeyFileLocation), []byte(testSecret+"\n")))
+	for _, provider := range []string{WebhookProviderGithub, WebhookProviderGitlab, WebhookProviderGitea, WebhookProviderGeneric} {
+		secret, err := webhookSecret(context.TODO(), parent, provider)
+		assert.NoError(t, err)
+		assert.Equal(t, testSecret, string(secret), provider)
+	}
+
+	_, err = webhookSecret(context.TODO(), "bbb94297acfc", WebhookProviderGithub)
+	assert.Error(t, err)
+}
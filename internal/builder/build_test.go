@@ -3,9 +3,17 @@ package builder
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/loader"
+	"github.com/podops/podops/internal/rss"
 )
 
 const (
@@ -55,3 +63,77 @@ func _TestAssemble(t *testing.T) {
 	assert.NoError(t, err)
 }
 */
+
+func TestBuildSingleFile(t *testing.T) {
+	ctx := context.TODO()
+	root := t.TempDir()
+
+	show := podops.DefaultShow("singlefilepodcast", "SINGLE FILE PODCAST", "SUMMARY", "a7c94297acfc", "https://example.com", "https://cdn.example.com")
+	for i, guid := range []string{"86124f7f9cf4", "95235f8f0ad5"} {
+		e := podops.DefaultEpisode(fmt.Sprintf("episode%d", i+1), "singlefilepodcast", guid, "a7c94297acfc", "https://example.com", "https://cdn.example.com")
+		e.Metadata.Date = time.Now().Add(-time.Hour).UTC().Format(time.RFC1123Z)
+		e.Description.Title = fmt.Sprintf("EPISODE %d", i+1)
+		show.Episodes = append(show.Episodes, e)
+	}
+	assert.NoError(t, loader.WriteResource(ctx, filepath.Join(root, "show.json"), show))
+
+	// the episodes are kept in the show's file
+	_, err := Build(ctx, root, true, false, true, false, false)
+	assert.NoError(t, err)
+
+	feed, err := os.ReadFile(filepath.Join(root, config.BuildLocation, config.DefaultFeedName))
+	assert.NoError(t, err)
+	assert.Contains(t, string(feed), "<title>EPISODE 1</title>")
+	assert.Contains(t, string(feed), "<title>EPISODE 2</title>")
+}
+
+func TestTransformToPodcast(t *testing.T) {
+	s := podops.DefaultShow("fidelitypodcast", "FIDELITY PODCAST", "SUMMARY", "a7c94297acfc", "https://example.com", "https://cdn.example.com")
+
+	// no hub by default
+	feed, err := transformToPodcast(s, false)
+	assert.NoError(t, err)
+	assert.Empty(t, feed.AtomLinks)
+
+	s.HubLink = &podops.AssetRef{URI: "https://pubsubhubbub.appspot.com/"}
+	feed, err = transformToPodcast(s, false)
+	assert.NoError(t, err)
+
+	xml := feed.String()
+	assert.Contains(t, xml, `<atom:link href="https://example.com/fidelitypodcast/feed.xml" rel="self" type="application/rss+xml"></atom:link>`)
+	assert.Contains(t, xml, `<atom:link href="https://pubsubhubbub.appspot.com/" rel="hub"></atom:link>`)
+}
+
+func TestTransformToItem(t *testing.T) {
+	e := podops.DefaultEpisode("firstepisode", "fidelitypodcast", "86124f7f9cf4", "a7c94297acfc", "https://example.com", "https://cdn.example.com")
+	e.Metadata.FeedGUID = "tag:example.com,2021:episode-1"
+	e.Description.EpisodeText = "<p>The long version</p>"
+	e.AlternateEnclosures = []podops.AssetRef{{URI: "https://example.com/episode1.ogg", Rel: podops.ResourceTypeExternal, Type: "audio/ogg", Size: 800}}
+	e.Podcast = []podops.PodcastTag{{Name: "transcript", Attrs: map[string]string{"url": "https://example.com/episode1.vtt", "type": "text/vtt"}}}
+
+	item, err := transformToItem(e, false)
+	assert.NoError(t, err)
+
+	feed := rss.New("Test", "https://example.com", nil, nil)
+	_, err = feed.AddItem(item)
+	assert.NoError(t, err)
+	xml := feed.String()
+
+	assert.Contains(t, xml, `xmlns:podcast="https://podcastindex.org/namespace/1.0"`)
+	assert.Contains(t, xml, `<guid>tag:example.com,2021:episode-1</guid>`)
+	assert.Contains(t, xml, `<content:encoded><![CDATA[<p>The long version</p>]]></content:encoded>`)
+	assert.Contains(t, xml, `<podcast:alternateEnclosure length="800" type="audio/ogg">`)
+	assert.Contains(t, xml, `<podcast:source uri="https://example.com/episode1.ogg"></podcast:source>`)
+	assert.Contains(t, xml, `<podcast:transcript type="text/vtt" url="https://example.com/episode1.vtt"></podcast:transcript>`)
+
+	// alternate enclosures are rewritten like the enclosure
+	item, err = transformToItem(e, true)
+	assert.NoError(t, err)
+	feed = rss.New("Test", "https://example.com", nil, nil)
+	_, err = feed.AddItem(item)
+	assert.NoError(t, err)
+	xml = feed.String()
+
+	assert.NotContains(t, xml, `<podcast:source uri="https://example.com/episode1.ogg">`)
+	assert.Contains(t, xml, "/"+e.Parent()+"/"+e.AlternateEnclosures[0].MediaReference()+`"></podcast:source>`)
+}
@@ -36,8 +36,6 @@ func init() {
 func Build(ctx context.Context, root string, skipValidate, skipBuild, skipAssemble, forceAssemble, purge bool) (string, error) {
 	var v *validate.Validator
 	var episodes podops.EpisodeList
-	episodeLookup := make(map[string]*podops.Episode)
-	episodePath := make(map[string]string)
 
 	// cache dir
 	assetPath := filepath.Join(root, config.BuildLocation)
@@ -52,7 +50,7 @@ func Build(ctx context.Context, root string, skipValidate, skipBuild, skipAssemb
 	}
 
 	// find and load the show.yaml
-	showPath := filepath.Join(root, config.DefaultShowName)
+	showPath := loader.ShowPath(root)
 	rsrc, kind, parentGUID, err := loader.ReadResource(ctx, showPath)
 	if err != nil {
 		return "", err
@@ -83,63 +81,48 @@ func Build(ctx context.Context, root string, skipValidate, skipBuild, skipAssemb
 		}
 	}
 
-	// find all episodes
+	// find all episodes, with their defaults
+	all, err := loader.ReadEpisodes(ctx, root, show)
+	if err != nil {
+		return show.Metadata.Name, err
+	}
+
 	now := timestamp.Now()
+	for _, episode := range all {
+		if episode.PublishDateTimestamp() >= now {
+			continue // episodes with a FUTURE timestamp are valid but will be excluded
+		}
 
-	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		base, _ := filepath.Split(path)
+		if !skipValidate {
+			v = episode.Validate("episode."+episode.GUID(), v)
 
-		// skip the assets dir
-		if filepath.Base(base) == config.BuildLocation {
-			return nil
+			// check mismatch episode.parent & show.guid
+			if episode.Metadata.Parent != "" && episode.Metadata.Parent != show.Metadata.GUID {
+				v.AddError(fmt.Sprintf(podops.MsgResourceInvalidReference, episode.Metadata.Parent))
+			}
 		}
-
-		if filepath.Ext(path) == ".yaml" {
-			rsrc, kind, _, err := loader.ReadResource(ctx, path)
-			if err != nil {
-				return err
+		if !skipAssemble {
+			if err := ValidateResource(ctx, parentGUID, root, &episode.Image); err != nil {
+				return show.Metadata.Name, err
 			}
-			if kind == podops.ResourceEpisode {
-				episode := rsrc.(*podops.Episode)
-				if episode.PublishDateTimestamp() < now { // episodes with a FUTURE timestamp are valid but will be excluded
-
-					if !skipValidate {
-						v = episode.Validate("episode."+episode.GUID(), v)
-
-						// check mismatch episode.parent & show.guid
-						if episode.Metadata.Parent != "" && episode.Metadata.Parent != show.Metadata.GUID {
-							v.AddError(fmt.Sprintf(podops.MsgResourceInvalidReference, episode.Metadata.Parent))
-						}
-					}
-					if !skipAssemble {
-						if err := ValidateResource(ctx, parentGUID, root, &episode.Image); err != nil {
-							return err
-						}
-						if err = ValidateResource(ctx, parentGUID, root, &episode.Enclosure); err != nil {
-							return err
-						}
-					}
-
-					// FIXME filter for other flags, e.g. Block = true
-
-					// add to lookup structure
-					episodePath[episode.Metadata.GUID] = base
-					episodeLookup[episode.Metadata.GUID] = episode
-
-					// add to list of episodes
-					episodes = append(episodes, episode)
+			if err = ValidateResource(ctx, parentGUID, root, &episode.Enclosure); err != nil {
+				return show.Metadata.Name, err
+			}
+			for i := range episode.AlternateEnclosures {
+				if err = ValidateResource(ctx, parentGUID, root, &episode.AlternateEnclosures[i]); err != nil {
+					return show.Metadata.Name, err
 				}
 			}
 		}
-		return nil
-	})
 
-	// abort here in case of any errors so far ...
+		// FIXME filter for other flags, e.g. Block = true
 
-	if err != nil {
-		return show.Metadata.Name, err
+		// add to list of episodes
+		episodes = append(episodes, episode)
 	}
 
+	// abort here in case of any errors so far ...
+
 	if !skipValidate {
 		if v.Errors != 0 {
 			return show.Metadata.Name, v.AsError()
@@ -172,6 +155,11 @@ func Build(ctx context.Context, root string, skipValidate, skipBuild, skipAssemb
 			if err := ResolveResource(ctx, parentGUID, root, forceAssemble, false, &e.Enclosure); err != nil {
 				return show.Metadata.Name, err
 			}
+			for i := range e.AlternateEnclosures {
+				if err := ResolveResource(ctx, parentGUID, root, forceAssemble, false, &e.AlternateEnclosures[i]); err != nil {
+					return show.Metadata.Name, err
+				}
+			}
 		}
 
 		item, err := transformToItem(e, forceAssemble)
@@ -195,7 +183,7 @@ func Build(ctx context.Context, root string, skipValidate, skipBuild, skipAssemb
 func Assemble(ctx context.Context, root string, force, overwrite, purge bool) error {
 
 	// find and load the show.yaml
-	showPath := filepath.Join(root, config.DefaultShowName)
+	showPath := loader.ShowPath(root)
 	_, kind, parent, err := loader.ReadResource(ctx, showPath)
 	if err != nil {
 		return err
@@ -0,0 +1,461 @@
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/txsvc/stdlib/v2/timestamp"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/loader"
+)
+
+const (
+	exportMediaLocation = "media"
+	exportManifestJSON  = "episodes.json"
+	exportManifestCSV   = "episodes.csv"
+)
+
+type (
+	// ExportedEpisode describes an episode in the export manifests
+	ExportedEpisode struct {
+		Season    int    `json:"season"`
+		Episode   int    `json:"episode"`
+		Title     string `json:"title"`
+		GUID      string `json:"guid"` // the guid in the feed
+		Published string `json:"published"`
+		Duration  int    `json:"duration"`
+		File      string `json:"file,omitempty"` // the enclosure in the export, empty if it is only available at URL
+		URL       string `json:"url"`
+		Type      string `json:"type"`
+		Size      int    `json:"size"`
+		Image     string `json:"image,omitempty"` // the episode's image in the export
+	}
+
+	// ExportManifest lists the content of an export
+	ExportManifest struct {
+		Name     string             `json:"name"`
+		GUID     string             `json:"guid"`
+		Title    string             `json:"title"`
+		Feed     string             `json:"feed"`
+		Image    string             `json:"image,omitempty"`
+		Episodes []*ExportedEpisode `json:"episodes"`
+		Missing  []string           `json:"missing,omitempty"` // media without a local copy, the feed refers to their URL
+		Bytes    int64              `json:"bytes"`
+	}
+
+	// exportWriter writes the files of an export
+	exportWriter interface {
+		WriteFile(name string, r io.Reader, size int64) error
+		Close() error
+	}
+
+	dirWriter struct {
+		root string
+	}
+
+	archiveWriter struct {
+		prefix string
+		gz     *gzip.Writer
+		tw     *tar.Writer
+	}
+)
+
+// Export converts the repository at root into a self-contained export that other hosting platforms
+// understand: a standard feed.xml, a JSON and CSV manifest of all episodes and the media files, named
+// after their episodes. If the output ends in .tar.gz or .tgz, an archive is written, a directory
+// otherwise. With a baseURL, the media URLs in the feed point to baseURL/media, where the media of
+// the export is uploaded to. Without it, the feed refers to the current location of the media.
+//
+// Media is taken from the local build location, see Assemble. Media without a local copy is not
+// exported and listed in the manifest instead.
+func Export(ctx context.Context, root, output, baseURL string) (*ExportManifest, error) {
+	show, episodes, err := loadPublished(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(output, ".tar.gz") || strings.HasSuffix(output, ".tgz") {
+		return exportArchive(ctx, root, output, baseURL, show, episodes)
+	}
+
+	if err := os.MkdirAll(output, os.ModePerm); err != nil {
+		return nil, err
+	}
+	w := &dirWriter{root: output}
+	manifest, err := export(ctx, root, baseURL, show, episodes, w)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return manifest, w.Close()
+}
+
+// exportArchive writes the export into a temporary file next to output, which replaces
+// output only if the archive is complete. A failed export leaves no truncated archive behind.
+func exportArchive(ctx context.Context, root, output, baseURL string, show *podops.Show, episodes podops.EpisodeList) (manifest *ExportManifest, err error) {
+	f, err := os.CreateTemp(filepath.Dir(output), filepath.Base(output)+".*.part")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = f.Chmod(0644); err != nil {
+		return nil, err
+	}
+
+	w := newArchiveWriter(f, show.Metadata.Name)
+	if manifest, err = export(ctx, root, baseURL, show, episodes, w); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	if err = f.Close(); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(f.Name(), output); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func export(ctx context.Context, root, baseURL string, show *podops.Show, episodes podops.EpisodeList, w exportWriter) (*ExportManifest, error) {
+	cdn := config.Settings().GetOption(config.PodopsContentEndpointEnv)
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	manifest := ExportManifest{
+		Name:     show.Metadata.Name,
+		GUID:     show.Metadata.GUID,
+		Title:    show.Description.Title,
+		Feed:     config.DefaultFeedName,
+		Episodes: make([]*ExportedEpisode, 0, len(episodes)),
+	}
+	exported := make(map[string]string) // local file -> name in the export
+	missing := make(map[string]bool)
+
+	// exportAsset copies a media file into the export and points ref to its new location
+	exportAsset := func(ref *podops.AssetRef, parent, name string) (string, error) {
+		if ref.URI == "" {
+			return "", nil
+		}
+		src := localCopy(root, parent, ref)
+		if src == "" {
+			if !missing[ref.URI] {
+				manifest.Missing = append(manifest.Missing, ref.URI)
+				missing[ref.URI] = true
+			}
+			*ref = exportRef(ref, ref.CanonicalReference(cdn, parent, false))
+			return "", nil
+		}
+
+		file, found := exported[src]
+		if !found {
+			file = path.Join(exportMediaLocation, name+mediaExtension(ref, src))
+			n, err := copyFile(w, src, file)
+			if err != nil {
+				return "", err
+			}
+			exported[src] = file
+			manifest.Bytes += n
+		}
+
+		url := ref.CanonicalReference(cdn, parent, false)
+		if baseURL != "" {
+			url = baseURL + "/" + file
+		}
+		*ref = exportRef(ref, url)
+		return file, nil
+	}
+
+	// the feed only refers to media by absolute URLs
+	image, err := exportAsset(&show.Image, show.GUID(), "cover")
+	if err != nil {
+		return nil, err
+	}
+	manifest.Image = image
+
+	feed, err := transformToPodcast(show, false)
+	if err != nil {
+		return nil, err
+	}
+	// the feed is hosted elsewhere, its self link, hub and move belong to its current location
+	feed.AtomLinks = nil
+	feed.INewFeedURL = ""
+	if baseURL != "" {
+		feed.AddAtomLink(baseURL + "/" + config.DefaultFeedName)
+	}
+	if len(episodes) > 0 {
+		tt, _ := time.Parse(time.RFC1123Z, episodes[0].PublishDate())
+		feed.AddPubDate(&tt)
+	}
+
+	for _, e := range episodes {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		name := fmt.Sprintf("s%02de%03d-%s", e.SeasonAsInt(), e.EpisodeAsInt(), e.Metadata.Name)
+		image, err := exportAsset(&e.Image, e.Parent(), name)
+		if err != nil {
+			return nil, err
+		}
+		file, err := exportAsset(&e.Enclosure, e.Parent(), name)
+		if err != nil {
+			return nil, err
+		}
+		for i := range e.AlternateEnclosures {
+			if _, err := exportAsset(&e.AlternateEnclosures[i], e.Parent(), fmt.Sprintf("%s-%d", name, i+1)); err != nil {
+				return nil, err
+			}
+		}
+
+		item, err := transformToItem(e, false)
+		if err != nil {
+			return nil, err
+		}
+		feed.AddItem(item)
+
+		duration := e.Enclosure.Duration
+		if duration <= 1 {
+			duration = e.Description.Duration
+		}
+		manifest.Episodes = append(manifest.Episodes, &ExportedEpisode{
+			Season:    e.SeasonAsInt(),
+			Episode:   e.EpisodeAsInt(),
+			Title:     e.Description.Title,
+			GUID:      item.GUID,
+			Published: e.PublishDate(),
+			Duration:  duration,
+			File:      file,
+			URL:       e.Enclosure.URI,
+			Type:      e.Enclosure.Type,
+			Size:      e.Enclosure.Size,
+			Image:     image,
+		})
+	}
+
+	if err := writeBytes(w, config.DefaultFeedName, feed.Bytes()); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(&manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBytes(w, exportManifestJSON, data); err != nil {
+		return nil, err
+	}
+
+	data, err = episodesCSV(manifest.Episodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBytes(w, exportManifestCSV, data); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// loadPublished reads the show at root and its published episodes, sorted like in the feed
+func loadPublished(ctx context.Context, root string) (*podops.Show, podops.EpisodeList, error) {
+	rsrc, kind, _, err := loader.ReadResource(ctx, loader.ShowPath(root))
+	if err != nil {
+		return nil, nil, err
+	}
+	if kind != podops.ResourceShow {
+		return nil, nil, podops.ErrBuildNoShow
+	}
+	show := rsrc.(*podops.Show)
+	show.Episodes = nil // they are found below, like all other episodes
+
+	all, err := loader.ReadEpisodes(ctx, root, show)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := timestamp.Now()
+	episodes := make(podops.EpisodeList, 0, len(all))
+	for _, episode := range all {
+		if episode.PublishDateTimestamp() < now { // like in the feed, future episodes are not published yet
+			episodes = append(episodes, episode)
+		}
+	}
+	if len(episodes) == 0 {
+		return nil, nil, podops.ErrBuildNoEpisodes
+	}
+
+	sort.Sort(episodes)
+	return show, episodes, nil
+}
+
+// localCopy returns the path of the local copy of a media file, or an empty string
+func localCopy(root, parent string, ref *podops.AssetRef) string {
+	// the build knows more about an asset than its resource
+	if built, err := loader.ReadEnclosure(context.TODO(), filepath.Join(root, config.BuildLocation, fmt.Sprintf("%s.yaml", ref.AssetReference(parent)))); err == nil {
+		ref.ETag = built.ETag
+		ref.Type = stringWithDefault(ref.Type, built.Type)
+		if built.Size > 0 {
+			ref.Size = built.Size
+		}
+		if built.Duration > 0 {
+			ref.Duration = built.Duration
+		}
+	}
+
+	if ref.ETag != "" {
+		src := filepath.Join(root, config.BuildLocation, ref.MediaReference())
+		if _, err := os.Stat(src); err == nil {
+			return src
+		}
+	}
+	if ref.Rel == podops.ResourceTypeLocal {
+		src := filepath.Join(root, ref.URI)
+		if _, err := os.Stat(src); err == nil {
+			return src
+		}
+	}
+	return ""
+}
+
+// exportRef returns a copy of ref that points to url
+func exportRef(ref *podops.AssetRef, url string) podops.AssetRef {
+	r := ref.Clone()
+	r.URI = url
+	r.Rel = podops.ResourceTypeExternal
+	return r
+}
+
+// mediaExtension returns the extension of a media file, including the '.'
+func mediaExtension(ref *podops.AssetRef, src string) string {
+	if ext := path.Ext(strings.Split(ref.URI, "?")[0]); ext != "" && len(ext) <= 5 {
+		return ext
+	}
+	return filepath.Ext(src)
+}
+
+func stringWithDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// episodesCSV returns the manifest of the episodes as CSV, for spreadsheets and bulk uploaders
+func episodesCSV(episodes []*ExportedEpisode) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+
+	cw.Write([]string{"season", "episode", "title", "guid", "published", "duration", "file", "url", "type", "size", "image"})
+	for _, e := range episodes {
+		cw.Write([]string{
+			fmt.Sprintf("%d", e.Season),
+			fmt.Sprintf("%d", e.Episode),
+			e.Title,
+			e.GUID,
+			e.Published,
+			fmt.Sprintf("%d", e.Duration),
+			e.File,
+			e.URL,
+			e.Type,
+			fmt.Sprintf("%d", e.Size),
+			e.Image,
+		})
+	}
+	cw.Flush()
+
+	return buf.Bytes(), cw.Error()
+}
+
+func copyFile(w exportWriter, src, name string) (int64, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), w.WriteFile(name, f, fi.Size())
+}
+
+func writeBytes(w exportWriter, name string, data []byte) error {
+	return w.WriteFile(name, bytes.NewReader(data), int64(len(data)))
+}
+
+// WriteFile writes a file below the export directory
+func (d *dirWriter) WriteFile(name string, r io.Reader, size int64) error {
+	p := filepath.Join(d.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Close is a no-op, all files are closed after writing
+func (d *dirWriter) Close() error {
+	return nil
+}
+
+// newArchiveWriter returns a writer for a tar.gz archive, all entries are prefixed with prefix
+func newArchiveWriter(w io.Writer, prefix string) *archiveWriter {
+	gz := gzip.NewWriter(w)
+	return &archiveWriter{
+		prefix: prefix,
+		gz:     gz,
+		tw:     tar.NewWriter(gz),
+	}
+}
+
+// WriteFile adds a file to the archive
+func (a *archiveWriter) WriteFile(name string, r io.Reader, size int64) error {
+	hdr := &tar.Header{
+		Name:    path.Join(a.prefix, name),
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(a.tw, r)
+	return err
+}
+
+// Close completes the archive
+func (a *archiveWriter) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
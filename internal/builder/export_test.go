@@ -0,0 +1,110 @@
+package builder
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/loader"
+)
+
+func TestExport(t *testing.T) {
+	ctx := context.TODO()
+	root := t.TempDir()
+	published := time.Now().Add(-time.Hour).UTC().Format(time.RFC1123Z)
+
+	show := podops.DefaultShow("exportpodcast", "EXPORT PODCAST", "SUMMARY", "a7c94297acfc", "https://example.com", "https://cdn.example.com")
+	show.HubLink = &podops.AssetRef{URI: "https://pubsubhubbub.appspot.com/"}
+	show.NewFeedLink = &podops.AssetRef{URI: "https://example.com/moved/feed.xml"}
+	assert.NoError(t, loader.WriteResource(ctx, filepath.Join(root, config.DefaultShowName), show))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "cover.png"), []byte("cover"), 0644))
+
+	// a local enclosure, its image is missing
+	e1 := podops.DefaultEpisode("firstepisode", "exportpodcast", "86124f7f9cf4", "a7c94297acfc", "https://example.com", "https://cdn.example.com")
+	e1.Metadata.Date = published
+	assert.NoError(t, loader.WriteResource(ctx, filepath.Join(root, "episode1.yaml"), e1))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "episode.mp3"), []byte("episode 1"), 0644))
+
+	// an imported enclosure in the build location, with the show's image
+	e2 := podops.DefaultEpisode("secondepisode", "exportpodcast", "95235f8f0ad5", "a7c94297acfc", "https://example.com", "https://cdn.example.com")
+	e2.Metadata.Date = published
+	e2.Metadata.Labels[podops.LabelEpisode] = "2"
+	e2.Image = show.Image
+	e2.Enclosure = podops.AssetRef{URI: "https://old.example.com/episode2.mp3", Rel: podops.ResourceTypeImport, ETag: "0123456789abcdef", Size: 9, Type: "audio/mpeg"}
+	assert.NoError(t, loader.WriteResource(ctx, filepath.Join(root, "episode2.yaml"), e2))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, config.BuildLocation), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, config.BuildLocation, e2.Enclosure.MediaReference()), []byte("episode 2"), 0644))
+
+	// export into a directory
+	output := filepath.Join(t.TempDir(), "export")
+	manifest, err := Export(ctx, root, output, "https://media.example.com/show/")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(manifest.Episodes))
+	assert.Equal(t, []string{"episode.png"}, manifest.Missing)
+	assert.Equal(t, int64(len("cover")+len("episode 1")+len("episode 2")), manifest.Bytes)
+
+	for _, name := range []string{"feed.xml", "episodes.json", "episodes.csv", "media/cover.png", "media/s01e001-firstepisode.mp3", "media/s01e002-secondepisode.mp3"} {
+		_, err := os.Stat(filepath.Join(output, name))
+		assert.NoError(t, err, name)
+	}
+
+	feed, err := os.ReadFile(filepath.Join(output, "feed.xml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(feed), `<enclosure url="https://media.example.com/show/media/s01e002-secondepisode.mp3" length="9" type="audio/mpeg">`)
+	assert.Contains(t, string(feed), `<itunes:image href="https://media.example.com/show/media/cover.png">`)
+	assert.Contains(t, string(feed), `<atom:link href="https://media.example.com/show/feed.xml" rel="self" type="application/rss+xml"></atom:link>`)
+	assert.NotContains(t, string(feed), `rel="hub"`)
+	assert.NotContains(t, string(feed), "itunes:new-feed-url")
+
+	csv, err := os.ReadFile(filepath.Join(output, "episodes.csv"))
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(csv)), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "season,episode,title,guid"))
+
+	// export into an archive
+	archive := filepath.Join(t.TempDir(), "export.tar.gz")
+	_, err = Export(ctx, root, archive, "")
+	assert.NoError(t, err)
+
+	f, err := os.Open(archive)
+	assert.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gz)
+	names := make([]string, 0)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+	}
+	assert.Contains(t, names, "exportpodcast/feed.xml")
+	assert.Contains(t, names, "exportpodcast/media/s01e001-firstepisode.mp3")
+
+	// a failed export keeps the last archive and leaves no partial file behind
+	before, err := os.ReadFile(archive)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Remove(filepath.Join(root, "episode.mp3")))
+	assert.NoError(t, os.Mkdir(filepath.Join(root, "episode.mp3"), 0755))
+
+	_, err = Export(ctx, root, archive, "")
+	assert.Error(t, err)
+	after, err := os.ReadFile(archive)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+	files, err := os.ReadDir(filepath.Dir(archive))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+}
@@ -2,6 +2,7 @@ package builder
 
 import (
 	"bytes"
+	"fmt"
 	"time"
 
 	"github.com/yuin/goldmark"
@@ -41,6 +42,11 @@ func transformToPodcast(s *podops.Show, rewrite bool) (*rss.Channel, error) {
 		Email: s.Description.Owner.Email,
 	}
 	pf.Copyright = s.Description.Copyright
+	if s.HubLink != nil && s.HubLink.URI != "" {
+		// advertise the hub, WebSub requires the feed's self link too
+		pf.AddAtomLink(fmt.Sprintf("%s/%s", s.Description.Link.URI, config.DefaultFeedName))
+		pf.AddHubLink(s.HubLink.URI)
+	}
 	if s.NewFeedLink != nil {
 		pf.INewFeedURL = s.NewFeedLink.URI
 	}
@@ -59,6 +65,7 @@ func transformToPodcast(s *podops.Show, rewrite bool) (*rss.Channel, error) {
 	if s.Metadata.Labels[podops.LabelComplete] == "yes" {
 		pf.IComplete = "yes"
 	}
+	pf.Podcast = transformPodcastTags(s.Podcast)
 
 	return &pf, nil
 }
@@ -94,7 +101,13 @@ func transformToItem(e *podops.Episode, rewrite bool) (*rss.Item, error) {
 
 	ef.Link = e.Description.Link.CanonicalReference(config.Settings().GetOption(config.PodopsContentEndpointEnv), e.Parent(), false)
 	ef.ISubtitle = e.Description.Summary
+	ef.IAuthor = e.Description.Author
+	ef.AddContent(e.Description.EpisodeText)
 	ef.GUID = e.Metadata.GUID
+	if e.Metadata.FeedGUID != "" {
+		// podcast apps know imported episodes by their original GUID
+		ef.GUID = e.Metadata.FeedGUID
+	}
 	ef.IExplicit = e.Metadata.Labels[podops.LabelExplicit]
 	ef.ISeason = e.Metadata.Labels[podops.LabelSeason]
 	ef.IEpisode = e.Metadata.Labels[podops.LabelEpisode]
@@ -103,9 +116,31 @@ func transformToItem(e *podops.Episode, rewrite bool) (*rss.Item, error) {
 		ef.IBlock = "yes"
 	}
 
+	// alternate enclosures are published like the enclosure, see https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md#alternate-enclosure
+	for _, a := range e.AlternateEnclosures {
+		attrs := map[string]string{"type": a.Type}
+		if a.Size > 0 {
+			attrs["length"] = fmt.Sprintf("%d", a.Size)
+		}
+		alternate := rss.NewExtension("podcast:alternateEnclosure", "", attrs)
+		alternate.Children = append(alternate.Children, rss.NewExtension("podcast:source", "", map[string]string{"uri": a.CanonicalReference(config.Settings().GetOption(config.PodopsContentEndpointEnv), e.Parent(), rewrite)}))
+		ef.Podcast = append(ef.Podcast, alternate)
+	}
+	ef.Podcast = append(ef.Podcast, transformPodcastTags(e.Podcast)...)
+
 	return ef, nil
 }
 
+// transformPodcastTags returns the Podcasting 2.0 elements of a show or episode
+func transformPodcastTags(tags []podops.PodcastTag) []*rss.Extension {
+	elements := make([]*rss.Extension, len(tags))
+	for i, t := range tags {
+		elements[i] = rss.NewExtension("podcast:"+t.Name, t.Value, t.Attrs)
+		elements[i].Children = transformPodcastTags(t.Children)
+	}
+	return elements
+}
+
 func renderMarkdown(md string) (string, error) {
 	var buf bytes.Buffer
 	if err := goldmark.Convert([]byte(md), &buf); err != nil {
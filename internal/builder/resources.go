@@ -153,9 +153,23 @@ func ValidateResource(ctx context.Context, parent, root string, encl *podops.Ass
 	if encl.Rel == podops.ResourceTypeLocal {
 		return validateLocal(parent, root, encl)
 	}
+	if encl.Rel == podops.ResourceTypeImport && isImported(root, encl) {
+		// already imported, the original host is not needed anymore
+		enclosurePath := filepath.Join(root, config.BuildLocation, fmt.Sprintf("%s.yaml", encl.AssetReference(parent)))
+		return loader.WriteResource(context.TODO(), enclosurePath, encl)
+	}
 	return validateRemote(ctx, parent, root, encl)
 }
 
+// isImported returns true if the referenced resource was already imported into the local build location
+func isImported(root string, encl *podops.AssetRef) bool {
+	if encl.ETag == "" {
+		return false
+	}
+	fi, err := os.Stat(filepath.Join(root, config.BuildLocation, encl.MediaReference()))
+	return err == nil && fi.Size() == int64(encl.Size)
+}
+
 // validateLocal validates that the referenced resource exists on the filesystem
 // and extracts additional metadata from the file. The metadata is written to the cache.
 func validateLocal(parent, root string, encl *podops.AssetRef) error {
@@ -0,0 +1,294 @@
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/txsvc/stdlib/v2/timestamp"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal"
+	"github.com/podops/podops/internal/builder"
+	"github.com/podops/podops/internal/loader"
+	"github.com/podops/podops/internal/notify"
+	"github.com/podops/podops/internal/storage"
+)
+
+const (
+	// BuildQueued indicates that the build waits for its turn
+	BuildQueued = "queued"
+	// BuildRunning indicates that the build is in progress
+	BuildRunning = "running"
+	// BuildSucceeded indicates that the build was published to the CDN
+	BuildSucceeded = "succeeded"
+	// BuildFailed indicates that the build failed, see the log for details
+	BuildFailed = "failed"
+
+	maxConcurrentBuilds = 2
+	maxBuildHistory     = 10
+	buildTimeout        = 30 * time.Minute
+)
+
+type (
+	// BuildStatus describes a server-side build of a show's repository
+	BuildStatus struct {
+		ID       string   `json:"id"`
+		Parent   string   `json:"parent"`
+		Repo     string   `json:"repo"`
+		Status   string   `json:"status"`
+		Error    string   `json:"error,omitempty"`
+		Created  int64    `json:"created"`
+		Started  int64    `json:"started,omitempty"`
+		Finished int64    `json:"finished,omitempty"`
+		Log      []string `json:"log,omitempty"`
+	}
+
+	// buildQueue serializes builds per show and limits the number of concurrent builds
+	buildQueue struct {
+		jobs    map[string]chan *BuildStatus // parent -> pending builds
+		history map[string][]*BuildStatus    // parent -> recent builds, latest first
+		slots   chan struct{}
+		mu      sync.Mutex
+	}
+)
+
+var (
+	builds *buildQueue
+)
+
+func init() {
+	builds = &buildQueue{
+		jobs:    make(map[string]chan *BuildStatus),
+		history: make(map[string][]*BuildStatus),
+		slots:   make(chan struct{}, maxConcurrentBuilds),
+	}
+}
+
+// EnqueueBuild schedules a server-side build of a show's repository. Builds of the same show
+// run one after the other. If a build of the show is still waiting, no new build is queued
+// as the waiting build will pick up the latest changes anyways.
+func EnqueueBuild(repo, parent string) BuildStatus {
+	builds.mu.Lock()
+	defer builds.mu.Unlock()
+
+	if h := builds.history[parent]; len(h) > 0 && h[0].Status == BuildQueued {
+		return h[0].copy()
+	}
+
+	job := &BuildStatus{
+		ID:      internal.CreateRandomAssetGUID(),
+		Parent:  parent,
+		Repo:    repo,
+		Status:  BuildQueued,
+		Created: timestamp.Now(),
+	}
+
+	h := append([]*BuildStatus{job}, builds.history[parent]...)
+	if len(h) > maxBuildHistory {
+		h = h[:maxBuildHistory]
+	}
+	builds.history[parent] = h
+
+	jobs, ok := builds.jobs[parent]
+	if !ok {
+		jobs = make(chan *BuildStatus, maxBuildHistory)
+		builds.jobs[parent] = jobs
+		go builds.worker(jobs)
+	}
+	jobs <- job
+
+	return job.copy()
+}
+
+// ListBuilds returns the recent builds of a show, latest first
+func ListBuilds(parent string) []BuildStatus {
+	builds.mu.Lock()
+	defer builds.mu.Unlock()
+
+	h := builds.history[parent]
+	l := make([]BuildStatus, len(h))
+	for i, b := range h {
+		l[i] = b.copy()
+		l[i].Log = nil // only the details include the log
+	}
+	return l
+}
+
+// LookupBuild returns a build of a show, including its log
+func LookupBuild(parent, id string) (*BuildStatus, bool) {
+	builds.mu.Lock()
+	defer builds.mu.Unlock()
+
+	for _, b := range builds.history[parent] {
+		if b.ID == id {
+			bs := b.copy()
+			return &bs, true
+		}
+	}
+	return nil, false
+}
+
+func (q *buildQueue) worker(jobs chan *BuildStatus) {
+	for job := range jobs {
+		q.slots <- struct{}{}
+		q.run(job)
+		<-q.slots
+	}
+}
+
+func (q *buildQueue) run(job *BuildStatus) {
+	ctx, cancel := context.WithTimeout(context.Background(), buildTimeout)
+	defer cancel()
+
+	q.mu.Lock()
+	job.Status = BuildRunning
+	job.Started = timestamp.Now()
+	q.mu.Unlock()
+
+	logf := func(format string, a ...interface{}) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		job.Log = append(job.Log, fmt.Sprintf("%s %s", time.Now().UTC().Format(time.RFC3339), fmt.Sprintf(format, a...)))
+	}
+
+	err := buildAndPublish(ctx, job.Repo, job.Parent, logf)
+	if err != nil {
+		logf("error: %v", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job.Finished = timestamp.Now()
+	if err != nil {
+		job.Status = BuildFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = BuildSucceeded
+	}
+}
+
+// buildAndPublish pulls a show's repository, builds and assembles it
+// and copies the results into the show's storage location.
+func buildAndPublish(ctx context.Context, repo, parent string, logf func(string, ...interface{})) error {
+	logf("pulling '%s'", repo)
+	root, err := CloneOrPullRepo(repo, parent)
+	if err != nil {
+		return err
+	}
+
+	// make sure the repository belongs to the show
+	_, kind, guid, err := loader.ReadResource(ctx, filepath.Join(root, config.DefaultShowName))
+	if err != nil {
+		return err
+	}
+	if kind != podops.ResourceShow {
+		return podops.ErrBuildNoShow
+	}
+	if guid != parent {
+		return fmt.Errorf(podops.MsgResourceInvalidGUID, config.DefaultShowName, guid)
+	}
+
+	logf("building '%s'", root)
+	name, err := builder.Build(ctx, root, false, false, false, false, false)
+	if err != nil {
+		return err
+	}
+
+	logf("assembling '%s'", name)
+	if err := builder.Assemble(ctx, root, false, false, false); err != nil {
+		return err
+	}
+
+	// remember the published episodes before the feed gets replaced
+	notify.Prepare(ctx, parent)
+
+	n, err := PublishBuild(ctx, root, parent)
+	if err != nil {
+		return err
+	}
+	if err := PublishChange(ctx, parent); err != nil {
+		return err
+	}
+
+	if err := notify.Publish(ctx, parent); err != nil {
+		logf("error sending notifications: %v", err)
+	}
+
+	logf("published %d resource(s) of '%s'", n, name)
+	return nil
+}
+
+// PublishBuild copies new or changed media assets and the feed.xml from a repository's
+// build location into a show's storage. It returns the number of copied files.
+func PublishBuild(ctx context.Context, root, parent string) (int, error) {
+	assetPath := filepath.Join(root, config.BuildLocation)
+	n := 0
+
+	err := filepath.Walk(assetPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+
+		ar, err := builder.LoadAssetRef(path)
+		if err != nil {
+			return err
+		}
+		if ar.Rel == podops.ResourceTypeExternal {
+			return nil // not part of the build
+		}
+
+		src := filepath.Join(assetPath, ar.MediaReference())
+		key := storage.Key(parent, ar.MediaReference())
+
+		si, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		if ti, err := storage.Default().Stat(ctx, key); err == nil && ti.Size == si.Size() {
+			return nil // already published
+		}
+
+		n++
+		return putFile(ctx, src, key)
+	})
+	if err != nil {
+		return n, err
+	}
+
+	// publish feed.xml last
+	feed := config.DefaultFeedName
+	if err := putFile(ctx, filepath.Join(assetPath, feed), storage.Key(parent, feed)); err != nil {
+		return n, err
+	}
+	return n + 1, nil
+}
+
+// putFile copies a local file into the storage
+func putFile(ctx context.Context, path, key string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return storage.Default().Put(ctx, key, f, info.Size())
+}
+
+func (b *BuildStatus) copy() BuildStatus {
+	bs := *b
+	bs.Log = append([]string(nil), b.Log...)
+	return bs
+}
@@ -4,13 +4,18 @@ import (
 	"context"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
+	"strings"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/storage"
 )
 
-// ReceiveFileUpload handles the reception of uploads and puts the files into a specified location
-func ReceiveFileUpload(ctx context.Context, req *http.Request, location, formName string) (string, error) {
-	var path string
+// ReceiveFileUpload handles the reception of uploads and puts the files into the show's storage.
+// It returns the name of the received file.
+func ReceiveFileUpload(ctx context.Context, req *http.Request, parent, formName string) (string, error) {
+	var name string
 
 	mr, err := req.MultipartReader()
 	if err != nil {
@@ -27,20 +32,34 @@ func ReceiveFileUpload(ctx context.Context, req *http.Request, location, formNam
 		}
 
 		if part.FormName() == formName {
-			path = filepath.Join(location, part.FileName())
-
-			os.MkdirAll(filepath.Dir(path), os.ModePerm) // make sure sub-folders exist
-			out, err := os.Create(path)
-			if err != nil {
-				return "", err
+			// keep the file inside the show's namespace, server state can not be replaced
+			name = path.Clean("/" + part.FileName())[1:]
+			if name == "" || IsPrivate(name) {
+				return "", podops.ErrInvalidResourceName
 			}
-			defer out.Close()
 
-			if _, err := io.Copy(out, part); err != nil {
+			if err := storage.Default().Put(ctx, storage.Key(parent, name), part, -1); err != nil {
 				return "", err
 			}
 		}
 	}
 
-	return path, nil
+	return name, nil
+}
+
+// IsPrivate returns true if key is server state that must not be served, e.g. a show's keys,
+// its webhook subscriptions or the event journal
+func IsPrivate(key string) bool {
+	for _, part := range strings.Split(key, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+
+	switch name := path.Base(key); name {
+	case config.DefaultNotifyFileLocation, config.DefaultShowMetaFileLocation, config.DefaultRedirectFileLocation:
+		return true
+	default:
+		return strings.HasSuffix(name, ".key")
+	}
 }
@@ -0,0 +1,19 @@
+package cdn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPrivate(t *testing.T) {
+	assert.True(t, IsPrivate("a7c94297acfc/master.key"))
+	assert.True(t, IsPrivate("a7c94297acfc/webhook.key"))
+	assert.True(t, IsPrivate("a7c94297acfc/notify.yaml"))
+	assert.True(t, IsPrivate("a7c94297acfc/meta.yaml"))
+	assert.True(t, IsPrivate("a7c94297acfc/redirects.yaml"))
+	assert.True(t, IsPrivate(".events/01634564327123456789-a7c94297acfc"))
+	assert.True(t, IsPrivate("a7c94297acfc/.hidden"))
+	assert.False(t, IsPrivate("a7c94297acfc/feed.xml"))
+	assert.False(t, IsPrivate("a7c94297acfc/86124f7f9cf.mp3"))
+}
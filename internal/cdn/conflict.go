@@ -0,0 +1,87 @@
+package cdn
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/txsvc/stdlib/v2/timestamp"
+
+	"github.com/podops/podops"
+)
+
+type (
+	// Conflict describes shows that claim the same canonical name. Only the owner is served under the name.
+	Conflict struct {
+		Name     string   `json:"name"`
+		Owner    string   `json:"owner"`    // GUID of the show that got the name
+		Rejected []string `json:"rejected"` // GUIDs of the shows that claim the name too
+	}
+)
+
+// ReserveName claims a canonical name for a show before its first feed is published.
+// A show that already has a different name is renamed.
+func ReserveName(ctx context.Context, parent, name string) error {
+	if !podops.ValidName(name) {
+		return podops.ErrInvalidResourceName
+	}
+	if guid, ok := ResolveName(name); ok && guid != parent {
+		return podops.ErrNameExists
+	}
+
+	meta, err := LoadShowMeta(ctx, parent)
+	if err != nil {
+		return err
+	}
+	if meta.Name == name && meta.Reserved > 0 {
+		return nil // nothing to do
+	}
+	if meta.Name != "" && meta.Name != name {
+		return RenameShow(ctx, parent, name)
+	}
+
+	meta.Name = name
+	meta.Reserved = timestamp.Now()
+	if err := WriteShowMeta(ctx, parent, meta); err != nil {
+		return err
+	}
+
+	return PublishChange(ctx, parent)
+}
+
+// ListConflicts returns the names that are claimed by more than one show, ordered by name
+func ListConflicts() []Conflict {
+	mu.Lock()
+	defer mu.Unlock()
+
+	l := make([]Conflict, 0, len(conflicts))
+	for _, c := range conflicts {
+		l = append(l, c)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+	return l
+}
+
+// precedes returns true if show a has a better claim on its name than show b:
+// reserved names win over names taken from a feed, earlier reservations win over
+// later ones and if that does not decide, the lower GUID wins.
+func precedes(a, b *showMapping) bool {
+	if (a.reserved > 0) != (b.reserved > 0) {
+		return a.reserved > 0
+	}
+	if a.reserved != b.reserved {
+		return a.reserved < b.reserved
+	}
+	return a.parent < b.parent
+}
+
+// logConflicts reports conflicts that are new or changed, the caller holds mu
+func logConflicts(found map[string]Conflict) {
+	for name, c := range found {
+		if old, ok := conflicts[name]; ok && old.Owner == c.Owner && strings.Join(old.Rejected, ",") == strings.Join(c.Rejected, ",") {
+			continue
+		}
+		log.Printf("cdn: name '%s' is claimed by more than one show, serving '%s' and rejecting '%s'", name, c.Owner, strings.Join(c.Rejected, "', '"))
+	}
+}
@@ -0,0 +1,62 @@
+package cdn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/storage"
+)
+
+func TestNameConflicts(t *testing.T) {
+	s := storage.NewLocal(t.TempDir())
+	storage.SetDefault(s)
+
+	first := "aaa94297acfc"
+	second := "bbb94297acfc"
+	feed := `<rss version="2.0"><channel><title>Minimal</title><link>https://podops.dev/minimalpodcast</link></channel></rss>`
+
+	// two feeds with the same name, the lower GUID wins
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, storage.Key(first, config.DefaultFeedName), []byte(feed)))
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, storage.Key(second, config.DefaultFeedName), []byte(feed)))
+	assert.NoError(t, CreateInventoryMappings(context.TODO()))
+
+	key, ok := Rewrite("/minimalpodcast/feed.xml")
+	assert.True(t, ok)
+	assert.Equal(t, "aaa94297acfc/feed.xml", key)
+
+	conflicts := ListConflicts()
+	assert.Equal(t, 1, len(conflicts))
+	assert.Equal(t, "minimalpodcast", conflicts[0].Name)
+	assert.Equal(t, first, conflicts[0].Owner)
+	assert.Equal(t, []string{second}, conflicts[0].Rejected)
+
+	// the name is taken
+	assert.Equal(t, podops.ErrNameExists, ReserveName(context.TODO(), second, "minimalpodcast"))
+
+	// a reserved name wins over a name taken from a feed
+	assert.NoError(t, WriteShowMeta(context.TODO(), second, &ShowMeta{Name: "minimalpodcast", Reserved: 1}))
+	assert.NoError(t, UpdateInventoryMapping(context.TODO(), second))
+
+	guid, ok := ResolveName("minimalpodcast")
+	assert.True(t, ok)
+	assert.Equal(t, second, guid)
+	assert.Equal(t, []string{first}, ListConflicts()[0].Rejected)
+
+	// a new show reserves its name before the first feed is published
+	third := "ccc94297acfc"
+	assert.NoError(t, ReserveName(context.TODO(), third, "otherpodcast"))
+
+	guid, ok = ResolveName("otherpodcast")
+	assert.True(t, ok)
+	assert.Equal(t, third, guid)
+	_, ok = Rewrite("/otherpodcast/feed.xml")
+	assert.False(t, ok)
+
+	// no conflicts once the show without a reservation is gone
+	assert.NoError(t, DeleteShow(context.TODO(), first))
+	assert.Empty(t, ListConflicts())
+}
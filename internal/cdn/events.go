@@ -0,0 +1,212 @@
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/internal/storage"
+)
+
+const (
+	// ChangeRetention is the time events are kept in the journal
+	ChangeRetention = time.Hour
+
+	eventPrefix = ".events/"
+)
+
+type (
+	// ChangeEvent tells the CDN that the feed, meta data or redirects of a show changed.
+	// Events are kept in a journal in the storage, so that the API and the CDN can run
+	// as separate processes.
+	ChangeEvent struct {
+		Parent    string
+		Timestamp int64 // unix nanoseconds
+	}
+
+	// changeWatcher applies the events in the journal to the inventory mappings
+	changeWatcher struct {
+		seen map[string]bool // keys of the events that were applied already
+	}
+)
+
+var (
+	changeHandlers []func(parent string) // called by the watcher for every show that changed
+)
+
+// OnChange registers a func that is called with the GUID of every changed show the inventory
+// watcher applies, e.g. to drop cached data of the show. Register before calling WatchInventory.
+func OnChange(f func(parent string)) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	changeHandlers = append(changeHandlers, f)
+}
+
+// PublishChange records a change of a show in the event journal. If the inventory
+// mappings exist in this process, they are updated right away. A feed that moved
+// announces its new URL, see AnnounceMove.
+func PublishChange(ctx context.Context, parent string) error {
+	// the feed tells podcast apps where it moved to before the CDN serves the change
+	if err := AnnounceMove(ctx, parent); err != nil {
+		return err
+	}
+
+	e := ChangeEvent{Parent: parent, Timestamp: time.Now().UnixNano()}
+	if err := storage.WriteFile(ctx, storage.Default(), e.key(), nil); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	local := indexed
+	mu.Unlock()
+
+	if local {
+		return UpdateInventoryMapping(ctx, parent)
+	}
+	return nil
+}
+
+// ReadChanges returns the events in the journal, oldest first
+func ReadChanges(ctx context.Context) ([]ChangeEvent, error) {
+	objects, err := storage.Default().List(ctx, eventPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]ChangeEvent, 0, len(objects))
+	for _, o := range objects {
+		if e, ok := parseChangeEvent(o.Key); ok {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// ExpireChanges removes events that are older than retention from the journal
+func ExpireChanges(ctx context.Context, retention time.Duration) error {
+	events, err := ReadChanges(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-retention).UnixNano()
+	for _, e := range events {
+		if e.Timestamp >= cutoff {
+			break
+		}
+		if err := storage.Default().Delete(ctx, e.key()); err != nil && !storage.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchInventory creates the inventory mappings and then applies the events from the
+// journal every interval until ctx is done. Only the changed shows are re-read.
+// Errors while applying events are passed to onError, the events are retried.
+func WatchInventory(ctx context.Context, interval time.Duration, onError func(error)) error {
+	w := &changeWatcher{seen: make(map[string]bool)}
+
+	// events that happened before the full scan are part of it
+	if _, err := w.poll(ctx); err != nil {
+		return err
+	}
+	if err := CreateInventoryMappings(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.apply(ctx); err != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// poll returns the events that were not seen before. Events that left the journal are forgotten.
+func (w *changeWatcher) poll(ctx context.Context) ([]ChangeEvent, error) {
+	events, err := ReadChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]bool, len(events))
+	unseen := make([]ChangeEvent, 0)
+	for _, e := range events {
+		key := e.key()
+		current[key] = true
+		if !w.seen[key] {
+			unseen = append(unseen, e)
+		}
+	}
+	w.seen = current
+
+	return unseen, nil
+}
+
+// apply updates the mappings of all shows with new events
+func (w *changeWatcher) apply(ctx context.Context) error {
+	events, err := w.poll(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	updated := make(map[string]bool)
+	for _, e := range events {
+		if updated[e.Parent] {
+			continue
+		}
+		if err := UpdateInventoryMapping(ctx, e.Parent); err != nil {
+			delete(w.seen, e.key()) // try again next time
+			failed = err
+			continue
+		}
+		updated[e.Parent] = true
+	}
+
+	mu.Lock()
+	handlers := changeHandlers
+	mu.Unlock()
+
+	for parent := range updated {
+		for _, f := range handlers {
+			f(parent)
+		}
+	}
+	return failed
+}
+
+// key returns the event's key in the journal, e.g. .events/01634564327123456789-a7c94297acfc.
+// Keys sort by time.
+func (e ChangeEvent) key() string {
+	return fmt.Sprintf("%s%020d-%s", eventPrefix, e.Timestamp, e.Parent)
+}
+
+func parseChangeEvent(key string) (ChangeEvent, bool) {
+	parts := strings.SplitN(path.Base(key), "-", 2)
+	if len(parts) != 2 || !podops.ValidGUID(parts[1]) {
+		return ChangeEvent{}, false
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return ChangeEvent{}, false
+	}
+	return ChangeEvent{Parent: parts[1], Timestamp: ts}, true
+}
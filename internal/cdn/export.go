@@ -0,0 +1,63 @@
+package cdn
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+
+	"github.com/podops/podops/internal/storage"
+)
+
+// ExportArchive writes a tar.gz archive of a show's storage to w. All entries are
+// prefixed with the show's GUID. Keys and other server state, e.g. the webhook subscriptions
+// and their secrets, are not exported, see IsPrivate. The show gets new ones when it is
+// initialized in a different environment.
+func ExportArchive(ctx context.Context, parent string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	objects, err := storage.Default().List(ctx, parent+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, o := range objects {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if IsPrivate(o.Key) {
+			continue
+		}
+
+		hdr := &tar.Header{
+			Name:    o.Key,
+			Mode:    0644,
+			Size:    o.Size,
+			ModTime: o.Modified,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if err := copyObject(ctx, tw, o.Key); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func copyObject(ctx context.Context, w io.Writer, key string) error {
+	r, err := storage.Default().Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,46 @@
+package cdn
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops/internal/storage"
+)
+
+func TestExportArchive(t *testing.T) {
+	s := storage.NewLocal(t.TempDir())
+	storage.SetDefault(s)
+
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, "aaa94297acfc/feed.xml", []byte("<rss/>")))
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, "aaa94297acfc/a1b2c3.mp3", []byte("mp3")))
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, "aaa94297acfc/master.key", []byte("secret")))
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, "aaa94297acfc/notify.yaml", []byte("- url: https://example.com/hook\n  secret: secret\n")))
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, "aaa94297acfc/meta.yaml", []byte("name: minimalpodcast\n")))
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, "aaa94297acfc/redirects.yaml", []byte("- oldpodcast\n")))
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, "bbb94297acfc/feed.xml", []byte("<rss/>")))
+
+	var buf bytes.Buffer
+	err := ExportArchive(context.TODO(), "aaa94297acfc", &buf)
+	assert.NoError(t, err)
+
+	gz, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	names := make([]string, 0)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+	assert.ElementsMatch(t, []string{"aaa94297acfc/feed.xml", "aaa94297acfc/a1b2c3.mp3"}, names)
+}
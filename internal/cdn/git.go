@@ -1,47 +1,25 @@
 package cdn
 
 import (
-	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/go-git/go-git/v5"
-	"github.com/mmcdole/gofeed"
 
 	"github.com/podops/podops"
 	"github.com/podops/podops/config"
 )
 
-func CloneOrPullRepo(repo, parent string) error {
+// CloneOrPullRepo clones a show's repository into the static location or pulls
+// the latest changes if it was cloned before. It returns the repository's local path.
+func CloneOrPullRepo(repo, parent string) (string, error) {
 
-	if parent == "" {
-		return podops.ErrInvalidGUID
+	if !podops.ValidGUID(parent) {
+		return "", podops.ErrInvalidGUID
 	}
 
-	// swap the GUID for the short name
-	feedPath := filepath.Join(config.StorageLocation, parent, config.DefaultFeedName)
-
-	// parse feed.xml and extract the name & uri
-	file, err := os.Open(feedPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	fp := gofeed.NewParser()
-	feed, err := fp.Parse(file)
-	if err != nil {
-		return err
-	}
-	link, err := url.Parse(feed.Link)
-	if err != nil {
-		return err
-	}
-	parts := strings.Split(link.Path, "/")
-	name := parts[len(parts)-1]
-
-	repoPath := filepath.Join(config.StaticLocation, name)
+	// the checkout is keyed by the GUID, a show can be built before it has a feed or a name
+	repoPath := filepath.Join(config.StaticLocation, parent)
 
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
 		// git clone
@@ -50,24 +28,24 @@ func CloneOrPullRepo(repo, parent string) error {
 			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
 		})
 		if err != nil {
-			return err
+			return "", err
 		}
 	} else {
 		// git pull
 		r, err := git.PlainOpen(repoPath)
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		w, err := r.Worktree()
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		err = w.Pull(&git.PullOptions{RemoteName: "origin"})
-		if err != nil {
-			return err
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return "", err
 		}
 	}
-	return nil
+	return repoPath, nil
 }
@@ -0,0 +1,57 @@
+package cdn
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/storage"
+)
+
+func TestCloneOrPullRepo(t *testing.T) {
+	// a show that was never published, i.e. it has no feed.xml yet
+	storage.SetDefault(storage.NewLocal(t.TempDir()))
+
+	static := config.StaticLocation
+	config.StaticLocation = t.TempDir()
+	defer func() { config.StaticLocation = static }()
+
+	repo := t.TempDir()
+	r, err := git.PlainInit(repo, false)
+	assert.NoError(t, err)
+	w, err := r.Worktree()
+	assert.NoError(t, err)
+	commit := func(content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(repo, "show.yaml"), []byte(content), 0644))
+		_, err := w.Add("show.yaml")
+		assert.NoError(t, err)
+		_, err = w.Commit(content, &git.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}})
+		assert.NoError(t, err)
+	}
+
+	// the first push clones the repository
+	commit("first")
+	root, err := CloneOrPullRepo(repo, "aaa94297acfc")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(config.StaticLocation, "aaa94297acfc"), root)
+	data, err := os.ReadFile(filepath.Join(root, "show.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "first", string(data))
+
+	// later pushes pull the changes
+	commit("second")
+	_, err = CloneOrPullRepo(repo, "aaa94297acfc")
+	assert.NoError(t, err)
+	data, err = os.ReadFile(filepath.Join(root, "show.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+
+	_, err = CloneOrPullRepo(repo, "../outside")
+	assert.Error(t, err)
+}
@@ -2,32 +2,49 @@ package cdn
 
 import (
 	"context"
-	"fmt"
-	"io/ioutil"
-	"net/url"
-	"os"
-	"path/filepath"
-	"strconv"
+	"path"
+	"sort"
 	"strings"
 	"sync"
 
-	"github.com/mmcdole/gofeed"
-
-	"github.com/txsvc/stdlib/v2/settings"
-	"github.com/txsvc/stdlib/v2/timestamp"
-
+	"github.com/podops/podops"
 	"github.com/podops/podops/auth"
 	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal"
 	"github.com/podops/podops/internal/metadata"
+	"github.com/podops/podops/internal/storage"
+)
+
+type (
+	// showMapping is what a show contributes to the mappings
+	showMapping struct {
+		parent    string
+		name      string
+		reserved  int64 // time the name was reserved, 0 if the name is taken from the feed
+		served    bool  // false if the show has no feed yet
+		aliases   []string
+		redirects []Redirect
+	}
 )
 
 var (
-	feedPathMapping map[string]string // e.g. /minimalpodcast/feed or /minimalpodcast/feed.xml -> /a7c94297acfc/feed.xml
-	nameMapping     map[string]string // e.g. minimalpodcast -> a7c94297acfc
+	feedPathMapping map[string]string       // e.g. /minimalpodcast/feed or /minimalpodcast/feed.xml -> a7c94297acfc/feed.xml
+	nameMapping     map[string]string       // e.g. minimalpodcast -> a7c94297acfc
+	redirectMapping map[string]string       // e.g. /oldname/feed.xml -> /minimalpodcast/feed.xml
+	conflicts       map[string]Conflict     // e.g. minimalpodcast -> the shows that claim the name
+	shows           map[string]*showMapping // e.g. a7c94297acfc -> what the show contributes to the maps above
+	deleted         map[string]bool         // GUIDs of soft-deleted shows, their files are not served
+	indexed         bool                    // true once CreateInventoryMappings was called in this process
 
 	mu sync.Mutex // used to protect the above maps
 )
 
+func init() {
+	shows = make(map[string]*showMapping)
+	deleted = make(map[string]bool)
+	rebuildMappings()
+}
+
 // Rewrite takes the canonical name of a podcast feed and returns its path in the cdn.
 // If no mapping exists, the function just returns an empty string/false as status code.
 func Rewrite(path string) (string, bool) {
@@ -52,136 +69,248 @@ func ResolveName(name string) (string, bool) {
 	return "", false
 }
 
-// CreateInventoryMappings scans a file location for podcast feeds and creates
-// the canonical names mappings to their file location on the cdn.
-func CreateInventoryMappings(ctx context.Context, root string) error {
+// CreateInventoryMappings scans the storage for podcast feeds and name reservations
+// and creates the canonical names mappings to their keys in the cdn storage.
+func CreateInventoryMappings(ctx context.Context) error {
+	parents, err := listParents(ctx, config.DefaultFeedName, config.DefaultShowMetaFileLocation)
+	if err != nil {
+		return err
+	}
+
+	all := make(map[string]*showMapping)
+	removed := make(map[string]bool)
+	for _, parent := range parents {
+		m, isDeleted, err := loadShowMapping(ctx, parent)
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			all[parent] = m
+		}
+		if isDeleted {
+			removed[parent] = true
+		}
+	}
 
 	mu.Lock()
 	defer mu.Unlock()
 
-	// re-initialize the maps i.e. forget old stuff
-	feedPathMapping = make(map[string]string)
-	nameMapping = make(map[string]string)
+	// forget old stuff
+	shows = all
+	deleted = removed
+	rebuildMappings()
+	indexed = true
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-
-		name := filepath.Base(path)
-		if name == config.DefaultFeedName {
-			parts := strings.Split(path, "/")
-			parent := parts[len(parts)-2]
+	return nil
+}
 
-			// parse feed.xml and extract the name & uri
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
+// UpdateInventoryMapping re-reads a single show and updates the mappings.
+// Deleted shows and shows without a feed or reservation are removed from the mappings.
+func UpdateInventoryMapping(ctx context.Context, parent string) error {
+	m, isDeleted, err := loadShowMapping(ctx, parent)
+	if err != nil {
+		return err
+	}
 
-			fp := gofeed.NewParser()
-			feed, err := fp.Parse(file)
-			if err != nil {
-				return err
-			}
+	mu.Lock()
+	defer mu.Unlock()
 
-			link, err := url.Parse(feed.Link)
-			if err != nil {
-				return err
-			}
+	if m != nil {
+		shows[parent] = m
+	} else {
+		delete(shows, parent)
+	}
+	if isDeleted {
+		deleted[parent] = true
+	} else {
+		delete(deleted, parent)
+	}
+	rebuildMappings()
 
-			// FIXME what if the mapping already exists? should not happen but ... ?
+	return nil
+}
 
-			// map path/feed and path/feed.xml to the location in the cdn storage
-			feedPathMapping[link.Path+"/feed"] = path
-			feedPathMapping[link.Path+"/feed.xml"] = path
+// loadShowMapping reads the name, aliases and redirects of a show. It returns nil
+// if the show neither is served nor has reserved a name, and true if it was deleted.
+func loadShowMapping(ctx context.Context, parent string) (*showMapping, bool, error) {
+	// deleted shows are not served
+	meta, err := LoadShowMeta(ctx, parent)
+	if err != nil {
+		return nil, false, err
+	}
+	if meta.Deleted > 0 {
+		return nil, true, nil
+	}
 
-			// map podcast name to GUID
-			nameMapping[link.Path[1:]] = parent
+	m := showMapping{
+		parent:   parent,
+		name:     meta.Name,
+		reserved: meta.Reserved,
+		served:   storage.Exists(ctx, storage.Default(), storage.Key(parent, config.DefaultFeedName)),
+		aliases:  meta.Aliases,
+	}
+	if !m.served && m.name == "" {
+		return nil, false, nil
+	}
+	if m.name == "" {
+		// parse feed.xml and extract the name
+		if m.name, err = feedName(ctx, parent); err != nil {
+			return nil, false, err
 		}
+	}
+	if meta.Name == "" {
+		m.reserved = 0 // the name is not reserved, only taken from the feed
+	}
 
-		return nil
-	})
+	// previous names and moved feeds are redirected
+	if m.redirects, err = LoadRedirects(ctx, parent); err != nil {
+		return nil, false, err
+	}
 
-	return err
+	return &m, false, nil
 }
 
-// CreateCredentialsMappings scans a file location for repo specific client credentials.
-func CreateCredentialsMappings(ctx context.Context, root string) error {
+// rebuildMappings derives the maps from the shows. If several shows claim the same name,
+// only the one with the best claim gets it, see precedes. The caller holds mu.
+func rebuildMappings() {
+	feedPathMapping = make(map[string]string)
+	nameMapping = make(map[string]string)
+	redirectMapping = make(map[string]string)
+	found := make(map[string]Conflict)
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	// the claims on each name, best claim first
+	claims := make(map[string][]*showMapping)
+	for _, m := range shows {
+		claims[m.name] = append(claims[m.name], m)
+	}
+	for name, c := range claims {
+		sort.Slice(c, func(i, j int) bool { return precedes(c[i], c[j]) })
+
+		owner := c[0]
+		nameMapping[name] = owner.parent
+		if owner.served {
+			// map path/feed and path/feed.xml to the key in the cdn storage
+			key := storage.Key(owner.parent, config.DefaultFeedName)
+			feedPathMapping["/"+name+"/feed"] = key
+			feedPathMapping["/"+name+"/feed.xml"] = key
+		}
 
-		name := filepath.Base(path)
-		if name == config.DefaultMasterKeyFileLocation {
-			if cfg, err := settings.ReadSettingsFromFile(path); err == nil {
-				auth.RegisterAuthorization(cfg)
+		if len(c) > 1 {
+			conflict := Conflict{Name: name, Owner: owner.parent, Rejected: make([]string, 0, len(c)-1)}
+			for _, m := range c[1:] {
+				conflict.Rejected = append(conflict.Rejected, m.parent)
 			}
+			found[name] = conflict
 		}
+	}
 
-		return nil
-	})
+	// shows that lost their name can not redirect either. A show only redirects its own name
+	// and aliases, aliases owned by other shows are ignored, the first show (by GUID) gets them.
+	parents := make([]string, 0, len(shows))
+	for parent, m := range shows {
+		if nameMapping[m.name] == parent && m.served {
+			parents = append(parents, parent)
+		}
+	}
+	sort.Strings(parents)
+
+	for _, parent := range parents {
+		m := shows[parent]
+		for path, to := range mapRedirects(m.name, m.aliases, m.redirects) {
+			name := strings.Split(path, "/")[1]
+			if owner, ok := nameMapping[name]; ok && owner != parent {
+				continue
+			}
+			if _, ok := redirectMapping[path]; !ok {
+				redirectMapping[path] = to
+			}
+		}
+	}
 
-	return err
+	logConflicts(found)
+	conflicts = found
 }
 
-// ListResources collects all the media resources in the specified location
-// and returns the metadata for these resources.
-func ListResources(ctx context.Context, root string) (*[]metadata.Metadata, error) {
-
-	var rsrc []metadata.Metadata
-	rsrc = make([]metadata.Metadata, 0)
+// CreateCredentialsMappings scans the storage for repo specific client credentials.
+func CreateCredentialsMappings(ctx context.Context) error {
+	parents, err := listParents(ctx, config.DefaultMasterKeyFileLocation)
+	if err != nil {
+		return err
+	}
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, e error) error {
-		fi, err := os.Stat(path)
-		if err != nil {
-			return err
-		}
-		if fi.IsDir() {
-			return nil
+	for _, parent := range parents {
+		if cfg, err := ReadCredentials(ctx, parent); err == nil {
+			auth.RegisterAuthorization(cfg)
 		}
+	}
+	return nil
+}
 
-		ext := filepath.Ext(path)
+// ListResources collects all the media resources of a show
+// and returns the metadata for these resources.
+func ListResources(ctx context.Context, parent string) (*[]metadata.Metadata, error) {
+	objects, err := storage.Default().List(ctx, parent+"/")
+	if err != nil {
+		return nil, err
+	}
 
+	rsrc := make([]metadata.Metadata, 0)
+	for _, o := range objects {
+		ext := path.Ext(o.Key)
 		if ext == ".xml" || ext == ".yaml" || ext == ".key" {
-			return nil // skip e.g feed.xml
+			continue // skip e.g feed.xml
 		}
 
-		m, err := metadata.ExtractMetadataFromFile(path)
-		if err != nil {
-			return err
+		m := metadata.Metadata{
+			Name:        path.Base(o.Key),
+			Size:        o.Size,
+			ContentType: o.ContentType,
+			Timestamp:   o.Modified.Unix(),
 		}
-
 		parts := strings.Split(m.Name, ".")
 		if len(parts) == 2 {
-			// relace the etag as it does not reflect the etag on the client / producer side
+			// use the name as etag as it reflects the etag on the client / producer side
 			m.ETag = parts[0]
-		} else {
-			m.ETag = ""
 		}
-		rsrc = append(rsrc, *m)
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
+		m.ContentETag = ContentETag(&o)
+		rsrc = append(rsrc, m)
 	}
+
 	return &rsrc, nil
 }
 
-func MarkStorageChanged() {
-	path := filepath.Join(config.StorageLocation, config.DefaultTouchFile)
-	data := []byte(fmt.Sprintf("%d", timestamp.Now()))
-	os.WriteFile(path, data, 0644)
+// ContentETag returns the etag an object is served with. It changes whenever the object is replaced.
+func ContentETag(info *storage.ObjectInfo) string {
+	return internal.CreateETag(path.Base(info.Key), info.Size, info.Modified.Unix())
 }
 
-func IsStorageChanged(ts int64) bool {
-	path := filepath.Join(config.StorageLocation, config.DefaultTouchFile)
-	body, err := ioutil.ReadFile(path)
+// ShowExists returns true if the show was initialized on the cdn
+func ShowExists(ctx context.Context, parent string) bool {
+	return storage.Exists(ctx, storage.Default(), storage.Key(parent, config.DefaultMasterKeyFileLocation))
+}
+
+// listParents returns the GUIDs of all shows that have a file with one of the given names, ordered by GUID
+func listParents(ctx context.Context, names ...string) ([]string, error) {
+	objects, err := storage.Default().List(ctx, "")
 	if err != nil {
-		return true // better save than sorry, i.e. reload
+		return nil, err
 	}
-	i, err := strconv.ParseInt(string(body), 10, 0)
-	if err != nil {
-		return true // better save than sorry, i.e. reload
+
+	parents := make([]string, 0)
+	for _, o := range objects {
+		parts := strings.Split(o.Key, "/")
+		if len(parts) != 2 || !podops.ValidGUID(parts[0]) {
+			continue
+		}
+		if n := len(parents); n > 0 && parents[n-1] == parts[0] {
+			continue // keys are sorted, i.e. the show was added already
+		}
+		for _, name := range names {
+			if parts[1] == name {
+				parents = append(parents, parts[0])
+				break
+			}
+		}
 	}
-	return ts < i
+	return parents, nil
 }
@@ -3,10 +3,11 @@ package cdn
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/storage"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,7 +18,9 @@ const (
 
 func TestCreateInventoryMappings(t *testing.T) {
 
-	err := CreateInventoryMappings(context.TODO(), rootDir)
+	storage.SetDefault(storage.NewLocal(rootDir))
+
+	err := CreateInventoryMappings(context.TODO())
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -27,8 +30,9 @@ func TestCreateInventoryMappings(t *testing.T) {
 
 func TestInventory(t *testing.T) {
 
-	root := filepath.Join(inventoryDir, config.BuildLocation)
-	rsrc, err := ListResources(context.TODO(), root)
+	storage.SetDefault(storage.NewLocal(inventoryDir))
+
+	rsrc, err := ListResources(context.TODO(), config.BuildLocation)
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -37,3 +41,77 @@ func TestInventory(t *testing.T) {
 	assert.NotNil(t, rsrc)
 	assert.NotEmpty(t, rsrc)
 }
+
+func TestUpdateInventoryMapping(t *testing.T) {
+	s := storage.NewLocal(t.TempDir())
+	storage.SetDefault(s)
+
+	parent := "aaa94297acfc"
+	feed := `<rss version="2.0"><channel><title>Minimal</title><link>https://podops.dev/minimalpodcast</link></channel></rss>`
+
+	assert.NoError(t, CreateInventoryMappings(context.TODO()))
+	_, ok := ResolveName("minimalpodcast")
+	assert.False(t, ok)
+
+	// a new feed
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, storage.Key(parent, config.DefaultFeedName), []byte(feed)))
+	assert.NoError(t, PublishChange(context.TODO(), parent))
+
+	key, ok := Rewrite("/minimalpodcast/feed.xml")
+	assert.True(t, ok)
+	assert.Equal(t, "aaa94297acfc/feed.xml", key)
+
+	// a rename replaces the mappings of the show
+	assert.NoError(t, RenameShow(context.TODO(), parent, "newpodcast"))
+
+	_, ok = Rewrite("/minimalpodcast/feed.xml")
+	assert.False(t, ok)
+	guid, ok := ResolveName("newpodcast")
+	assert.True(t, ok)
+	assert.Equal(t, parent, guid)
+	to, ok := LookupRedirect("/minimalpodcast/feed")
+	assert.True(t, ok)
+	assert.Equal(t, "/newpodcast/feed.xml", to)
+
+	// deleted shows are removed
+	assert.NoError(t, DeleteShow(context.TODO(), parent))
+
+	_, ok = Rewrite("/newpodcast/feed")
+	assert.False(t, ok)
+	_, ok = LookupRedirect("/minimalpodcast/feed")
+	assert.False(t, ok)
+
+	// all changes are in the journal
+	events, err := ReadChanges(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, parent, events[0].Parent)
+
+	assert.NoError(t, ExpireChanges(context.TODO(), -time.Second))
+	events, err = ReadChanges(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestChangeWatcher(t *testing.T) {
+	storage.SetDefault(storage.NewLocal(t.TempDir()))
+
+	w := &changeWatcher{seen: make(map[string]bool)}
+	assert.NoError(t, PublishChange(context.TODO(), "aaa94297acfc"))
+
+	events, err := w.poll(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(events))
+
+	events, err = w.poll(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	// handlers learn about the changed shows
+	changed := make([]string, 0)
+	OnChange(func(parent string) { changed = append(changed, parent) })
+
+	assert.NoError(t, PublishChange(context.TODO(), "aaa94297acfc"))
+	assert.NoError(t, w.apply(context.TODO()))
+	assert.Equal(t, []string{"aaa94297acfc"}, changed)
+}
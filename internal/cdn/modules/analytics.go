@@ -0,0 +1,144 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	analyticsOff  = "off"
+	analyticsLog  = "log"
+	analyticsFile = "file"
+)
+
+type (
+	// AnalyticsConfig selects where served requests are recorded:
+	// log writes them to the handler's log, file appends them as JSON lines to Path.
+	AnalyticsConfig struct {
+		Sink string `json:"sink"`
+		Path string `json:"path,omitempty"`
+	}
+
+	// analyticsEvent describes a served request
+	analyticsEvent struct {
+		Timestamp  int64  `json:"timestamp"`
+		Metric     string `json:"metric"`
+		Parent     string `json:"parent"`
+		Name       string `json:"name"`
+		Type       string `json:"type"`
+		Size       int64  `json:"size"`
+		Method     string `json:"method"`
+		Range      string `json:"range,omitempty"`
+		UserAgent  string `json:"user_agent"`
+		RemoteAddr string `json:"remote_addr"`
+	}
+
+	// analyticsSink records events
+	analyticsSink interface {
+		record(e *analyticsEvent)
+		io.Closer
+	}
+
+	// sinkFunc opens an analytics sink
+	sinkFunc func(cfg *AnalyticsConfig, logger *zap.Logger) (analyticsSink, error)
+
+	logSink struct {
+		logger *zap.Logger
+	}
+
+	fileSink struct {
+		mu sync.Mutex
+		f  *os.File
+	}
+)
+
+var (
+	sinks = map[string]sinkFunc{
+		analyticsLog:  newLogSink,
+		analyticsFile: newFileSink,
+	}
+)
+
+// openAnalyticsSink returns the configured sink or nil if nothing is recorded
+func openAnalyticsSink(cfg *AnalyticsConfig, logger *zap.Logger) (analyticsSink, error) {
+	if cfg == nil || cfg.Sink == analyticsOff {
+		return nil, nil
+	}
+	if fn, ok := sinks[cfg.Sink]; ok {
+		return fn(cfg, logger)
+	}
+	return nil, fmt.Errorf("unknown analytics sink '%s'", cfg.Sink)
+}
+
+// record passes a served request to the analytics sink, if there is one
+func (c *Config) record(metric, parent, name, contentType string, size int64, r *http.Request) {
+	if c.sink == nil {
+		return
+	}
+	c.sink.record(&analyticsEvent{
+		Timestamp:  time.Now().Unix(),
+		Metric:     metric,
+		Parent:     parent,
+		Name:       name,
+		Type:       contentType,
+		Size:       size,
+		Method:     r.Method,
+		Range:      r.Header.Get("Range"),
+		UserAgent:  r.UserAgent(),
+		RemoteAddr: r.RemoteAddr,
+	})
+}
+
+func newLogSink(cfg *AnalyticsConfig, logger *zap.Logger) (analyticsSink, error) {
+	return &logSink{logger: logger}, nil
+}
+
+func (s *logSink) record(e *analyticsEvent) {
+	s.logger.Info(e.Metric,
+		zap.String("parent", e.Parent),
+		zap.String("name", e.Name),
+		zap.String("type", e.Type),
+		zap.Int64("size", e.Size),
+		zap.String("method", e.Method),
+		zap.String("range", e.Range),
+		zap.String("user_agent", e.UserAgent),
+		zap.String("remote_addr", e.RemoteAddr),
+	)
+}
+
+func (s *logSink) Close() error {
+	return nil
+}
+
+func newFileSink(cfg *AnalyticsConfig, logger *zap.Logger) (analyticsSink, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("analytics sink '%s' needs a path", cfg.Sink)
+	}
+	f, err := os.OpenFile(cfg.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{f: f}, nil
+}
+
+func (s *fileSink) record(e *analyticsEvent) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.f.Write(append(data, '\n')) // analytics are best effort only, ignore errors
+}
+
+func (s *fileSink) Close() error {
+	return s.f.Close()
+}
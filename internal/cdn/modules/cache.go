@@ -1,82 +1,433 @@
 package modules
 
 import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"context"
 	"fmt"
+	"io"
 	weakrand "math/rand"
+	"mime"
 	"net/http"
 	"os"
+	"path"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/andybalholm/brotli"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
 
 	"github.com/podops/podops/config"
-	"github.com/podops/podops/internal/metadata"
+	"github.com/podops/podops/internal/cdn"
+	"github.com/podops/podops/internal/storage"
 )
 
 const (
-	cacheControl           = "public, max-age=1800"
+	defaultMaxAge          = 30 * time.Minute
+	defaultMetadataTTL     = time.Minute
+	maxCachedObjects       = 10000    // metadata only, the compressed copies are bounded by maxEncodedBytes
+	maxEncodedSize         = 10 << 20 // larger objects are not compressed
+	maxEncodedBytes        = 64 << 20 // memory for compressed copies of all objects
 	minBackoff, maxBackoff = 2, 5
 )
 
-// serveAndCache returns the requested resource. The implementation borrows from Caddy's own implementation:
-// https://github.com/caddyserver/caddy/blob/master/modules/caddyhttp/fileserver/staticfiles.go
-func serveAndCache(parent, path, metric string, w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+type (
+	// CacheConfig configures the lifetimes of the responses and of the cached metadata:
+	//
+	//	cdn_mapping {
+	//		max_age application/rss+xml 5m
+	//		max_age audio/* 7d
+	//		metadata_ttl 1m
+	//	}
+	CacheConfig struct {
+		// MaxAge maps content types to the max-age of the responses. Types are matched exactly,
+		// then by e.g. audio/* and then by *. The default is 30 minutes.
+		MaxAge map[string]caddy.Duration `json:"max_age,omitempty"`
+		// MetadataTTL is the time the metadata of an object is cached in memory. Feeds of
+		// changed shows are dropped from the cache right away. The default is one minute.
+		MetadataTTL caddy.Duration `json:"metadata_ttl,omitempty"`
+	}
+
+	// cachedObject is what is kept in memory about an object in the storage
+	cachedObject struct {
+		info    *storage.ObjectInfo
+		etag    string
+		expires time.Time
+
+		mu sync.Mutex // an object is compressed only once at a time
+	}
+
+	// encodedCache keeps compressed copies of objects up to max bytes, the least recently used are dropped first
+	encodedCache struct {
+		mu     sync.Mutex
+		max    int64
+		size   int64
+		lru    *list.List // of *encodedCopy, the most recently used first
+		copies map[string]*list.Element
+	}
+
+	// encodedCopy is an object compressed with a content encoding
+	encodedCopy struct {
+		id   string // key, etag and content encoding
+		key  string
+		data []byte
+	}
+
+	// encoder compresses responses with a content encoding
+	encoder struct {
+		name      string
+		newWriter func(w io.Writer) io.WriteCloser
+	}
+)
+
+var (
+	objects   = make(map[string]*cachedObject) // by key in the storage
+	objectsMu sync.Mutex
+	encoded   = newEncodedCache(maxEncodedBytes)
 
-	// only continue if the file exits
-	info, err := os.Stat(path)
-	if os.IsNotExist(err) {
-		return next.ServeHTTP(w, r)
+	// encoders in order of preference
+	encoders = []encoder{
+		{"br", func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }},
+		{"gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
 	}
+)
+
+func init() {
+	cdn.OnChange(dropCachedObjects)
+}
+
+// serveAndCache returns the requested resource. The implementation borrows from Caddy's own implementation:
+// https://github.com/caddyserver/caddy/blob/master/modules/caddyhttp/fileserver/staticfiles.go
+func (c *Config) serveAndCache(parent, key, metric string, w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	ctx := r.Context()
 
-	// extract metadata from the file and the headers
-	meta, err := metadata.ExtractMetadataFromFile(path)
+	// only continue if the object exits
+	obj, err := lookupObject(ctx, key, c.metadataTTL())
 	if err != nil {
-		return err
+		if storage.IsNotExist(err) {
+			return next.ServeHTTP(w, r)
+		}
+		return unavailable(err, w)
 	}
-	//headers := api.ExtractHeaders(r)
-	//offset, length := headers.Ranges()
 
 	// metrics for analytics
-	// FIXME observer.Meter(context.TODO(), metric, "production", parent, "user-agent", headers.UserAgent, "remote_addr", r.RemoteAddr, "type", meta.ContentType, "method", r.Method, "name", meta.Name, "size", fmt.Sprintf("%d", meta.Size), "offset", fmt.Sprintf("%d", offset), "length", fmt.Sprintf("%d", length))
+	c.record(metric, parent, path.Base(key), obj.info.ContentType, obj.info.Size, r)
 
-	// write our own set of headers for the response
-	w.Header().Add("etag", meta.ETag)
-	w.Header().Add("accept-ranges", "bytes")
-	w.Header().Add("cache-control", cacheControl)
-	w.Header().Add("content-type", meta.ContentType)
-	w.Header().Add("content-length", fmt.Sprintf("%d", meta.Size))
-	w.Header().Add("x-served-by", config.ServerString) // FIXME change to a podops name?
+	// write our own set of headers for the response. http.ServeContent adds content-length
+	// and last-modified and answers conditional and range requests.
+	w.Header().Set("accept-ranges", "bytes")
+	w.Header().Set("cache-control", c.cacheControl(obj.info.ContentType))
+	w.Header().Set("content-type", obj.info.ContentType)
+	w.Header().Set("x-served-by", config.ServerString) // FIXME change to a podops name?
 
-	file, err := openFile(path, w)
-	if err != nil {
-		return err
+	// feeds are compressed if the client accepts it, each encoding has its own etag
+	if compressible(obj.info) {
+		w.Header().Add("vary", "Accept-Encoding")
+
+		if enc := acceptedEncoding(r.Header.Get("Accept-Encoding")); enc != nil {
+			data, err := obj.encode(ctx, enc)
+			if err != nil {
+				return unavailable(err, w)
+			}
+			w.Header().Set("content-encoding", enc.name)
+			w.Header().Set("etag", fmt.Sprintf("\"%s-%s\"", obj.etag, enc.name))
+
+			http.ServeContent(w, r, "", obj.info.Modified, bytes.NewReader(data))
+			return nil
+		}
 	}
-	defer file.Close()
+	w.Header().Set("etag", fmt.Sprintf("\"%s\"", obj.etag))
+
+	// objects are read lazily with range requests, i.e. only what is requested is transferred
+	object := storage.NewReadSeeker(ctx, storage.Default(), key, obj.info.Size)
+	defer object.Close()
 
 	// let the implementation in the standard library deal with this ...
-	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	http.ServeContent(w, r, "", obj.info.Modified, object)
 
 	return nil
 }
 
-func openFile(filename string, w http.ResponseWriter) (*os.File, error) {
-	file, err := os.Open(filename)
+// cacheControl returns the cache-control header for a content type
+func (cc CacheConfig) cacheControl(contentType string) string {
+	maxAge := defaultMaxAge
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	for _, pattern := range []string{mediaType, strings.Split(mediaType, "/")[0] + "/*", "*"} {
+		if d, ok := cc.MaxAge[pattern]; ok {
+			maxAge = time.Duration(d)
+			break
+		}
+	}
+
+	return fmt.Sprintf("public, max-age=%d", int64(maxAge.Seconds()))
+}
+
+func (cc CacheConfig) metadataTTL() time.Duration {
+	if cc.MetadataTTL > 0 {
+		return time.Duration(cc.MetadataTTL)
+	}
+	return defaultMetadataTTL
+}
+
+// validate checks the cache settings
+func (cc CacheConfig) validate() error {
+	for pattern, d := range cc.MaxAge {
+		if pattern != "*" && !strings.Contains(pattern, "/") {
+			return fmt.Errorf("invalid content type '%s'", pattern)
+		}
+		if d < 0 {
+			return fmt.Errorf("invalid max_age for '%s'", pattern)
+		}
+	}
+	if cc.MetadataTTL < 0 {
+		return fmt.Errorf("invalid metadata_ttl")
+	}
+	return nil
+}
+
+// unmarshalSubdirective reads the cache setting the dispenser is at
+func (cc *CacheConfig) unmarshalSubdirective(d *caddyfile.Dispenser) error {
+	switch d.Val() {
+	case "max_age":
+		args := d.RemainingArgs()
+		if len(args) != 2 {
+			return d.ArgErr()
+		}
+		maxAge, err := caddy.ParseDuration(args[1])
+		if err != nil {
+			return d.Errf("invalid max_age '%s': %v", args[1], err)
+		}
+		if cc.MaxAge == nil {
+			cc.MaxAge = make(map[string]caddy.Duration)
+		}
+		cc.MaxAge[args[0]] = caddy.Duration(maxAge)
+
+	case "metadata_ttl":
+		if !d.NextArg() {
+			return d.ArgErr()
+		}
+		ttl, err := caddy.ParseDuration(d.Val())
+		if err != nil {
+			return d.Errf("invalid metadata_ttl '%s': %v", d.Val(), err)
+		}
+		cc.MetadataTTL = caddy.Duration(ttl)
+
+	default:
+		return d.Errf("unknown subdirective '%s'", d.Val())
+	}
+	return nil
+}
+
+// lookupObject returns the metadata of an object, from memory if possible
+func lookupObject(ctx context.Context, key string, ttl time.Duration) (*cachedObject, error) {
+	objectsMu.Lock()
+	obj, ok := objects[key]
+	objectsMu.Unlock()
+
+	now := time.Now()
+	if ok && now.Before(obj.expires) {
+		return obj, nil
+	}
+
+	info, err := storage.Default().Stat(ctx, key)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			objectsMu.Lock()
+			delete(objects, key)
+			objectsMu.Unlock()
+		}
+		return nil, err
+	}
+
+	fresh := &cachedObject{
+		info:    info,
+		etag:    cdn.ContentETag(info),
+		expires: now.Add(ttl),
+	}
+
+	objectsMu.Lock()
+	defer objectsMu.Unlock()
+
+	if len(objects) >= maxCachedObjects {
+		objects = make(map[string]*cachedObject) // start over instead of tracking usage
+	}
+	objects[key] = fresh
+
+	return fresh, nil
+}
+
+// dropCachedObjects removes the objects of a show from the cache
+func dropCachedObjects(parent string) {
+	objectsMu.Lock()
+	for key := range objects {
+		if strings.HasPrefix(key, parent+"/") {
+			delete(objects, key)
+		}
+	}
+	objectsMu.Unlock()
+
+	encoded.drop(parent + "/")
+}
+
+// encode returns the compressed object, it is compressed only once as long as it stays in the cache
+func (obj *cachedObject) encode(ctx context.Context, enc *encoder) ([]byte, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	id := fmt.Sprintf("%s@%s/%s", obj.info.Key, obj.etag, enc.name)
+	if data, ok := encoded.get(id); ok {
+		return data, nil
+	}
 
+	data, err := storage.ReadFile(ctx, storage.Default(), obj.info.Key)
 	if err != nil {
+		return nil, err
+	}
 
-		if os.IsNotExist(err) {
-			return nil, caddyhttp.Error(http.StatusNotFound, err)
-		} else if os.IsPermission(err) {
-			return nil, caddyhttp.Error(http.StatusForbidden, err)
+	var buf bytes.Buffer
+	zw := enc.newWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	encoded.put(id, obj.info.Key, buf.Bytes())
+	return buf.Bytes(), nil
+}
+
+func newEncodedCache(max int64) *encodedCache {
+	return &encodedCache{
+		max:    max,
+		lru:    list.New(),
+		copies: make(map[string]*list.Element),
+	}
+}
+
+// get returns a compressed copy and marks it as recently used
+func (c *encodedCache) get(id string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.copies[id]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*encodedCopy).data, true
+}
+
+// put adds a compressed copy of the object key and drops the least recently used copies to make room for it
+func (c *encodedCache) put(id, key string, data []byte) {
+	if int64(len(data)) > c.max {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.copies[id]; ok {
+		c.remove(e)
+	}
+	c.copies[id] = c.lru.PushFront(&encodedCopy{id: id, key: key, data: data})
+	c.size += int64(len(data))
+
+	for c.size > c.max {
+		c.remove(c.lru.Back())
+	}
+}
+
+// drop removes the compressed copies of all objects with the prefix
+func (c *encodedCache) drop(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if strings.HasPrefix(e.Value.(*encodedCopy).key, prefix) {
+			c.remove(e)
 		}
+		e = next
+	}
+}
 
-		// maybe the server is under load and ran out of file descriptors?
-		// have client wait arbitrary seconds to help prevent a stampede
-		//nolint:gosec
-		backoff := weakrand.Intn(maxBackoff-minBackoff) + minBackoff
-		w.Header().Set("Retry-After", strconv.Itoa(backoff))
+func (c *encodedCache) remove(e *list.Element) {
+	cp := c.lru.Remove(e).(*encodedCopy)
+	delete(c.copies, cp.id)
+	c.size -= int64(len(cp.data))
+}
 
-		return nil, caddyhttp.Error(http.StatusServiceUnavailable, err)
+// compressible returns true for text based objects, e.g. feeds, that are small enough to be compressed in memory
+func compressible(info *storage.ObjectInfo) bool {
+	if info.Size > maxEncodedSize {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(info.ContentType)
+	if err != nil {
+		return false
 	}
-	return file, nil
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "xml") || strings.HasSuffix(mediaType, "json")
+}
+
+// acceptedEncoding returns the encoder to use for an Accept-Encoding header or nil.
+// The encoding with the highest q-value wins, ties are decided by the order of encoders.
+func acceptedEncoding(header string) *encoder {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = q
+	}
+
+	var best *encoder
+	bestQ := 0.0
+	for i := range encoders {
+		q, ok := accepted[encoders[i].name]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best = &encoders[i]
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// unavailable asks the client to come back later
+func unavailable(err error, w http.ResponseWriter) error {
+	if os.IsPermission(err) {
+		return caddyhttp.Error(http.StatusForbidden, err)
+	}
+
+	// maybe the server is under load and ran out of file descriptors?
+	// have client wait arbitrary seconds to help prevent a stampede
+	//nolint:gosec
+	backoff := weakrand.Intn(maxBackoff-minBackoff) + minBackoff
+	w.Header().Set("Retry-After", strconv.Itoa(backoff))
+
+	return caddyhttp.Error(http.StatusServiceUnavailable, err)
 }
@@ -0,0 +1,146 @@
+package modules
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops/internal/storage"
+)
+
+const (
+	parent = "aaa94297acfc"
+	feed   = `<rss version="2.0"><channel><title>Minimal</title><link>https://podops.dev/minimalpodcast</link></channel></rss>`
+)
+
+var notFound = caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+	w.WriteHeader(http.StatusNotFound)
+	return nil
+})
+
+func get(c Config, key string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/"+key, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	c.serveAndCache(parent, key, "test", w, r, notFound)
+	return w
+}
+
+func TestServeAndCache(t *testing.T) {
+	s := storage.NewLocal(t.TempDir())
+	storage.SetDefault(s)
+
+	key := storage.Key(parent, "feed.xml")
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, key, []byte(feed)))
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, storage.Key(parent, "episode.mp3"), []byte("not really audio")))
+
+	c := Config{CacheConfig: CacheConfig{MaxAge: map[string]caddy.Duration{"text/xml": caddy.Duration(5 * time.Minute)}}}
+
+	w := get(c, key, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, feed, w.Body.String())
+	assert.Equal(t, "public, max-age=300", w.Header().Get("cache-control"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("vary"))
+	etag := w.Header().Get("etag")
+	lastModified := w.Header().Get("last-modified")
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, lastModified)
+
+	// compressed
+	w = get(c, key, http.Header{"Accept-Encoding": {"gzip, deflate"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("content-encoding"))
+	assert.NotEqual(t, etag, w.Header().Get("etag"))
+	zr, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(zr)
+	assert.Equal(t, feed, string(data))
+
+	w = get(c, key, http.Header{"Accept-Encoding": {"gzip;q=0.5, br"}})
+	assert.Equal(t, "br", w.Header().Get("content-encoding"))
+
+	// conditional requests
+	w = get(c, key, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	w = get(c, key, http.Header{"If-Modified-Since": {lastModified}})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	w = get(c, key, http.Header{"If-None-Match": {"\"something-else\""}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// media is not compressed
+	w = get(c, storage.Key(parent, "episode.mp3"), http.Header{"Accept-Encoding": {"gzip"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("content-encoding"))
+	assert.Equal(t, "public, max-age=1800", w.Header().Get("cache-control"))
+
+	// changed shows are dropped from the cache
+	assert.NoError(t, storage.WriteFile(context.TODO(), s, key, []byte(feed+"\n")))
+	dropCachedObjects(parent)
+	w = get(c, key, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = get(c, storage.Key(parent, "unknown.mp3"), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestEncodedCache(t *testing.T) {
+	c := newEncodedCache(10)
+
+	c.put("a", parent+"/a.xml", []byte("1234"))
+	c.put("b", parent+"/b.xml", []byte("1234"))
+	_, ok := c.get("a") // b is now the least recently used
+	assert.True(t, ok)
+	c.put("c", "other/c.xml", []byte("1234"))
+
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	data, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1234", string(data))
+	assert.Equal(t, int64(8), c.size)
+
+	// too large to be kept at all
+	c.put("d", parent+"/d.xml", []byte("12345678901"))
+	_, ok = c.get("d")
+	assert.False(t, ok)
+
+	c.drop(parent + "/")
+	_, ok = c.get("a")
+	assert.False(t, ok)
+	_, ok = c.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), c.size)
+}
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.1, gzip", "gzip"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if enc := acceptedEncoding(tt.header); enc != nil {
+			got = enc.name
+		}
+		assert.Equal(t, tt.want, got, tt.header)
+	}
+}
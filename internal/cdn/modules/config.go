@@ -0,0 +1,182 @@
+package modules
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+
+	"github.com/podops/podops/internal/storage"
+)
+
+type (
+	// Config is shared by the cdn_mapping and cdn_server directives. Missing settings
+	// fall back to the environment, e.g. PODOPS_STORAGE_BACKEND and PODOPS_STORAGE_LOCATION.
+	//
+	//	cdn_server {
+	//		storage local /data/storage
+	//		methods GET HEAD
+	//		analytics file /data/logs/analytics.log
+	//		max_age audio/* 7d
+	//	}
+	Config struct {
+		// Storage selects the storage backend and its root directory or bucket
+		Storage *StorageConfig `json:"storage,omitempty"`
+		// Methods are the request methods that are served, GET and HEAD by default.
+		// Requests with other methods are passed on to the next handler.
+		Methods []string `json:"methods,omitempty"`
+		// Analytics selects where served requests are recorded, nothing is recorded by default
+		Analytics *AnalyticsConfig `json:"analytics,omitempty"`
+
+		CacheConfig
+
+		sink analyticsSink
+	}
+
+	// StorageConfig selects the storage. The storage and the name mappings are shared by all
+	// handlers in a process, i.e. all handlers that set a storage have to use the same one.
+	StorageConfig struct {
+		// Backend is local or s3
+		Backend string `json:"backend"`
+		// Location is the root directory of a local storage or the bucket of an s3 storage
+		Location string `json:"location,omitempty"`
+	}
+)
+
+var (
+	activeStorage   *StorageConfig // the storage the first handler in this process selected
+	activeStorageMu sync.Mutex
+)
+
+// provision opens the storage and the analytics sink
+func (c *Config) provision(logger *zap.Logger) error {
+	if c.Storage != nil {
+		if err := selectStorage(c.Storage); err != nil {
+			return err
+		}
+	}
+
+	sink, err := openAnalyticsSink(c.Analytics, logger)
+	if err != nil {
+		return err
+	}
+	c.sink = sink
+
+	return nil
+}
+
+// cleanup closes the analytics sink
+func (c *Config) cleanup() error {
+	if c.sink != nil {
+		return c.sink.Close()
+	}
+	return nil
+}
+
+// validate checks the settings
+func (c *Config) validate() error {
+	for _, m := range c.Methods {
+		if m != http.MethodGet && m != http.MethodHead {
+			return fmt.Errorf("method '%s' can not be served, only GET and HEAD", m)
+		}
+	}
+	if c.Analytics != nil {
+		if _, ok := sinks[c.Analytics.Sink]; !ok && c.Analytics.Sink != analyticsOff {
+			return fmt.Errorf("unknown analytics sink '%s'", c.Analytics.Sink)
+		}
+	}
+	return c.CacheConfig.validate()
+}
+
+// serves returns true if requests with the method are served
+func (c *Config) serves(method string) bool {
+	if len(c.Methods) == 0 {
+		return method == http.MethodGet || method == http.MethodHead
+	}
+	for _, m := range c.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalSubdirective reads the setting the dispenser is at
+func (c *Config) unmarshalSubdirective(d *caddyfile.Dispenser) error {
+	switch d.Val() {
+	case "storage":
+		args := d.RemainingArgs()
+		if len(args) < 1 || len(args) > 2 {
+			return d.ArgErr()
+		}
+		c.Storage = &StorageConfig{Backend: args[0]}
+		if len(args) == 2 {
+			c.Storage.Location = args[1]
+		}
+
+	case "methods":
+		args := d.RemainingArgs()
+		if len(args) == 0 {
+			return d.ArgErr()
+		}
+		for _, m := range args {
+			c.Methods = append(c.Methods, strings.ToUpper(m))
+		}
+
+	case "analytics":
+		args := d.RemainingArgs()
+		if len(args) < 1 || len(args) > 2 {
+			return d.ArgErr()
+		}
+		c.Analytics = &AnalyticsConfig{Sink: args[0]}
+		if len(args) == 2 {
+			c.Analytics.Path = args[1]
+		}
+
+	default:
+		return c.CacheConfig.unmarshalSubdirective(d)
+	}
+	return nil
+}
+
+// unmarshalBlock calls f for every subdirective in the directive's block
+func unmarshalBlock(d *caddyfile.Dispenser, f func(d *caddyfile.Dispenser) error) error {
+	for d.Next() {
+		if d.NextArg() {
+			return d.ArgErr()
+		}
+		for d.NextBlock(0) {
+			if err := f(d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// selectStorage makes s the default storage of the process. Handlers can not use different
+// storages in the same process, changing the storage requires a restart.
+func selectStorage(s *StorageConfig) error {
+	activeStorageMu.Lock()
+	defer activeStorageMu.Unlock()
+
+	if activeStorage != nil {
+		if *activeStorage != *s {
+			return fmt.Errorf("storage '%s %s' conflicts with '%s %s' that is in use already", s.Backend, s.Location, activeStorage.Backend, activeStorage.Location)
+		}
+		return nil
+	}
+
+	st, err := storage.Open(s.Backend, s.Location)
+	if err != nil {
+		return fmt.Errorf("storage '%s': %w", s.Backend, err)
+	}
+	storage.SetDefault(st)
+	activeStorage = s
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package modules
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestContentMapperConfig(t *testing.T) {
+	var cm ContentMapper
+	d := caddyfile.NewTestDispenser(`cdn_mapping {
+		storage local /data/storage
+		methods get
+		analytics file /data/logs/analytics.log
+		reload scan 1m
+		redirect temporary
+		max_age application/rss+xml 5m
+		max_age audio/* 7d
+		max_age * 1h
+		metadata_ttl 10s
+	}`)
+	assert.NoError(t, cm.UnmarshalCaddyfile(d))
+	assert.NoError(t, cm.Validate())
+
+	assert.Equal(t, &StorageConfig{Backend: "local", Location: "/data/storage"}, cm.Storage)
+	assert.Equal(t, &AnalyticsConfig{Sink: "file", Path: "/data/logs/analytics.log"}, cm.Analytics)
+	assert.True(t, cm.serves(http.MethodGet))
+	assert.False(t, cm.serves(http.MethodHead))
+	assert.Equal(t, http.StatusFound, cm.redirectStatus())
+
+	strategy, interval := cm.reload()
+	assert.Equal(t, reloadScan, strategy)
+	assert.Equal(t, time.Minute, interval)
+
+	assert.Equal(t, "public, max-age=300", cm.cacheControl("application/rss+xml; charset=utf-8"))
+	assert.Equal(t, "public, max-age=604800", cm.cacheControl("audio/mpeg"))
+	assert.Equal(t, "public, max-age=3600", cm.cacheControl("image/png"))
+	assert.Equal(t, 10*time.Second, cm.metadataTTL())
+}
+
+func TestContentMapperDefaults(t *testing.T) {
+	var cm ContentMapper
+	assert.NoError(t, cm.UnmarshalCaddyfile(caddyfile.NewTestDispenser(`cdn_mapping`)))
+	assert.NoError(t, cm.Validate())
+
+	assert.True(t, cm.serves(http.MethodGet))
+	assert.True(t, cm.serves(http.MethodHead))
+	assert.False(t, cm.serves(http.MethodPost))
+	assert.Equal(t, http.StatusMovedPermanently, cm.redirectStatus())
+
+	strategy, interval := cm.reload()
+	assert.Equal(t, reloadJournal, strategy)
+	assert.Equal(t, defaultReloadInterval, interval)
+	assert.Equal(t, "public, max-age=1800", cm.cacheControl("audio/mpeg"))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	invalid := []string{
+		`cdn_mapping something`,
+		`cdn_mapping {
+			unknown
+		}`,
+		`cdn_mapping {
+			max_age 5m
+		}`,
+		`cdn_mapping {
+			reload journal soon
+		}`,
+		`cdn_mapping {
+			storage
+		}`,
+	}
+	for _, cfg := range invalid {
+		var cm ContentMapper
+		assert.Error(t, cm.UnmarshalCaddyfile(caddyfile.NewTestDispenser(cfg)), cfg)
+	}
+
+	assert.Error(t, (&ContentMapper{Reload: &ReloadConfig{Strategy: "sometimes"}}).Validate())
+	assert.Error(t, (&ContentMapper{Redirect: "maybe"}).Validate())
+	assert.Error(t, (&ContentMapper{Config: Config{Methods: []string{http.MethodPost}}}).Validate())
+	assert.Error(t, (&ContentMapper{Config: Config{Analytics: &AnalyticsConfig{Sink: "somewhere"}}}).Validate())
+	assert.Error(t, (&ContentStorage{Config: Config{CacheConfig: CacheConfig{MaxAge: map[string]caddy.Duration{"audio": 1}}}}).Validate())
+}
+
+func TestStorageConflicts(t *testing.T) {
+	root := t.TempDir()
+
+	assert.NoError(t, selectStorage(&StorageConfig{Backend: "local", Location: root}))
+	assert.NoError(t, selectStorage(&StorageConfig{Backend: "local", Location: root}))
+	assert.Error(t, selectStorage(&StorageConfig{Backend: "local", Location: "/data/other"}))
+}
+
+func TestFileAnalytics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analytics.log")
+
+	c := Config{Analytics: &AnalyticsConfig{Sink: "file", Path: path}}
+	assert.NoError(t, c.provision(zap.NewNop()))
+
+	r, _ := http.NewRequest(http.MethodGet, "/aaa94297acfc/episode.mp3", nil)
+	r.Header.Set("Range", "bytes=0-99")
+	c.record("cdn.content.get", "aaa94297acfc", "episode.mp3", "audio/mpeg", 1000, r)
+	assert.NoError(t, c.cleanup())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+	assert.Contains(t, string(data), `"range":"bytes=0-99"`)
+}
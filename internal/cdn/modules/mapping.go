@@ -2,6 +2,7 @@ package modules
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -13,9 +14,6 @@ import (
 	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
 
-	"github.com/txsvc/stdlib/v2/timestamp"
-
-	"github.com/podops/podops/config"
 	"github.com/podops/podops/internal/cdn"
 )
 
@@ -23,19 +21,53 @@ import (
 // see https://github.com/pquerna/cachecontrol
 
 const (
-	reloadInterval = 60 // seconds
+	// reload strategies
+	reloadJournal = "journal" // apply the changes the API publishes
+	reloadScan    = "scan"    // re-read the whole storage
+	reloadOff     = "off"     // read the storage once at startup
+
+	// redirect behaviour
+	redirectPermanent = "permanent"
+	redirectTemporary = "temporary"
+	redirectOff       = "off"
+
+	defaultReloadInterval = 2 * time.Second
 )
 
 type (
+	// ContentMapper serves the feeds of the shows by their canonical names, e.g. /minimalpodcast/feed.xml
+	//
+	//	cdn_mapping {
+	//		reload journal 2s
+	//		redirect permanent
+	//	}
+	//
+	// All settings of the cdn_server directive are supported as well.
 	ContentMapper struct {
+		Config
+
+		// Reload selects how the name mappings are kept up-to-date, see ReloadConfig
+		Reload *ReloadConfig `json:"reload,omitempty"`
+		// Redirect is permanent (301, the default), temporary (302) or off, i.e. moved feeds are not redirected
+		Redirect string `json:"redirect,omitempty"`
+
 		logger *zap.Logger
 	}
+
+	// ReloadConfig selects how the name mappings are kept up-to-date
+	ReloadConfig struct {
+		// Strategy is journal (the default), scan or off
+		Strategy string `json:"strategy"`
+		// Interval is the time between two reloads, 2 seconds by default
+		Interval caddy.Duration `json:"interval,omitempty"`
+	}
 )
 
 var (
 	// Interface guards
 	_ caddy.Validator             = (*ContentMapper)(nil)
 	_ caddy.Provisioner           = (*ContentMapper)(nil)
+	_ caddy.CleanerUpper          = (*ContentMapper)(nil)
 	_ caddyhttp.MiddlewareHandler = (*ContentMapper)(nil)
 	_ caddyfile.Unmarshaler       = (*ContentMapper)(nil)
 )
@@ -47,19 +79,24 @@ func init() {
 
 func (cr ContentMapper) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
 
-	// anything else than a GET/HEAD is handled elsewhere
-	switch r.Method {
-	case http.MethodGet:
-	case http.MethodHead:
-	default:
+	// anything else than e.g. a GET/HEAD is handled elsewhere
+	if !cr.serves(r.Method) {
 		return next.ServeHTTP(w, r)
 	}
 
-	if path, ok := cdn.Rewrite(r.RequestURI); ok {
+	// moved feeds are redirected
+	if status := cr.redirectStatus(); status != 0 {
+		if to, ok := cdn.LookupRedirect(r.RequestURI); ok {
+			http.Redirect(w, r, to, status)
+			return nil
+		}
+	}
+
+	if key, ok := cdn.Rewrite(r.RequestURI); ok {
 		parts := strings.Split(r.RequestURI[1:], "/")
 		guid, _ := cdn.ResolveName(parts[0])
 
-		return serveAndCache(guid, path, "cdn.feed.get", w, r, next)
+		return cr.serveAndCache(guid, key, "cdn.feed.get", w, r, next)
 	}
 
 	return next.ServeHTTP(w, r)
@@ -75,45 +112,128 @@ func (ContentMapper) CaddyModule() caddy.ModuleInfo {
 func (cm *ContentMapper) Provision(ctx caddy.Context) error {
 
 	cm.logger = ctx.Logger(cm)
+	if err := cm.provision(cm.logger); err != nil {
+		return err
+	}
 
-	// setup a goroutine that periodically scans the StorageLocation for changes
-	ticker := time.NewTicker(reloadInterval * time.Second)
-	quit := make(chan struct{})
-	go func() {
-		ts := cm.reload(0)
-		for {
-			select {
-			case <-ticker.C:
-				ts = cm.reload(ts)
-			case <-quit:
-				ticker.Stop()
-				return
-			}
-		}
-	}()
+	// build the mappings and keep them up-to-date.
+	// ctx is cancelled when the config is unloaded, this stops the reloads.
+	onError := func(err error) {
+		cm.logger.Error("error updating the name mapping", zap.Error(err))
+	}
 
-	return cdn.CreateInventoryMappings(context.Background(), config.StorageLocation)
+	strategy, interval := cm.reload()
+	switch strategy {
+	case reloadJournal:
+		return cdn.WatchInventory(ctx, interval, onError)
+	case reloadScan:
+		return scanInventory(ctx, interval, onError)
+	case reloadOff:
+		return cdn.CreateInventoryMappings(ctx)
+	}
+	return fmt.Errorf("unknown reload strategy '%s'", strategy)
 }
 
-func (cm *ContentMapper) reload(ts int64) int64 {
-	if cdn.IsStorageChanged(ts) {
-		err := cdn.CreateInventoryMappings(context.TODO(), config.StorageLocation)
-		if err != nil {
-			cm.logger.Error("error reloading name mapping", zap.Error(err))
-			return ts
+func (cm *ContentMapper) Validate() error {
+	if cm.Reload != nil {
+		switch cm.Reload.Strategy {
+		case reloadJournal, reloadScan, reloadOff:
+		default:
+			return fmt.Errorf("unknown reload strategy '%s'", cm.Reload.Strategy)
 		}
+		if cm.Reload.Interval < 0 {
+			return fmt.Errorf("invalid reload interval")
+		}
+	}
 
-		cm.logger.Info("reloaded the name mapping")
-		return timestamp.Now()
+	switch cm.Redirect {
+	case "", redirectPermanent, redirectTemporary, redirectOff:
+	default:
+		return fmt.Errorf("unknown redirect behaviour '%s'", cm.Redirect)
 	}
-	return ts
+
+	return cm.validate()
 }
 
-func (cm *ContentMapper) Validate() error {
-	return nil
+func (cm *ContentMapper) Cleanup() error {
+	return cm.cleanup()
 }
 
 func (cm *ContentMapper) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	return unmarshalBlock(d, func(d *caddyfile.Dispenser) error {
+		switch d.Val() {
+		case "reload":
+			args := d.RemainingArgs()
+			if len(args) < 1 || len(args) > 2 {
+				return d.ArgErr()
+			}
+			cm.Reload = &ReloadConfig{Strategy: args[0]}
+			if len(args) == 2 {
+				interval, err := caddy.ParseDuration(args[1])
+				if err != nil {
+					return d.Errf("invalid reload interval '%s': %v", args[1], err)
+				}
+				cm.Reload.Interval = caddy.Duration(interval)
+			}
+
+		case "redirect":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			cm.Redirect = d.Val()
+
+		default:
+			return cm.unmarshalSubdirective(d)
+		}
+		return nil
+	})
+}
+
+// reload returns the reload strategy and interval
+func (cm *ContentMapper) reload() (string, time.Duration) {
+	strategy, interval := reloadJournal, defaultReloadInterval
+	if cm.Reload != nil {
+		strategy = cm.Reload.Strategy
+		if cm.Reload.Interval > 0 {
+			interval = time.Duration(cm.Reload.Interval)
+		}
+	}
+	return strategy, interval
+}
+
+// redirectStatus returns the status code of redirects or 0 if feeds are not redirected
+func (cm *ContentMapper) redirectStatus() int {
+	switch cm.Redirect {
+	case redirectTemporary:
+		return http.StatusFound
+	case redirectOff:
+		return 0
+	}
+	return http.StatusMovedPermanently
+}
+
+// scanInventory creates the inventory mappings and then re-creates them every interval until ctx is done
+func scanInventory(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if err := cdn.CreateInventoryMappings(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := cdn.CreateInventoryMappings(ctx); err != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
 	return nil
 }
 
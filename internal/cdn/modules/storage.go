@@ -2,14 +2,16 @@ package modules
 
 import (
 	"net/http"
-	"path/filepath"
+	"path"
 	"strings"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
 	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
-	"github.com/podops/podops/config"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/internal/cdn"
 )
 
 // see https://github.com/caddyserver/cache-handler/blob/master/httpcache.go
@@ -18,7 +20,9 @@ import (
 // see https://github.com/podops/podops.legacy/blob/43c7df67e38056b9a08b4da3f484ec8aecfb994a/internal/cdn/cdn.go
 
 type (
+	// ContentStorage serves the media files of the shows, e.g. /a7c94297acfc/86124f7f9cf.mp3
 	ContentStorage struct {
+		Config
 	}
 )
 
@@ -26,6 +30,7 @@ var (
 	// Interface guards
 	_ caddy.Validator             = (*ContentStorage)(nil)
 	_ caddy.Provisioner           = (*ContentStorage)(nil)
+	_ caddy.CleanerUpper          = (*ContentStorage)(nil)
 	_ caddyhttp.MiddlewareHandler = (*ContentStorage)(nil)
 	_ caddyfile.Unmarshaler       = (*ContentStorage)(nil)
 )
@@ -37,18 +42,23 @@ func init() {
 
 func (cs ContentStorage) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
 
-	// anything else than a GET/HEAD is handled elsewhere
-	switch r.Method {
-	case http.MethodGet:
-	case http.MethodHead:
-	default:
+	// anything else than e.g. a GET/HEAD is handled elsewhere
+	if !cs.serves(r.Method) {
 		return next.ServeHTTP(w, r)
 	}
 
+	// server state is not served at all, i.e. not by e.g. a file_server after this handler either
+	if cdn.IsPrivate(path.Clean(r.URL.Path)[1:]) {
+		return caddyhttp.Error(http.StatusNotFound, nil)
+	}
+
 	uri := r.RequestURI // expected is e.g. /a7c94297acfc/86124f7f9cf.mp3
 	parts := strings.Split(uri[1:], "/")
+	if podops.ValidGUID(parts[0]) && cdn.IsDeleted(parts[0]) {
+		return caddyhttp.Error(http.StatusGone, nil)
+	}
 	if len(parts) == 2 {
-		return serveAndCache(parts[0], filepath.Join(config.StorageLocation, uri), "cdn.content.get", w, r, next)
+		return cs.serveAndCache(parts[0], uri[1:], "cdn.content.get", w, r, next)
 	}
 
 	return next.ServeHTTP(w, r)
@@ -62,15 +72,19 @@ func (ContentStorage) CaddyModule() caddy.ModuleInfo {
 }
 
 func (cs *ContentStorage) Provision(ctx caddy.Context) error {
-	return nil
+	return cs.provision(ctx.Logger(cs))
 }
 
 func (cs *ContentStorage) Validate() error {
-	return nil
+	return cs.validate()
+}
+
+func (cs *ContentStorage) Cleanup() error {
+	return cs.cleanup()
 }
 
 func (cs *ContentStorage) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
-	return nil
+	return unmarshalBlock(d, cs.unmarshalSubdirective)
 }
 
 func parseContentStorageConfig(h httpcaddyfile.Helper) (caddyhttp.MiddlewareHandler, error) {
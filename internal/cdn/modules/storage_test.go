@@ -0,0 +1,75 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops/internal/cdn"
+	"github.com/podops/podops/internal/storage"
+)
+
+func TestContentStoragePrivate(t *testing.T) {
+	root := t.TempDir()
+	s := storage.NewLocal(root)
+	storage.SetDefault(s)
+
+	for _, name := range []string{"master.key", "webhook.key", "notify.yaml", "meta.yaml", "redirects.yaml", "episode.mp3"} {
+		assert.NoError(t, storage.WriteFile(context.TODO(), s, storage.Key(parent, name), []byte("secret")))
+	}
+
+	// like the Caddyfiles, with a file_server for the same root after the handler
+	fileServer := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		http.FileServer(http.Dir(root)).ServeHTTP(w, r)
+		return nil
+	})
+	serve := func(uri string) (int, error) {
+		r := httptest.NewRequest(http.MethodGet, uri, nil)
+		w := httptest.NewRecorder()
+		err := ContentStorage{}.ServeHTTP(w, r, fileServer)
+		return w.Code, err
+	}
+
+	for _, uri := range []string{"/" + parent + "/master.key", "/" + parent + "/webhook.key", "/" + parent + "/notify.yaml", "/" + parent + "/meta.yaml", "/" + parent + "/redirects.yaml", "/" + parent + "/master.key?download=1", "/" + parent + "/sub/../webhook.key"} {
+		_, err := serve(uri)
+		var herr caddyhttp.HandlerError
+		assert.True(t, errors.As(err, &herr), uri)
+		assert.Equal(t, http.StatusNotFound, herr.StatusCode, uri)
+	}
+
+	code, err := serve("/" + parent + "/episode.mp3")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestContentStorageDeleted(t *testing.T) {
+	s := storage.NewLocal(t.TempDir())
+	storage.SetDefault(s)
+
+	removed := "bbb94297acfc"
+	for _, p := range []string{parent, removed} {
+		assert.NoError(t, storage.WriteFile(context.TODO(), s, storage.Key(p, "feed.xml"), []byte(feed)))
+		assert.NoError(t, storage.WriteFile(context.TODO(), s, storage.Key(p, "episode.mp3"), []byte("not really audio")))
+	}
+	assert.NoError(t, cdn.WriteShowMeta(context.TODO(), removed, &cdn.ShowMeta{Deleted: 1}))
+	assert.NoError(t, cdn.CreateInventoryMappings(context.TODO()))
+
+	// the storage is not read again, the inventory knows the show was deleted
+	assert.NoError(t, storage.Default().Delete(context.TODO(), storage.Key(removed, "meta.yaml")))
+
+	r := httptest.NewRequest(http.MethodGet, "/"+removed+"/episode.mp3", nil)
+	err := ContentStorage{}.ServeHTTP(httptest.NewRecorder(), r, notFound)
+	var herr caddyhttp.HandlerError
+	assert.True(t, errors.As(err, &herr))
+	assert.Equal(t, http.StatusGone, herr.StatusCode)
+
+	r = httptest.NewRequest(http.MethodGet, "/"+parent+"/episode.mp3", nil)
+	w := httptest.NewRecorder()
+	assert.NoError(t, ContentStorage{}.ServeHTTP(w, r, notFound))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,47 @@
+package cdn
+
+import (
+	"context"
+
+	"github.com/podops/podops/internal/storage"
+)
+
+type (
+	// Usage summarizes the storage a show consumes on the CDN
+	Usage struct {
+		Bytes int64 `json:"bytes"`
+		Files int64 `json:"files"`
+	}
+)
+
+// StorageUsage calculates the number of files and bytes stored for a show.
+// Server state, e.g. the show's credentials and subscriptions, is not counted, see IsPrivate.
+func StorageUsage(ctx context.Context, parent string) (*Usage, error) {
+	var usage Usage
+
+	objects, err := storage.Default().List(ctx, parent+"/")
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range objects {
+		if IsPrivate(o.Key) {
+			continue
+		}
+		usage.Bytes += o.Size
+		usage.Files++
+	}
+
+	return &usage, nil
+}
+
+// Exceeds verifies if adding a file of the given size would exceed the quota.
+// A limit of 0 means that there is no quota.
+func (u *Usage) Exceeds(size, maxBytes, maxFiles int64) bool {
+	if maxBytes > 0 && u.Bytes+size > maxBytes {
+		return true
+	}
+	if maxFiles > 0 && u.Files+1 > maxFiles {
+		return true
+	}
+	return false
+}
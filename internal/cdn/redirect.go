@@ -0,0 +1,253 @@
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/storage"
+)
+
+type (
+	// Redirect moves the feed of a show permanently. Requests to the feed of From are
+	// answered with a 301 to the feed of To.
+	Redirect struct {
+		From string `json:"from" yaml:"from"` // a show name, e.g. minimalpodcast
+		To   string `json:"to" yaml:"to"`     // a show name or the absolute URL of a feed on another host
+	}
+)
+
+// LoadRedirects reads the redirects of a show. A missing file is not an error.
+func LoadRedirects(ctx context.Context, parent string) ([]Redirect, error) {
+	var redirects []Redirect
+
+	data, err := storage.ReadFile(ctx, storage.Default(), storage.Key(parent, config.DefaultRedirectFileLocation))
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return make([]Redirect, 0), nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &redirects); err != nil {
+		return nil, err
+	}
+	return redirects, nil
+}
+
+// WriteRedirects replaces the redirects of a show. A show can only redirect its own name and aliases.
+func WriteRedirects(ctx context.Context, parent string, redirects []Redirect) error {
+	if err := ValidateRedirects(redirects); err != nil {
+		return err
+	}
+
+	names, err := showNames(ctx, parent)
+	if err != nil {
+		return err
+	}
+	for _, r := range redirects {
+		if !containsName(names, r.From) {
+			return podops.ErrInvalidResourceName
+		}
+	}
+
+	data, err := yaml.Marshal(redirects)
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteFile(ctx, storage.Default(), storage.Key(parent, config.DefaultRedirectFileLocation), data); err != nil {
+		return err
+	}
+
+	return PublishChange(ctx, parent)
+}
+
+// ValidateRedirects verifies that all redirects start at a show name and end at a show name or an absolute URL
+func ValidateRedirects(redirects []Redirect) error {
+	for _, r := range redirects {
+		if !podops.ValidName(r.From) {
+			return podops.ErrInvalidResourceName
+		}
+		if !isURL(r.To) {
+			if !podops.ValidName(r.To) {
+				return podops.ErrInvalidResourceName
+			}
+			if r.To == r.From {
+				return podops.ErrInvalidParameters
+			}
+			continue
+		}
+		u, err := url.Parse(r.To)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return podops.ErrInvalidParameters
+		}
+	}
+	return nil
+}
+
+// AnnounceMove adds the URL podcast apps should move to as itunes:new-feed-url to the show's feed,
+// or removes the one it added before if the show no longer moves. A feed moves if the show's
+// name is redirected or if the show was renamed. A new feed URL from the show itself is kept.
+func AnnounceMove(ctx context.Context, parent string) error {
+	key := storage.Key(parent, config.DefaultFeedName)
+	data, err := storage.ReadFile(ctx, storage.Default(), key)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil // nothing to announce
+		}
+		return err
+	}
+
+	meta, err := LoadShowMeta(ctx, parent)
+	if err != nil {
+		return err
+	}
+	name := meta.Name
+	if name == "" {
+		if name, err = feedName(ctx, parent); err != nil {
+			return err
+		}
+	}
+	redirects, err := LoadRedirects(ctx, parent)
+	if err != nil {
+		return err
+	}
+
+	to := newFeedURL(name, meta.Aliases, redirects)
+	if updated := setNewFeedURL(data, to, meta.NewFeedURL); !bytes.Equal(updated, data) {
+		if err := storage.WriteFile(ctx, storage.Default(), key, updated); err != nil {
+			return err
+		}
+	}
+	if meta.NewFeedURL != to {
+		meta.NewFeedURL = to
+		return WriteShowMeta(ctx, parent, meta)
+	}
+	return nil
+}
+
+// newFeedURL returns where the feed of a show moved to, if it moved
+func newFeedURL(name string, aliases []string, redirects []Redirect) string {
+	for _, r := range redirects {
+		if r.From == name {
+			return RedirectURL(r.To)
+		}
+	}
+	if len(aliases) > 0 {
+		// requests to the previous names are redirected to this feed
+		return RedirectURL(name)
+	}
+	return ""
+}
+
+var newFeedURLElement = regexp.MustCompile(`<itunes:new-feed-url>([^<]*)</itunes:new-feed-url>`)
+
+// setNewFeedURL replaces the feed's itunes:new-feed-url with to. If to is empty, the element is
+// only removed if the server added it, i.e. if it still is announced.
+func setNewFeedURL(feed []byte, to, announced string) []byte {
+	element := []byte("")
+	if to != "" {
+		var buf bytes.Buffer
+		buf.WriteString("<itunes:new-feed-url>")
+		xml.EscapeText(&buf, []byte(to))
+		buf.WriteString("</itunes:new-feed-url>")
+		element = buf.Bytes()
+	}
+
+	if m := newFeedURLElement.FindSubmatchIndex(feed); m != nil {
+		if to == "" && html.UnescapeString(string(feed[m[2]:m[3]])) != announced {
+			return feed // the show's own new feed URL
+		}
+		return append(append(append([]byte{}, feed[:m[0]]...), element...), feed[m[1]:]...)
+	}
+	if to == "" {
+		return feed
+	}
+
+	i := bytes.Index(feed, []byte("<channel>"))
+	if i < 0 || !bytes.Contains(feed[:i], []byte("xmlns:itunes=")) {
+		return feed
+	}
+	i += len("<channel>")
+	return append(append(append([]byte{}, feed[:i]...), element...), feed[i:]...)
+}
+
+// isURL tells an absolute URL apart from a show name
+func isURL(to string) bool {
+	return strings.Contains(to, "://")
+}
+
+// LookupRedirect returns the location a request path is permanently moved to, if any
+func LookupRedirect(path string) (string, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if to, ok := redirectMapping[path]; ok {
+		return to, true
+	}
+	return "", false
+}
+
+// RedirectURL returns the absolute URL of a redirect's target. A show name is resolved to its feed on the CDN.
+func RedirectURL(to string) string {
+	if isURL(to) {
+		return to
+	}
+	return fmt.Sprintf("%s%s", config.Settings().GetOption(config.PodopsContentEndpointEnv), feedPath(to))
+}
+
+// feedPath returns the canonical path of a show's feed on the CDN
+func feedPath(name string) string {
+	return fmt.Sprintf("/%s/%s", name, config.DefaultFeedName)
+}
+
+// showNames returns the current name and the aliases of a show
+func showNames(ctx context.Context, parent string) ([]string, error) {
+	meta, err := LoadShowMeta(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	names := append([]string{}, meta.Aliases...)
+	if meta.Name != "" {
+		return append(names, meta.Name), nil
+	}
+	if name, err := feedName(ctx, parent); err == nil {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// mapRedirects returns the renames and redirects of a show, e.g. /oldname/feed.xml -> /minimalpodcast/feed.xml.
+// Redirects of names that are neither the show's name nor one of its aliases are ignored.
+func mapRedirects(name string, aliases []string, redirects []Redirect) map[string]string {
+	paths := make(map[string]string)
+	add := func(from, to string) {
+		paths["/"+from+"/feed"] = to
+		paths["/"+from+"/feed.xml"] = to
+	}
+
+	// previous names of a show point to its current name
+	for _, alias := range aliases {
+		add(alias, feedPath(name))
+	}
+
+	for _, r := range redirects {
+		if r.From != name && !containsName(aliases, r.From) {
+			continue
+		}
+		to := r.To
+		if !isURL(to) {
+			to = feedPath(to)
+		}
+		add(r.From, to)
+	}
+	return paths
+}
@@ -0,0 +1,87 @@
+package cdn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/internal/storage"
+)
+
+func TestValidateRedirects(t *testing.T) {
+	assert.NoError(t, ValidateRedirects([]Redirect{{From: "oldpodcast", To: "newpodcast"}}))
+	assert.NoError(t, ValidateRedirects([]Redirect{{From: "oldpodcast", To: "https://example.com/feed.xml"}}))
+
+	assert.Equal(t, podops.ErrInvalidResourceName, ValidateRedirects([]Redirect{{From: "Not A Name", To: "newpodcast"}}))
+	assert.Equal(t, podops.ErrInvalidParameters, ValidateRedirects([]Redirect{{From: "oldpodcast", To: "oldpodcast"}}))
+	assert.Equal(t, podops.ErrInvalidParameters, ValidateRedirects([]Redirect{{From: "oldpodcast", To: "ftp://example.com/feed.xml"}}))
+}
+
+func TestLookupRedirect(t *testing.T) {
+	mu.Lock()
+	redirectMapping = mapRedirects("newpodcast", []string{"oldpodcast"}, []Redirect{{From: "newpodcast", To: "https://example.com/feed.xml"}, {From: "unclaimedpodcast", To: "https://example.com/phishing.xml"}})
+	mu.Unlock()
+
+	to, ok := LookupRedirect("/oldpodcast/feed")
+	assert.True(t, ok)
+	assert.Equal(t, "/newpodcast/feed.xml", to)
+
+	to, ok = LookupRedirect("/newpodcast/feed.xml")
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/feed.xml", to)
+
+	_, ok = LookupRedirect("/unknown/feed.xml")
+	assert.False(t, ok)
+
+	// names that are not the show's own are not redirected
+	_, ok = LookupRedirect("/unclaimedpodcast/feed.xml")
+	assert.False(t, ok)
+}
+
+func TestSetNewFeedURL(t *testing.T) {
+	feed := []byte(`<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel><title>Minimal</title></channel></rss>`)
+	moved := setNewFeedURL(feed, "https://example.com/feed.xml?a=1&b=2", "")
+	assert.Equal(t, `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel><itunes:new-feed-url>https://example.com/feed.xml?a=1&amp;b=2</itunes:new-feed-url><title>Minimal</title></channel></rss>`, string(moved))
+
+	// only what the server announced is removed
+	assert.Equal(t, string(feed), string(setNewFeedURL(moved, "", "https://example.com/feed.xml?a=1&b=2")))
+	assert.Equal(t, string(moved), string(setNewFeedURL(moved, "", "https://example.com/other.xml")))
+
+	// without the itunes namespace, nothing is announced
+	plain := []byte(`<rss version="2.0"><channel><title>Minimal</title></channel></rss>`)
+	assert.Equal(t, string(plain), string(setNewFeedURL(plain, "https://example.com/feed.xml", "")))
+}
+
+func TestAnnounceMove(t *testing.T) {
+	storage.SetDefault(storage.NewLocal(t.TempDir()))
+
+	parent := "aaa94297acfc"
+	key := storage.Key(parent, "feed.xml")
+	feed := `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel><title>Minimal</title><link>https://podops.dev/minimalpodcast</link></channel></rss>`
+	assert.NoError(t, storage.WriteFile(context.TODO(), storage.Default(), key, []byte(feed)))
+	read := func() string {
+		data, err := storage.ReadFile(context.TODO(), storage.Default(), key)
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	// a feed that did not move is left alone
+	assert.NoError(t, AnnounceMove(context.TODO(), parent))
+	assert.Equal(t, feed, read())
+
+	// the show's name is redirected
+	assert.NoError(t, WriteRedirects(context.TODO(), parent, []Redirect{{From: "minimalpodcast", To: "https://example.com/feed.xml"}}))
+	assert.Contains(t, read(), "<itunes:new-feed-url>https://example.com/feed.xml</itunes:new-feed-url>")
+
+	assert.NoError(t, WriteRedirects(context.TODO(), parent, nil))
+	assert.Equal(t, feed, read())
+
+	// a show can not redirect names of other shows or unclaimed names
+	assert.Equal(t, podops.ErrInvalidResourceName, WriteRedirects(context.TODO(), parent, []Redirect{{From: "unclaimedpodcast", To: "https://example.com/feed.xml"}}))
+
+	// the show was renamed
+	assert.NoError(t, RenameShow(context.TODO(), parent, "renamedpodcast"))
+	assert.Contains(t, read(), "<itunes:new-feed-url>"+RedirectURL("renamedpodcast")+"</itunes:new-feed-url>")
+}
@@ -0,0 +1,322 @@
+package cdn
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"gopkg.in/yaml.v3"
+
+	"github.com/txsvc/stdlib/v2/settings"
+	"github.com/txsvc/stdlib/v2/timestamp"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/auth"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal"
+	"github.com/podops/podops/internal/storage"
+)
+
+type (
+	// ShowMeta is the server-side state of a show that is not part of its feed.
+	// It is kept in meta.yaml next to the show's feed.
+	ShowMeta struct {
+		Name     string   `json:"name,omitempty" yaml:"name,omitempty"`         // canonical name, overrides the name in the feed
+		Reserved int64    `json:"reserved,omitempty" yaml:"reserved,omitempty"` // time the name was reserved, the earliest reservation wins
+		Aliases  []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`   // previous canonical names
+		Deleted  int64    `json:"deleted,omitempty" yaml:"deleted,omitempty"`   // time of the soft-delete, 0 if not deleted
+		// NewFeedURL is the itunes:new-feed-url the server added to the feed, see AnnounceMove
+		NewFeedURL string `json:"newFeedURL,omitempty" yaml:"newFeedURL,omitempty"`
+	}
+
+	// ShowInfo describes a show on the CDN
+	ShowInfo struct {
+		GUID    string   `json:"guid"`
+		Name    string   `json:"name"`
+		Owner   string   `json:"owner"`
+		Aliases []string `json:"aliases,omitempty"`
+		Deleted int64    `json:"deleted,omitempty"`
+		Bytes   int64    `json:"bytes"`
+		Files   int64    `json:"files"`
+	}
+)
+
+// LoadShowMeta reads the meta data of a show. A missing file is not an error.
+func LoadShowMeta(ctx context.Context, parent string) (*ShowMeta, error) {
+	var meta ShowMeta
+
+	data, err := storage.ReadFile(ctx, storage.Default(), storage.Key(parent, config.DefaultShowMetaFileLocation))
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return &meta, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// WriteShowMeta writes the meta data of a show
+func WriteShowMeta(ctx context.Context, parent string, meta *ShowMeta) error {
+	data, err := yaml.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(ctx, storage.Default(), storage.Key(parent, config.DefaultShowMetaFileLocation), data)
+}
+
+// ReadCredentials reads the master credentials of a show
+func ReadCredentials(ctx context.Context, parent string) (*settings.DialSettings, error) {
+	var cfg settings.DialSettings
+
+	data, err := storage.ReadFile(ctx, storage.Default(), storage.Key(parent, config.DefaultMasterKeyFileLocation))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// WriteCredentials writes the master credentials of a show
+func WriteCredentials(ctx context.Context, parent string, cfg *settings.DialSettings) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(ctx, storage.Default(), storage.Key(parent, config.DefaultMasterKeyFileLocation), data)
+}
+
+// IsDeleted returns true if the show was soft-deleted. Processes with inventory mappings,
+// e.g. the cdn, know it from memory, all others read the show's meta data.
+func IsDeleted(parent string) bool {
+	mu.Lock()
+	local, found := indexed, deleted[parent]
+	mu.Unlock()
+
+	if local {
+		return found
+	}
+
+	meta, err := LoadShowMeta(context.Background(), parent)
+	return err == nil && meta.Deleted > 0
+}
+
+// LookupShow returns the description of a show
+func LookupShow(ctx context.Context, parent string) (*ShowInfo, error) {
+	cfg, err := ReadCredentials(ctx, parent)
+	if err != nil {
+		return nil, podops.ErrResourceNotFound
+	}
+	meta, err := LoadShowMeta(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := StorageUsage(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	info := ShowInfo{
+		GUID:    parent,
+		Name:    meta.Name,
+		Owner:   cfg.Credentials.UserID,
+		Aliases: meta.Aliases,
+		Deleted: meta.Deleted,
+		Bytes:   usage.Bytes,
+		Files:   usage.Files,
+	}
+	if info.Name == "" {
+		info.Name, _ = feedName(ctx, parent)
+	}
+	return &info, nil
+}
+
+// ListShows returns all shows, ordered by GUID. If owner is not empty, only the owner's shows are returned.
+func ListShows(ctx context.Context, owner string) ([]ShowInfo, error) {
+	parents, err := listParents(ctx, config.DefaultMasterKeyFileLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	shows := make([]ShowInfo, 0)
+	for _, parent := range parents {
+		info, err := LookupShow(ctx, parent)
+		if err != nil {
+			continue // not a show, e.g. unreadable master.key
+		}
+		if owner != "" && info.Owner != owner {
+			continue
+		}
+		shows = append(shows, *info)
+	}
+
+	sort.Slice(shows, func(i, j int) bool { return shows[i].GUID < shows[j].GUID })
+	return shows, nil
+}
+
+// DeleteShow soft-deletes a show. It is no longer served and removed
+// for good after config.DeleteRetention days, unless it is restored before.
+func DeleteShow(ctx context.Context, parent string) error {
+	meta, err := LoadShowMeta(ctx, parent)
+	if err != nil {
+		return err
+	}
+	if meta.Deleted == 0 {
+		meta.Deleted = timestamp.Now()
+	}
+	if err := WriteShowMeta(ctx, parent, meta); err != nil {
+		return err
+	}
+
+	return PublishChange(ctx, parent)
+}
+
+// RestoreShow reverts the soft-delete of a show
+func RestoreShow(ctx context.Context, parent string) error {
+	meta, err := LoadShowMeta(ctx, parent)
+	if err != nil {
+		return err
+	}
+	meta.Deleted = 0
+	if err := WriteShowMeta(ctx, parent, meta); err != nil {
+		return err
+	}
+
+	return PublishChange(ctx, parent)
+}
+
+// ExpireDeletedShows removes all shows that were deleted more than retention ago
+func ExpireDeletedShows(ctx context.Context, retention time.Duration) error {
+	shows, err := ListShows(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-retention).Unix()
+	for _, s := range shows {
+		if s.Deleted > 0 && s.Deleted < cutoff {
+			if cfg, err := ReadCredentials(ctx, s.GUID); err == nil {
+				auth.UnregisterAuthorization(cfg)
+			}
+			if err := storage.DeleteAll(ctx, storage.Default(), s.GUID+"/"); err != nil {
+				return err
+			}
+			if err := PublishChange(ctx, s.GUID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TransferShow makes userid the owner of a show. The show gets a new
+// master token, the old one is revoked.
+func TransferShow(ctx context.Context, parent, userid string) (*settings.DialSettings, error) {
+	cfg, err := ReadCredentials(ctx, parent)
+	if err != nil {
+		return nil, podops.ErrResourceNotFound
+	}
+	auth.UnregisterAuthorization(cfg)
+
+	cfg.Credentials.UserID = userid
+	cfg.Credentials.Token = internal.CreateSimpleToken()
+	if err := WriteCredentials(ctx, parent, cfg); err != nil {
+		return nil, err
+	}
+
+	auth.RegisterAuthorization(cfg)
+	return cfg, nil
+}
+
+// RenameShow changes the canonical name of a show. The previous name
+// is kept as an alias, requests to it are redirected to the new name.
+func RenameShow(ctx context.Context, parent, name string) error {
+	if !podops.ValidName(name) {
+		return podops.ErrInvalidResourceName
+	}
+
+	if guid, ok := ResolveName(name); ok && guid != parent {
+		return podops.ErrNameExists
+	}
+
+	meta, err := LoadShowMeta(ctx, parent)
+	if err != nil {
+		return err
+	}
+
+	current := meta.Name
+	if current == "" {
+		current, _ = feedName(ctx, parent)
+	}
+	if current == name {
+		return nil
+	}
+	if current != "" {
+		meta.Aliases = appendName(meta.Aliases, current)
+	}
+	meta.Aliases = removeName(meta.Aliases, name)
+	meta.Name = name
+	meta.Reserved = timestamp.Now()
+
+	if err := WriteShowMeta(ctx, parent, meta); err != nil {
+		return err
+	}
+
+	return PublishChange(ctx, parent)
+}
+
+// feedName returns the show's name as found in the feed's link, e.g. https://podops.dev/minimalpodcast -> minimalpodcast
+func feedName(ctx context.Context, parent string) (string, error) {
+	r, err := storage.Default().Get(ctx, storage.Key(parent, config.DefaultFeedName))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	feed, err := gofeed.NewParser().Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	link, err := url.Parse(feed.Link)
+	if err != nil {
+		return "", err
+	}
+	if len(link.Path) < 2 {
+		return "", podops.ErrInvalidResourceName
+	}
+	return link.Path[1:], nil
+}
+
+func appendName(names []string, name string) []string {
+	if containsName(names, name) {
+		return names
+	}
+	return append(names, name)
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func removeName(names []string, name string) []string {
+	result := make([]string, 0, len(names))
+	for _, n := range names {
+		if n != name {
+			result = append(result, n)
+		}
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package cdn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/txsvc/stdlib/v2/settings"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/internal/storage"
+)
+
+func createTestShow(t *testing.T, parent, userid string) {
+	cfg := settings.DialSettings{
+		Credentials: &settings.Credentials{
+			ProjectID: parent,
+			UserID:    userid,
+			Token:     "token-" + parent,
+		},
+	}
+	assert.NoError(t, WriteCredentials(context.TODO(), parent, &cfg))
+}
+
+func TestShowLifecycle(t *testing.T) {
+	storage.SetDefault(storage.NewLocal(t.TempDir()))
+
+	parent := "aaa94297acfc"
+	createTestShow(t, parent, "alice")
+	createTestShow(t, "bbb94297acfc", "bob")
+
+	shows, err := ListShows(context.TODO(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(shows))
+
+	shows, err = ListShows(context.TODO(), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(shows))
+	assert.Equal(t, parent, shows[0].GUID)
+
+	// rename
+	assert.Equal(t, podops.ErrInvalidResourceName, RenameShow(context.TODO(), parent, "Not A Name"))
+	assert.NoError(t, RenameShow(context.TODO(), parent, "firstpodcast"))
+	assert.NoError(t, RenameShow(context.TODO(), parent, "secondpodcast"))
+
+	meta, err := LoadShowMeta(context.TODO(), parent)
+	assert.NoError(t, err)
+	assert.Equal(t, "secondpodcast", meta.Name)
+	assert.Equal(t, []string{"firstpodcast"}, meta.Aliases)
+
+	// transfer
+	cfg, err := TransferShow(context.TODO(), parent, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", cfg.Credentials.UserID)
+	assert.NotEqual(t, "token-aaa94297acfc", cfg.Credentials.Token)
+
+	// delete, restore and expire
+	assert.NoError(t, DeleteShow(context.TODO(), parent))
+	assert.NoError(t, ExpireDeletedShows(context.TODO(), time.Hour))
+	assert.True(t, ShowExists(context.TODO(), parent))
+
+	assert.NoError(t, RestoreShow(context.TODO(), parent))
+	meta, _ = LoadShowMeta(context.TODO(), parent)
+	assert.Equal(t, int64(0), meta.Deleted)
+
+	assert.NoError(t, DeleteShow(context.TODO(), parent))
+	assert.NoError(t, ExpireDeletedShows(context.TODO(), -time.Hour))
+	assert.False(t, ShowExists(context.TODO(), parent))
+	assert.True(t, ShowExists(context.TODO(), "bbb94297acfc"))
+}
+
+func TestStorageUsage(t *testing.T) {
+	storage.SetDefault(storage.NewLocal(t.TempDir()))
+
+	parent := "aaa94297acfc"
+	createTestShow(t, parent, "producer")
+	for _, name := range []string{"webhook.key", "notify.yaml", "meta.yaml", "redirects.yaml"} {
+		assert.NoError(t, storage.WriteFile(context.TODO(), storage.Default(), storage.Key(parent, name), []byte("server state")))
+	}
+	assert.NoError(t, storage.WriteFile(context.TODO(), storage.Default(), storage.Key(parent, "feed.xml"), []byte("feed")))
+	assert.NoError(t, storage.WriteFile(context.TODO(), storage.Default(), storage.Key(parent, "episode.mp3"), []byte("episode")))
+
+	usage, err := StorageUsage(context.TODO(), parent)
+	assert.NoError(t, err)
+	assert.Equal(t, &Usage{Bytes: int64(len("feed") + len("episode")), Files: 2}, usage)
+}
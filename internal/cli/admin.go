@@ -45,6 +45,39 @@ func InitCommand(c *cli.Context) error {
 	return register(config.Settings().Credentials.UserID, root)
 }
 
+// WebhookCommand creates a new webhook secret for the podcast
+func WebhookCommand(c *cli.Context) error {
+
+	if c.NArg() > 1 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	root, err := ResolveRootDirectory(c)
+	if err != nil {
+		return err
+	}
+
+	// reload the local credentials
+	config.UpdateClientSettings(filepath.Join(root, config.DefaultConfigFileLocation))
+
+	showPath := loader.ShowPath(root)
+	_, kind, parent, err := loader.ReadResource(context.TODO(), showPath)
+	if err != nil {
+		return err
+	}
+	if kind != podops.ResourceShow {
+		return podops.ErrBuildNoShow
+	}
+
+	secret, err := client.New(config.Settings()).RotateWebhookSecret(context.TODO(), parent)
+	if err != nil {
+		return err
+	}
+
+	printMsg(podops.MsgSecret, parent, secret)
+	return nil
+}
+
 // Create a default config for the CDN and API services
 func ConfigCommand(c *cli.Context) error {
 
@@ -95,9 +128,9 @@ func ConfigCommand(c *cli.Context) error {
 
 func register(userID, root string) error {
 	// try to read the parent GUID from the show.yaml
-	showPath := filepath.Join(root, config.DefaultShowName)
+	showPath := loader.ShowPath(root)
 
-	_, kind, parent, err := loader.ReadResource(context.TODO(), showPath)
+	r, kind, parent, err := loader.ReadResource(context.TODO(), showPath)
 	if err != nil {
 		return err
 	}
@@ -105,8 +138,8 @@ func register(userID, root string) error {
 		return podops.ErrBuildNoShow
 	}
 
-	// try to register the repo
-	cfg, err := client.Init(userID, parent)
+	// try to register the repo and reserve the show's name
+	cfg, err := client.New(config.Settings()).Init(context.TODO(), userID, parent, r.(*podops.Show).Metadata.Name)
 	if err != nil {
 		return err
 	}
@@ -3,6 +3,7 @@ package cli
 import (
 	"context"
 	"os"
+	"os/signal"
 	"path/filepath"
 
 	"github.com/urfave/cli/v2"
@@ -66,6 +67,7 @@ func AssembleCommand(c *cli.Context) error {
 	return nil
 }
 
+// SyncCommand uploads the assembled resources to the CDN
 func SyncCommand(c *cli.Context) error {
 
 	if c.NArg() > 1 {
@@ -82,13 +84,18 @@ func SyncCommand(c *cli.Context) error {
 		}
 		root = dir
 	}
-	purge := boolFlag(c, "purge") // --purge
+	purge := boolFlag(c, "purge")       // --purge
+	force := boolFlag(c, "force")       // --force
+	dryRun := boolFlag(c, "dry-run")    // --dry-run
+	asJSON := boolFlag(c, "json")       // --json
+	quiet := boolFlag(c, "quiet")       // --quiet
+	concurrency := c.Int("concurrency") // --concurrency
 
 	// reload the local credentials
 	config.UpdateClientSettings(filepath.Join(root, config.DefaultConfigFileLocation))
 
 	// find and load the show.yaml
-	showPath := filepath.Join(root, config.DefaultShowName)
+	showPath := loader.ShowPath(root)
 	_, kind, parent, err := loader.ReadResource(context.TODO(), showPath)
 	if err != nil {
 		return err
@@ -100,10 +107,110 @@ func SyncCommand(c *cli.Context) error {
 		return podops.ErrInvalidGUID
 	}
 
-	if err := client.Sync(parent, root, purge); err != nil {
+	// cancel the sync on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cl := client.New(config.Settings())
+
+	plan, err := cl.Plan(ctx, parent, root, purge)
+	if err != nil {
+		return err
+	}
+	if !quiet || dryRun {
+		if err := printPlan(plan, asJSON); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		return nil
+	}
+	if !force {
+		if err := confirmPurge(plan); err != nil {
+			return err
+		}
+	}
+
+	var bar *progressBar
+	if !quiet {
+		if bar = newProgressBar(); bar != nil {
+			cl.Progress = bar.Update
+		}
+	}
+
+	err = cl.Apply(ctx, plan, concurrency)
+	if bar != nil {
+		bar.Done()
+	}
+	if err != nil {
 		return err
 	}
 
 	printMsg(podops.MsgSyncSuccess)
 	return nil
 }
+
+// PullCommand downloads a show from the CDN into the local build location
+func PullCommand(c *cli.Context) error {
+
+	if c.NArg() > 1 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	root, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	archive := c.String("archive") // --archive
+	quiet := boolFlag(c, "quiet")  // --quiet
+
+	// reload the local credentials
+	config.UpdateClientSettings(filepath.Join(root, config.DefaultConfigFileLocation))
+
+	// the show's GUID is either given or taken from the local show.yaml
+	parent := c.Args().First()
+	if parent == "" {
+		_, kind, guid, err := loader.ReadResource(context.TODO(), loader.ShowPath(root))
+		if err != nil {
+			return err
+		}
+		if kind != podops.ResourceShow {
+			return podops.ErrBuildNoShow
+		}
+		parent = guid
+	}
+	if !podops.ValidGUID(parent) {
+		return podops.ErrInvalidGUID
+	}
+
+	// cancel the pull on Ctrl-C, a new pull resumes where this one stopped
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var bar *progressBar
+	cl := client.New(config.Settings())
+	if !quiet {
+		if bar = newProgressBar(); bar != nil {
+			cl.Progress = bar.Update
+		}
+	}
+
+	if archive != "" {
+		err = cl.Export(ctx, parent, archive)
+	} else {
+		err = cl.Pull(ctx, parent, root)
+	}
+	if bar != nil {
+		bar.Done()
+	}
+	if err != nil {
+		return err
+	}
+
+	if archive != "" {
+		printMsg(podops.MsgExportSuccess, parent, archive)
+	} else {
+		printMsg(podops.MsgPullSuccess, parent)
+	}
+	return nil
+}
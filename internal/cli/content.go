@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 	"github.com/podops/podops"
 	"github.com/podops/podops/config"
 	"github.com/podops/podops/internal"
+	"github.com/podops/podops/internal/loader"
 )
 
 const (
@@ -47,7 +49,7 @@ func NewCommand(c *cli.Context) error {
 	}
 
 	// setup the default show.yaml
-	showFilePath := filepath.Join(root, config.DefaultShowName)
+	showFilePath := loader.ShowPath(root)
 	if _, err := os.Stat(showFilePath); os.IsNotExist(err) {
 		guid := internal.CreateRandomAssetGUID()
 		show := podops.DefaultShow("NAME", "TITLE", "SUMMARY", guid, config.Settings().GetOption(config.PodopsServiceEndpointEnv), config.Settings().GetOption(config.PodopsContentEndpointEnv))
@@ -77,10 +79,20 @@ func TemplateCommand(c *cli.Context) error {
 		guid = internal.CreateRandomAssetGUID()
 	}
 	parentGUID := c.String("parent")
+	parentName := "PARENT-NAME"
+
+	// episodes belong to the show in the current directory, if there is one
+	var show *podops.Show
+	if r, kind, _, err := loader.ReadResource(context.TODO(), loader.ShowPath(".")); err == nil && kind == podops.ResourceShow {
+		show = r.(*podops.Show)
+		parentName = show.Metadata.Name
+		if parentGUID == "" {
+			parentGUID = show.GUID()
+		}
+	}
 	if parentGUID == "" {
 		parentGUID = "PARENT-GUID"
 	}
-	parentName := "PARENT-NAME"
 
 	name := strings.ToLower(fmt.Sprintf("%s-%s", template, guid))
 	if c.NArg() == 2 {
@@ -99,8 +111,16 @@ func TemplateCommand(c *cli.Context) error {
 			return err
 		}
 	} else if template == podops.ResourceEpisode {
+		defaults, err := loader.ReadDefaults(context.TODO(), ".", show)
+		if err != nil {
+			return err
+		}
+
+		// attributes the show's defaults cover are left out, the episode gets them when it is built
 		episode := podops.DefaultEpisode(name, parentName, guid, parentGUID, config.Settings().GetOption(config.PodopsServiceEndpointEnv), config.Settings().GetOption(config.PodopsContentEndpointEnv))
-		err := dumpResource(fmt.Sprintf("episode-%s.yaml", guid), episode)
+		loader.OmitDefaults(episode, defaults)
+
+		err = dumpResource(fmt.Sprintf("episode-%s.yaml", guid), episode)
 		if err != nil {
 			return err
 		}
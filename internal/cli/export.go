@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/builder"
+)
+
+// ExportCommand converts a podcast repository into a feed, manifests and media files other platforms can import
+func ExportCommand(c *cli.Context) error {
+
+	if c.NArg() > 1 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	root, err := ResolveRootDirectory(c)
+	if err != nil {
+		return err
+	}
+
+	output := c.String("output")    // --output
+	baseURL := c.String("base-url") // --base-url
+	asJSON := boolFlag(c, "json")   // --json
+	if output == "" {
+		output = config.DefaultExportName
+	}
+
+	manifest, err := builder.Export(context.TODO(), root, output, baseURL)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(manifest)
+	}
+	for _, uri := range manifest.Missing {
+		printMsg(podops.MsgExportMissing, uri)
+	}
+	printMsg(podops.MsgExportSummary, len(manifest.Episodes), formatBytes(manifest.Bytes))
+	printMsg(podops.MsgExportSuccess, manifest.Name, output)
+	return nil
+}
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 
@@ -10,18 +12,25 @@ import (
 	"github.com/podops/podops/internal/importer"
 )
 
-// Initialize a new podcast project and repository
+// ImportCommand creates a podcast repository from a feed, a local feed file or, with --opml, from all feeds in an OPML file
 func ImportCommand(c *cli.Context) error {
 
-	if c.NArg() < 1 {
+	update := boolFlag(c, "update") // --update
+	opml := c.String("opml")        // --opml
+	if c.NArg() < 1 && !update && opml == "" {
 		return podops.ErrInvalidNumArguments
 	}
 
 	// collect all the parameters
 	feedUrl := c.Args().First()
 	output := c.String("output")
-	//rewrite := boolFlag(c, "rewrite")        // --rewrite
+	rewrite := boolFlag(c, "rewrite")        // --rewrite
 	singleFile := boolFlag(c, "single-file") // --single-file
+	asJSON := boolFlag(c, "json")            // --json
+	strict := boolFlag(c, "strict")          // --strict
+	if update && (singleFile || strict || opml != "" || c.NArg() > 1) {
+		return podops.ErrInvalidParameters
+	}
 
 	// get the working directory
 	root := "."
@@ -38,34 +47,150 @@ func ImportCommand(c *cli.Context) error {
 		return podops.ErrInvalidParameters
 	}
 
-	show, err := importer.ImportPodcastFeed(feedUrl)
+	if update {
+		return updateImport(feedUrl, root, rewrite)
+	}
+
+	// several feeds are imported into one repository each
+	if opml != "" || c.NArg() > 1 {
+		sources := c.Args().Slice()
+		if opml != "" {
+			feeds, err := importer.ReadOPMLFile(opml)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, feeds...)
+		}
+		return importBatch(sources, root, singleFile, rewrite, strict, asJSON)
+	}
+
+	show, report, err := importer.ImportPodcast(feedUrl, strict)
+	if err != nil {
+		if report != nil {
+			// the feed was rejected in strict mode, show why
+			if asJSON {
+				printJSON(&importer.BatchResult{Source: feedUrl, Report: report, Error: err.Error()})
+			} else {
+				printImportWarnings(feedUrl, report)
+			}
+		}
+		return err
+	}
+	media, err := importer.WriteRepository(context.TODO(), root, feedUrl, show, singleFile, rewrite)
 	if err != nil {
 		return err
 	}
 
-	if singleFile {
-		err := dumpResource(fmt.Sprintf("%s/show.yaml", root), show)
-		if err != nil {
+	if asJSON {
+		result := importer.BatchResult{
+			Source:   feedUrl,
+			Name:     show.Metadata.Name,
+			Location: root,
+			Episodes: len(show.Episodes),
+			Warnings: report.Warnings(),
+			Report:   report,
+			Media:    media,
+		}
+		if err := printJSON(&result); err != nil {
 			return err
 		}
 	} else {
-		for _, e := range show.Episodes {
-			season := e.SeasonAsInt()
-			episode := e.EpisodeAsInt()
-			err := dumpResource(fmt.Sprintf("%s/episode-S%dE%d.yaml", root, season, episode), e)
-			if err != nil {
-				return err
-			}
+		printImportWarnings(feedUrl, report)
+		if media != nil {
+			printMigrationReport(media)
 		}
+		printMsg(podops.MsgImportSuccess, show.Metadata.Name, len(show.Episodes), root)
+	}
 
-		// unlink the episodes, we already dumped them
-		show.Episodes = nil
+	if media != nil && media.Failed() > 0 {
+		return podops.ErrImportFailed
+	}
+	return nil
+}
 
-		err := dumpResource(fmt.Sprintf("%s/show.yaml", root), show)
-		if err != nil {
+// printJSON prints a report for scripts
+func printJSON(report interface{}) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printImportWarnings lists everything in the feed that could not be imported as is
+func printImportWarnings(feedUrl string, report *importer.ImportReport) {
+	for _, w := range report.Warnings() {
+		printMsg(podops.MsgImportWarning, feedUrl, w)
+	}
+}
+
+// printMigrationReport lists the imported media files
+func printMigrationReport(report *importer.MigrationReport) {
+	for _, a := range report.Assets {
+		if a.Error != "" {
+			printMsg(podops.MsgImportFailed, a.URI, a.Error)
+			continue
+		}
+		printMsg(podops.MsgImportMediaFile, a.URI, formatBytes(a.Size))
+	}
+	printMsg(podops.MsgImportMedia, len(report.Assets)-report.Failed(), len(report.Assets), formatBytes(report.Bytes()), report.Failed())
+}
+
+// importBatch imports several feeds and reports the outcome of each
+func importBatch(sources []string, root string, singleFile, rewrite, strict, asJSON bool) error {
+	report := importer.ImportBatch(context.TODO(), sources, root, singleFile, rewrite, strict)
+
+	if asJSON {
+		if err := printJSON(report); err != nil {
 			return err
 		}
+	} else {
+		for _, r := range report.Results {
+			if r.Error != "" {
+				printMsg(podops.MsgImportFailed, r.Source, r.Error)
+				continue
+			}
+			for _, w := range r.Warnings {
+				printMsg(podops.MsgImportWarning, r.Source, w)
+			}
+			printMsg(podops.MsgImportSuccess, r.Name, r.Episodes, r.Location)
+		}
+		printMsg(podops.MsgImportBatch, len(sources)-report.Failed(), len(sources), report.WithWarnings(), report.Failed())
 	}
 
+	if report.Failed() > 0 {
+		return podops.ErrImportFailed
+	}
+	return nil
+}
+
+// updateImport re-imports the feed into the repository at root and reports the changes
+func updateImport(feedUrl, root string, rewrite bool) error {
+	result, err := importer.UpdateRepository(context.TODO(), feedUrl, root, rewrite)
+	if err != nil {
+		return err
+	}
+
+	if result.Show {
+		printMsg(podops.MsgImportUpdated, "show.yaml")
+	}
+	for _, path := range result.Added {
+		printMsg(podops.MsgImportAdded, path)
+	}
+	for _, path := range result.Updated {
+		printMsg(podops.MsgImportUpdated, path)
+	}
+	for _, path := range result.Removed {
+		printMsg(podops.MsgImportRemoved, path)
+	}
+	if result.Media != nil {
+		printMigrationReport(result.Media)
+	}
+	printMsg(podops.MsgImportSummary, len(result.Added), len(result.Updated), result.Unchanged)
+
+	if result.Media != nil && result.Media.Failed() > 0 {
+		return podops.ErrImportFailed
+	}
 	return nil
 }
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/txsvc/stdlib/v2/validate"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/internal/loader"
+)
+
+const (
+	lintFormatText  = "text"
+	lintFormatJSON  = "json"
+	lintFormatSARIF = "sarif"
+	lintFormatJUnit = "junit"
+)
+
+// LintCommand checks all resources against their schema and validates them
+func LintCommand(c *cli.Context) error {
+
+	if c.NArg() > 1 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	// print the schema instead, e.g. for editors
+	if kind := c.String("schema"); kind != "" {
+		schema, err := loader.Schema(kind)
+		if err != nil {
+			return err
+		}
+		return printJSON(schema)
+	}
+
+	root, err := ResolveRootDirectory(c)
+	if err != nil {
+		return err
+	}
+
+	format := c.String("format") // --format
+	output := c.String("output") // --output
+	if format == "" {
+		format = lintFormatText
+	}
+	if !validate.IsMemberOf(format, lintFormatText, lintFormatJSON, lintFormatSARIF, lintFormatJUnit) {
+		return podops.ErrInvalidParameters
+	}
+
+	report, err := loader.Lint(context.TODO(), root)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch format {
+	case lintFormatJSON:
+		data, err = json.MarshalIndent(report, "", "  ")
+	case lintFormatSARIF:
+		data, err = report.SARIF()
+	case lintFormatJUnit:
+		data, err = report.JUnit()
+	default:
+		for _, issue := range report.Issues {
+			data = append(data, fmt.Sprintf("%s: %s\n", issue.Level, issue)...)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if output != "" {
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return err
+		}
+	} else if len(data) > 0 {
+		fmt.Print(string(data))
+		if format != lintFormatText {
+			fmt.Println()
+		}
+	}
+
+	if format == lintFormatText || output != "" {
+		printMsg(podops.MsgLintSummary, len(report.Files), report.Errors(), report.Warnings())
+	}
+	if report.Errors() > 0 {
+		return podops.ErrLintFailed
+	}
+	return nil
+}
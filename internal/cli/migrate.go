@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/loader"
+)
+
+// MigrateCommand rewrites all resources with an old apiVersion in the latest one
+func MigrateCommand(c *cli.Context) error {
+
+	if c.NArg() > 1 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	root, err := ResolveRootDirectory(c)
+	if err != nil {
+		return err
+	}
+
+	dryRun := boolFlag(c, "dry-run") // --dry-run
+
+	files, err := loader.Migrate(context.TODO(), root, dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.Reformatted {
+			printMsg(podops.MsgMigrateReformat, f.File)
+		} else {
+			printMsg(podops.MsgMigrateFile, f.File)
+		}
+	}
+	if dryRun {
+		printMsg(podops.MsgMigrateDryRun, len(files), config.Version)
+		return nil
+	}
+	printMsg(podops.MsgMigrateSuccess, len(files), config.Version)
+	return nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/podops/podops/client"
+)
+
+const (
+	progressBarWidth = 30
+)
+
+type (
+	// progressBar renders the client's upload and download events as a single, self-updating line
+	progressBar struct {
+		out io.Writer
+		mu  sync.Mutex
+
+		count int   // number of files to transfer
+		total int64 // number of bytes to transfer
+		done  int   // number of files completed or failed
+		sent  int64 // bytes of completed or failed files
+
+		inflight map[string]int64 // bytes sent of the files currently transferred
+	}
+)
+
+// newProgressBar returns a progress bar writing to stderr or nil, if stderr is not a terminal
+func newProgressBar() *progressBar {
+	fi, err := os.Stderr.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return &progressBar{out: os.Stderr, inflight: make(map[string]int64)}
+}
+
+// Update implements client.ProgressFunc
+func (p *progressBar) Update(e client.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch e.Type {
+	case client.SyncPlanned:
+		p.count = e.Count
+		p.total = e.Total
+	case client.UploadStarted, client.DownloadStarted:
+		p.inflight[e.Name] = 0
+	case client.UploadProgress, client.DownloadProgress:
+		p.inflight[e.Name] = e.Bytes
+	case client.UploadCompleted, client.DownloadCompleted:
+		p.done++
+		p.sent += e.Total
+		delete(p.inflight, e.Name)
+	case client.UploadFailed, client.DownloadFailed:
+		p.done++
+		p.sent += e.Total
+		delete(p.inflight, e.Name)
+		p.clear()
+		fmt.Fprintf(p.out, "%s: %v\n", e.Name, e.Err)
+	case client.RequestRetried:
+		if _, ok := p.inflight[e.Name]; ok {
+			p.inflight[e.Name] = 0
+		}
+		p.clear()
+		fmt.Fprintf(p.out, "%s: %v, retrying in %s (attempt %d)\n", e.Name, e.Err, e.Wait, e.Attempt+1)
+	}
+	p.render(e.Name)
+}
+
+// Done ends the progress line
+func (p *progressBar) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.count > 0 {
+		fmt.Fprintln(p.out)
+	}
+}
+
+func (p *progressBar) render(name string) {
+	if p.count == 0 {
+		return
+	}
+
+	sent := p.sent
+	for _, n := range p.inflight {
+		sent += n
+	}
+
+	percent := 100
+	if p.total > 0 {
+		percent = int(sent * 100 / p.total)
+		if percent > 100 {
+			percent = 100
+		}
+	}
+	filled := percent * progressBarWidth / 100
+
+	fmt.Fprintf(p.out, "\r[%s%s] %3d%% %d/%d %s\033[K",
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", progressBarWidth-filled),
+		percent, p.done, p.count, name)
+}
+
+func (p *progressBar) clear() {
+	fmt.Fprint(p.out, "\r\033[K")
+}
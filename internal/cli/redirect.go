@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/client"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/cdn"
+	"github.com/podops/podops/internal/loader"
+)
+
+// RedirectListCommand lists the feed redirects of the podcast in the current directory
+func RedirectListCommand(c *cli.Context) error {
+
+	if c.NArg() > 0 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	parent, err := resolveShow("")
+	if err != nil {
+		return err
+	}
+
+	redirects, err := client.New(config.Settings()).Redirects(context.TODO(), parent)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range redirects {
+		printMsg("%s -> %s", r.From, r.To)
+	}
+	return nil
+}
+
+// RedirectAddCommand permanently redirects the feed of a podcast name to another name or URL
+func RedirectAddCommand(c *cli.Context) error {
+
+	if c.NArg() != 2 {
+		return fmt.Errorf(MsgMissingCmdParameters, "redirect add")
+	}
+
+	from := c.Args().First()
+	to := c.Args().Get(1)
+
+	err := updateRedirects(func(redirects []cdn.Redirect) []cdn.Redirect {
+		result := removeRedirect(redirects, from)
+		return append(result, cdn.Redirect{From: from, To: to})
+	}, fmt.Sprintf(podops.MsgRedirectAdded, from, to))
+	if err != nil {
+		return err
+	}
+	return updateNewFeedLink(from, cdn.RedirectURL(to))
+}
+
+// RedirectRemoveCommand removes the redirect of a podcast name
+func RedirectRemoveCommand(c *cli.Context) error {
+
+	if c.NArg() != 1 {
+		return fmt.Errorf(MsgMissingCmdParameters, "redirect remove")
+	}
+
+	from := c.Args().First()
+
+	err := updateRedirects(func(redirects []cdn.Redirect) []cdn.Redirect {
+		return removeRedirect(redirects, from)
+	}, fmt.Sprintf(podops.MsgRedirectRemoved, from))
+	if err != nil {
+		return err
+	}
+	return updateNewFeedLink(from, "")
+}
+
+// updateRedirects reads, modifies and writes back the redirects of the podcast in the current directory
+func updateRedirects(update func([]cdn.Redirect) []cdn.Redirect, msg string) error {
+	parent, err := resolveShow("")
+	if err != nil {
+		return err
+	}
+
+	cl := client.New(config.Settings())
+	redirects, err := cl.Redirects(context.TODO(), parent)
+	if err != nil {
+		return err
+	}
+
+	if err := cl.UpdateRedirects(context.TODO(), parent, update(redirects)); err != nil {
+		return err
+	}
+
+	printMsg(msg)
+	return nil
+}
+
+// updateNewFeedLink announces the move of the podcast in the current directory in its feed, if from
+// is its name. The feed is built from the show, so the new location has to be kept there.
+func updateNewFeedLink(from, uri string) error {
+	path := loader.ShowPath(".")
+	r, kind, _, err := loader.ReadResource(context.TODO(), path)
+	if err != nil {
+		return err
+	}
+	show, ok := r.(*podops.Show)
+	if kind != podops.ResourceShow || !ok || show.Metadata.Name != from {
+		return nil
+	}
+	if uri == "" && show.NewFeedLink == nil {
+		return nil
+	}
+
+	if err := loader.SetNewFeedLink(context.TODO(), path, uri); err != nil {
+		return err
+	}
+	if uri == "" {
+		printMsg(podops.MsgNewFeedLinkRemove, from)
+	} else {
+		printMsg(podops.MsgNewFeedLinkSet, from, uri)
+	}
+	return nil
+}
+
+func removeRedirect(redirects []cdn.Redirect, from string) []cdn.Redirect {
+	result := make([]cdn.Redirect, 0, len(redirects))
+	for _, r := range redirects {
+		if r.From != from {
+			result = append(result, r)
+		}
+	}
+	return result
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/client"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/loader"
+)
+
+// ShowListCommand lists the shows visible to the current credentials
+func ShowListCommand(c *cli.Context) error {
+
+	if c.NArg() > 0 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	shows, err := client.New(config.Settings()).Shows(context.TODO(), c.String("owner"))
+	if err != nil {
+		return err
+	}
+
+	if boolFlag(c, "json") {
+		data, err := json.MarshalIndent(shows, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, s := range shows {
+		status := ""
+		if s.Deleted > 0 {
+			status = fmt.Sprintf(" (deleted %s)", time.Unix(s.Deleted, 0).Format(time.RFC3339))
+		}
+		printMsg("%s  %-24s owner=%s files=%d size=%s%s", s.GUID, s.Name, s.Owner, s.Files, formatBytes(s.Bytes), status)
+	}
+	return nil
+}
+
+// ShowConflictsCommand lists the names that are claimed by more than one show
+func ShowConflictsCommand(c *cli.Context) error {
+
+	if c.NArg() > 0 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	conflicts, err := client.New(config.Settings()).Conflicts(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	if boolFlag(c, "json") {
+		data, err := json.MarshalIndent(conflicts, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, cf := range conflicts {
+		printMsg("%-24s owner=%s rejected=%s", cf.Name, cf.Owner, strings.Join(cf.Rejected, ","))
+	}
+	return nil
+}
+
+// ShowDeleteCommand soft-deletes a show
+func ShowDeleteCommand(c *cli.Context) error {
+
+	if c.NArg() > 1 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	parent, err := resolveShow(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if !boolFlag(c, "force") {
+		if err := confirm(fmt.Sprintf(podops.MsgShowDeleteConfirm, parent)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.New(config.Settings()).DeleteShow(context.TODO(), parent); err != nil {
+		return err
+	}
+
+	printMsg(podops.MsgShowDeleted, parent)
+	return nil
+}
+
+// ShowRestoreCommand reverts the soft-delete of a show
+func ShowRestoreCommand(c *cli.Context) error {
+
+	if c.NArg() > 1 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	parent, err := resolveShow(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if err := client.New(config.Settings()).RestoreShow(context.TODO(), parent); err != nil {
+		return err
+	}
+
+	printMsg(podops.MsgShowRestored, parent)
+	return nil
+}
+
+// ShowTransferCommand makes another user the owner of a show
+func ShowTransferCommand(c *cli.Context) error {
+
+	if c.NArg() < 1 {
+		return fmt.Errorf(MsgMissingCmdParameters, "show transfer")
+	}
+	if c.NArg() > 2 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	parent, err := resolveShow(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := client.New(config.Settings()).TransferShow(context.TODO(), parent, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	printMsg(podops.MsgShowTransferred, parent, cfg.Credentials.UserID, cfg.Credentials.Token)
+	return nil
+}
+
+// ShowRenameCommand changes the canonical name of a show
+func ShowRenameCommand(c *cli.Context) error {
+
+	if c.NArg() < 1 {
+		return fmt.Errorf(MsgMissingCmdParameters, "show rename")
+	}
+	if c.NArg() > 2 {
+		return podops.ErrInvalidNumArguments
+	}
+
+	name := c.Args().First()
+	if !podops.ValidName(name) {
+		return podops.ErrInvalidResourceName
+	}
+
+	parent, err := resolveShow(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	if err := client.New(config.Settings()).RenameShow(context.TODO(), parent, name); err != nil {
+		return err
+	}
+
+	printMsg(podops.MsgShowRenamed, parent, name)
+	return nil
+}
+
+// resolveShow returns parent if given. Otherwise the show in the current directory
+// is used, together with its local credentials.
+func resolveShow(parent string) (string, error) {
+	if parent != "" {
+		if !podops.ValidGUID(parent) {
+			return "", podops.ErrInvalidGUID
+		}
+		return parent, nil
+	}
+
+	root, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	// reload the local credentials
+	config.UpdateClientSettings(filepath.Join(root, config.DefaultConfigFileLocation))
+
+	_, kind, guid, err := loader.ReadResource(context.TODO(), loader.ShowPath(root))
+	if err != nil {
+		return "", err
+	}
+	if kind != podops.ResourceShow {
+		return "", podops.ErrBuildNoShow
+	}
+	return guid, nil
+}
+
+// confirm asks the user a yes/no question. It fails if stdin is not a terminal or the answer is not yes.
+func confirm(question string) error {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return podops.ErrNotConfirmed
+	}
+
+	fmt.Print(question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		return podops.ErrNotConfirmed
+	}
+
+	return nil
+}
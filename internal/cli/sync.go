@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/client"
+)
+
+// printPlan prints the sync plan either as JSON or in a human readable form
+func printPlan(plan *client.SyncPlan, asJSON bool) error {
+	if asJSON {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	var size int64
+	for _, u := range plan.Uploads {
+		printMsg(podops.MsgSyncPlanUpload, u.Name, formatBytes(u.Size))
+		size += u.Size
+	}
+	for _, d := range plan.Deletions {
+		printMsg(podops.MsgSyncPlanDelete, d.Name)
+	}
+	printMsg(podops.MsgSyncPlanPublish, plan.Feed.Name)
+	printMsg(podops.MsgSyncPlan, len(plan.Uploads), formatBytes(size), len(plan.Skips), len(plan.Deletions))
+
+	return nil
+}
+
+// confirmPurge asks the user to confirm the deletions in the plan
+func confirmPurge(plan *client.SyncPlan) error {
+	if len(plan.Deletions) == 0 {
+		return nil
+	}
+	return confirm(fmt.Sprintf(podops.MsgPurgeConfirm, len(plan.Deletions), plan.Parent))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
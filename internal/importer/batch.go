@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/loader"
+)
+
+type (
+	// BatchResult is the outcome of importing one feed in a batch
+	BatchResult struct {
+		Source   string           `json:"source"`
+		Name     string           `json:"name,omitempty"`
+		Location string           `json:"location,omitempty"`
+		Episodes int              `json:"episodes"`
+		Warnings []string         `json:"warnings,omitempty"`
+		Report   *ImportReport    `json:"report,omitempty"`
+		Media    *MigrationReport `json:"media,omitempty"`
+		Error    string           `json:"error,omitempty"`
+	}
+
+	// BatchReport summarizes the import of several feeds
+	BatchReport struct {
+		Results []*BatchResult `json:"results"`
+	}
+
+	opml struct {
+		Body struct {
+			Outlines []outline `xml:"outline"`
+		} `xml:"body"`
+	}
+
+	outline struct {
+		XMLURL   string    `xml:"xmlUrl,attr"`
+		Outlines []outline `xml:"outline"`
+	}
+)
+
+// Failed returns the number of feeds that could not be imported
+func (r *BatchReport) Failed() int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Error != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// WithWarnings returns the number of imported feeds with warnings
+func (r *BatchReport) WithWarnings() int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Error == "" && len(result.Warnings) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// ReadOPML returns the feeds listed in an OPML document, outlines can be nested
+func ReadOPML(r io.Reader) ([]string, error) {
+	var doc opml
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	feeds := make([]string, 0)
+	var collect func([]outline)
+	collect = func(outlines []outline) {
+		for _, o := range outlines {
+			if o.XMLURL != "" {
+				feeds = append(feeds, o.XMLURL)
+			}
+			collect(o.Outlines)
+		}
+	}
+	collect(doc.Body.Outlines)
+
+	return feeds, nil
+}
+
+// ReadOPMLFile returns the feeds listed in an OPML file. Feeds that are local files
+// are relative to the location of the OPML file.
+func ReadOPMLFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	feeds, err := ReadOPML(f)
+	if err != nil {
+		return nil, err
+	}
+	for i, feed := range feeds {
+		if !isURL(feed) && !filepath.IsAbs(feed) {
+			feeds[i] = filepath.Join(filepath.Dir(path), feed)
+		}
+	}
+	return feeds, nil
+}
+
+// ImportBatch imports each feed into its own repository below root. The repositories are named
+// after the shows. A failed import does not stop the batch, the report has the details.
+// If rewrite is true, the media files are imported as well, see RewriteAssets. If strict is true,
+// feeds that can not be imported as is are not imported, see ImportPodcast.
+func ImportBatch(ctx context.Context, sources []string, root string, singleFile, rewrite, strict bool) *BatchReport {
+	report := BatchReport{
+		Results: make([]*BatchResult, len(sources)),
+	}
+
+	for i, source := range sources {
+		result := BatchResult{Source: source}
+		if err := importInto(ctx, &result, root, singleFile, rewrite, strict); err != nil {
+			result.Error = err.Error()
+		}
+		report.Results[i] = &result
+	}
+
+	return &report
+}
+
+// importInto imports a feed into a new repository below root
+func importInto(ctx context.Context, result *BatchResult, root string, singleFile, rewrite, strict bool) error {
+	show, report, err := ImportPodcast(result.Source, strict)
+	result.Report = report
+	if err != nil {
+		return err
+	}
+	result.Name = show.Metadata.Name
+	result.Episodes = len(show.Episodes)
+	result.Warnings = report.Warnings()
+
+	// names taken from a feed are not always valid
+	dir := show.Metadata.Name
+	if !podops.ValidName(dir) {
+		dir = show.Metadata.GUID
+	}
+	location := filepath.Join(root, dir)
+	if _, err := os.Stat(location); err == nil {
+		return fmt.Errorf("%w: '%s'", podops.ErrResourceExists, location)
+	}
+	if err := os.MkdirAll(location, 0755); err != nil {
+		return err
+	}
+	result.Location = location
+
+	media, err := WriteRepository(ctx, location, result.Source, show, singleFile, rewrite)
+	if err != nil {
+		return err
+	}
+	if media != nil {
+		result.Media = media
+		for _, a := range media.Assets {
+			if a.Error != "" {
+				result.Warnings = append(result.Warnings, a.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteRepository writes an imported show and its episodes to the repository at root and
+// remembers the import for a later UpdateRepository. If rewrite is true, the media files are
+// imported into the repository first and a report of the migration is returned.
+func WriteRepository(ctx context.Context, root, source string, show *podops.Show, singleFile, rewrite bool) (*MigrationReport, error) {
+	// local feeds can be updated from any working directory
+	if !isURL(source) {
+		if abs, err := filepath.Abs(source); err == nil {
+			source = abs
+		}
+	}
+	// the state is what upstream has, before any rewrites
+	if err := SaveImportState(root, source, show); err != nil {
+		return nil, err
+	}
+
+	var report *MigrationReport
+	if rewrite {
+		var err error
+		if report, err = RewriteAssets(ctx, root, show); err != nil {
+			return nil, err
+		}
+	}
+
+	if singleFile {
+		return report, loader.WriteResource(ctx, filepath.Join(root, config.DefaultShowName), show)
+	}
+
+	// files of an earlier import are overwritten, episodes with the same numbers are not
+	written := make(map[string]bool)
+	for _, e := range show.Episodes {
+		path := episodePath(root, e, func(path string) bool { return written[path] })
+		if err := loader.WriteResource(ctx, path, e); err != nil {
+			return nil, err
+		}
+		written[path] = true
+	}
+	return report, loader.WriteResource(ctx, filepath.Join(root, config.DefaultShowName), showWithoutEpisodes(show))
+}
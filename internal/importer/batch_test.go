@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+)
+
+const testOPML = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+<head><title>Network</title></head>
+<body>
+	<outline text="Shows">
+		<outline type="rss" text="Update Podcast" xmlUrl="feeds/update.xml"/>
+		<outline type="rss" text="Empty Podcast" xmlUrl="feeds/empty.xml"/>
+	</outline>
+	<outline type="rss" text="Missing" xmlUrl="feeds/missing.xml"/>
+</body>
+</opml>`
+
+func TestReadOPML(t *testing.T) {
+	feeds, err := ReadOPML(strings.NewReader(testOPML))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"feeds/update.xml", "feeds/empty.xml", "feeds/missing.xml"}, feeds)
+}
+
+func TestImportPodcastReader(t *testing.T) {
+	show, err := ImportPodcastReader(strings.NewReader(feedWith("The show", map[int]string{1: "first", 2: "second"})))
+	assert.NoError(t, err)
+	assert.Equal(t, "update_podcast", show.Metadata.Name)
+	assert.Equal(t, 2, len(show.Episodes))
+}
+
+func TestImportBatch(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "feeds"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "feeds/update.xml"), []byte(feedWith("The show", map[int]string{1: "first", 2: "second"})), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "feeds/empty.xml"), []byte(strings.Replace(feedWith("Empty", nil), "Update Podcast", "Empty Podcast", 1)), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "network.opml"), []byte(testOPML), 0644))
+
+	sources, err := ReadOPMLFile(filepath.Join(dir, "network.opml"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "feeds/update.xml"), sources[0])
+
+	root := t.TempDir()
+	report := ImportBatch(context.TODO(), sources, root, false, false, false)
+	assert.Equal(t, 3, len(report.Results))
+	assert.Equal(t, 1, report.Failed())
+	assert.Equal(t, 2, report.WithWarnings())
+
+	r := report.Results[0]
+	assert.Empty(t, r.Error)
+	assert.Equal(t, 2, r.Episodes)
+	assert.Equal(t, filepath.Join(root, "update_podcast"), r.Location)
+	for _, name := range []string{"show.yaml", "episode-S1E1.yaml", "episode-S1E2.yaml", ".podops/import.json"} {
+		_, err := os.Stat(filepath.Join(r.Location, name))
+		assert.NoError(t, err, name)
+	}
+
+	assert.Contains(t, report.Results[1].Warnings, podops.MsgImportNoEpisodes)
+	assert.NotEmpty(t, report.Results[2].Error)
+
+	// existing repositories are not overwritten
+	report = ImportBatch(context.TODO(), sources[:1], root, false, false, false)
+	assert.Equal(t, 1, report.Failed())
+}
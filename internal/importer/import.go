@@ -1,13 +1,19 @@
 package importer
 
 import (
+	"bytes"
 	"context"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
 
 	"github.com/podops/podops"
 	"github.com/podops/podops/config"
@@ -18,44 +24,171 @@ const (
 	parseRSSTimeout = 60 * time.Second
 )
 
+type (
+	// rssEnclosures is the part of a RSS feed the parser does not keep
+	rssEnclosures struct {
+		Items []struct {
+			Enclosures []struct {
+				URL    string `xml:"url,attr"`
+				Type   string `xml:"type,attr"`
+				Length string `xml:"length,attr"`
+			} `xml:"enclosure"`
+		} `xml:"channel>item"`
+	}
+)
+
+// ImportPodcastFeed imports a show and its episodes from a feed. The feed is
+// either a URL or the path of a local file, e.g. an archived feed.xml.
 func ImportPodcastFeed(feedUrl string) (*podops.Show, error) {
+	show, _, err := ImportPodcast(feedUrl, false)
+	return show, err
+}
+
+// ImportPodcastReader imports a show and its episodes from the feed in r
+func ImportPodcastReader(r io.Reader) (*podops.Show, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	feed, err := parseFeedData(data)
+	if err != nil {
+		return nil, err
+	}
+	show, _ := importFeed(feed)
+	return show, nil
+}
+
+// ImportPodcast imports a show and its episodes from a URL or a local file. It also reports
+// anything in the feed that could not be imported as is. If strict is true, such a feed is
+// not imported and ErrImportNotStrict is returned together with the report.
+func ImportPodcast(source string, strict bool) (*podops.Show, *ImportReport, error) {
+	feed, err := parseFeed(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	show, report := importFeed(feed)
+	if strict {
+		if err := report.Err(); err != nil {
+			return nil, report, err
+		}
+	}
+	return show, report, nil
+}
+
+// parseFeed reads the feed at a URL or in a local file
+func parseFeed(source string) (*gofeed.Feed, error) {
+	data, err := readFeed(source)
+	if err != nil {
+		return nil, err
+	}
+	return parseFeedData(data)
+}
+
+// readFeed returns the raw feed at a URL or in a local file
+func readFeed(source string) ([]byte, error) {
+	if !isURL(source) {
+		return os.ReadFile(source)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), parseRSSTimeout)
 	defer cancel()
 
-	fp := gofeed.NewParser()
-	fp.UserAgent = config.UserAgentString
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", config.UserAgentString)
 
-	feed, err := fp.ParseURLWithContext(feedUrl, ctx)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(podops.MsgStatus, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseFeedData parses a raw feed. The RSS parser only keeps the last enclosure
+// of an item, all others are restored from the raw feed.
+func parseFeedData(data []byte) (*gofeed.Feed, error) {
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if feed.FeedType != "rss" {
+		return feed, nil
+	}
+
+	var doc rssEnclosures
+	if err := xml.Unmarshal(data, &doc); err != nil || len(doc.Items) != len(feed.Items) {
+		return feed, nil // keep what the parser found
+	}
+	for i, item := range doc.Items {
+		if len(item.Enclosures) < 2 {
+			continue
+		}
+		enclosures := make([]*gofeed.Enclosure, len(item.Enclosures))
+		for j, encl := range item.Enclosures {
+			enclosures[j] = &gofeed.Enclosure{
+				URL:    strings.TrimSpace(encl.URL),
+				Type:   strings.TrimSpace(encl.Type),
+				Length: strings.TrimSpace(encl.Length),
+			}
+		}
+		feed.Items[i].Enclosures = enclosures
+	}
+	return feed, nil
+}
+
+func importFeed(feed *gofeed.Feed) (*podops.Show, *ImportReport) {
+	report := ImportReport{}
 
 	// import the description of the show
-	show := importShow(feed)
+	show := importShow(feed, &report)
 
 	// import all episodes
 	fixEpisodeNumbers := false
 	countFixEpisodeNumbers := 0
-	show.Episodes = make(podops.EpisodeList, len(feed.Items))
-	for i, item := range feed.Items {
-		e := importEpisode(item)
+	guids := make(map[string]bool)
+	show.Episodes = make(podops.EpisodeList, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		if len(item.Enclosures) == 0 || item.Enclosures[0].URL == "" {
+			report.skip(item.Title, podops.MsgImportNoEnclosure)
+			continue
+		}
+		guid := itemGUID(item)
+		if guids[guid] {
+			report.skip(item.Title, fmt.Sprintf(podops.MsgImportDuplicate, item.GUID))
+			continue
+		}
+		guids[guid] = true
+		e := importEpisode(item, &report)
 
 		if e.EpisodeAsInt() < 1 {
 			// incomplete numbering ?
 			countFixEpisodeNumbers++
 		}
-		if e.Image.URI == "" {
+		if e.Image.URI == "" && show.Image.URI != "" {
 			// re-use the shows image for the episode
 			e.Image = podops.AssetRef{
 				URI: show.Image.URI,
 				Rel: show.Image.Rel,
 			}
+			report.repair(item.Title, podops.MsgImportShowImage)
 		}
 		if e.Metadata.Parent == "" {
 			e.Metadata.Parent = show.Metadata.GUID
 		}
 		// finally add the episode to the array
-		show.Episodes[i] = e
+		show.Episodes = append(show.Episodes, e)
+	}
+
+	if len(show.Episodes) == 0 {
+		report.missing("", podops.MsgImportNoEpisodes)
+		return &show, &report
 	}
 
 	// sort episodes, descending by timestamp
@@ -70,6 +203,7 @@ func ImportPodcastFeed(feedUrl string) (*podops.Show, error) {
 			show.Episodes[i].Metadata.Labels[podops.LabelEpisode] = fmt.Sprintf("%d", maxEpisode)
 			maxEpisode--
 		}
+		report.repair("", podops.MsgImportNumbered)
 	}
 
 	// adjust the publish date if the timestamp on the show is older than the one from the latest episode
@@ -77,15 +211,17 @@ func ImportPodcastFeed(feedUrl string) (*podops.Show, error) {
 		show.Metadata.Date = show.Episodes[0].Metadata.Date
 	}
 
-	return &show, nil
+	report.validateImport(&show)
+
+	return &show, &report
 }
 
-func importShow(feed *gofeed.Feed) podops.Show {
+func importShow(feed *gofeed.Feed, report *ImportReport) podops.Show {
 	show := podops.Show{
 		APIVersion:  config.Version,
 		Kind:        podops.ResourceShow,
-		Metadata:    importShowMetadata(feed),
-		Description: importShowDescription(feed),
+		Metadata:    importShowMetadata(feed, report),
+		Description: importShowDescription(feed, report),
 		Image:       importShowImageAssetRef(feed),
 	}
 
@@ -104,23 +240,31 @@ func importShow(feed *gofeed.Feed) podops.Show {
 			}
 		}
 	}
+	show.Podcast = importPodcastTags(feed.Extensions)
 
 	return show
 }
 
-func importShowMetadata(feed *gofeed.Feed) podops.Metadata {
+func importShowMetadata(feed *gofeed.Feed, report *ImportReport) podops.Metadata {
 	m := podops.Metadata{
 		Name:   formatName(feed.Title),
 		GUID:   internal.CreateShortGUID(feed.Title),
 		Labels: podops.DefaultShowMetadata(),
 	}
 
-	m.Labels[podops.LabelLanguage] = stringWithDefault(feed.Language, "en")
+	// resources use e.g. 'en_US', feeds e.g. 'en-us'
+	language := convLanguage(feed.Language)
+	if feed.Language == "" {
+		report.repair("", podops.MsgImportLanguage)
+	} else if language != feed.Language {
+		report.repair("", fmt.Sprintf(podops.MsgImportLocale, feed.Language, language))
+	}
+	m.Labels[podops.LabelLanguage] = language
 
 	if feed.Published != "" {
-		m.Date = feed.Published
+		m.Date = importDate("", feed.Published, feed.PublishedParsed, report)
 	} else if feed.Updated != "" {
-		m.Date = feed.Updated
+		m.Date = importDate("", feed.Updated, feed.UpdatedParsed, report)
 	}
 
 	if feed.ITunesExt != nil {
@@ -137,7 +281,7 @@ func importShowMetadata(feed *gofeed.Feed) podops.Metadata {
 	return m
 }
 
-func importShowDescription(feed *gofeed.Feed) podops.ShowDescription {
+func importShowDescription(feed *gofeed.Feed, report *ImportReport) podops.ShowDescription {
 	sd := podops.ShowDescription{
 		Title:   feed.Title,
 		Summary: feed.Description,
@@ -145,7 +289,7 @@ func importShowDescription(feed *gofeed.Feed) podops.ShowDescription {
 			URI: feed.Link,
 			Rel: podops.ResourceTypeExternal,
 		},
-		Category:  importCategory(feed),
+		Category:  importCategory(feed, report),
 		Owner:     importOwner(feed),
 		Author:    importAuthor(feed),
 		Copyright: feed.Copyright,
@@ -154,16 +298,24 @@ func importShowDescription(feed *gofeed.Feed) podops.ShowDescription {
 	return sd
 }
 
-func importCategory(feed *gofeed.Feed) []podops.Category {
+func importCategory(feed *gofeed.Feed, report *ImportReport) []podops.Category {
 	var cc *podops.Category                 // current category
 	cm := make(map[string]*podops.Category) // category map
 	ca := make([]*podops.Category, 0)       // category array
 
 	for _, c := range feed.Categories {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
 		lc, found := cm[c]
 		if found {
 			cc = lc
 		} else {
+			if strings.HasPrefix(c, " ") && cc == nil {
+				// a subcategory without a category
+				c = strings.Trim(c, " ")
+				report.repair("", fmt.Sprintf(podops.MsgImportSubCategory, c))
+			}
 			if strings.HasPrefix(c, " ") {
 				// found a subcategory
 				if len(cc.SubCategory) == 0 {
@@ -201,6 +353,9 @@ func importOwner(feed *gofeed.Feed) podops.Owner {
 			Email: feed.ITunesExt.Owner.Email,
 		}
 	}
+	if feed.Author == nil {
+		return podops.Owner{}
+	}
 	return podops.Owner{
 		Name:  feed.Author.Name,
 		Email: feed.Author.Email,
@@ -211,6 +366,9 @@ func importAuthor(feed *gofeed.Feed) string {
 	if feed.ITunesExt != nil && feed.ITunesExt.Author != "" {
 		return feed.ITunesExt.Author
 	}
+	if feed.Author == nil {
+		return ""
+	}
 	return feed.Author.Name
 }
 
@@ -231,14 +389,22 @@ func importShowImageAssetRef(feed *gofeed.Feed) podops.AssetRef {
 	}
 }
 
-func importEpisode(item *gofeed.Item) *podops.Episode {
+// importEpisode imports an item, items without an enclosure are skipped by the caller
+func importEpisode(item *gofeed.Item, report *ImportReport) *podops.Episode {
 	episode := podops.Episode{
 		APIVersion:  config.Version,
 		Kind:        podops.ResourceEpisode,
-		Metadata:    importEpisodeMetadata(item),
+		Metadata:    importEpisodeMetadata(item, report),
 		Description: importEpisodeDescription(item),
 		Image:       importItemImageAssetRef(item),
-		Enclosure:   importEnclosureAssetRef(item),
+		Podcast:     importPodcastTags(item.Extensions),
+	}
+	for i, encl := range item.Enclosures {
+		if i == 0 {
+			episode.Enclosure = importEnclosureAssetRef(encl, item, report)
+			continue
+		}
+		episode.AlternateEnclosures = append(episode.AlternateEnclosures, importEnclosureAssetRef(encl, item, report))
 	}
 
 	return &episode
@@ -256,27 +422,41 @@ func importEpisodeDescription(item *gofeed.Item) podops.EpisodeDescription {
 		Duration: 0,
 	}
 
+	if item.Author != nil {
+		ed.Author = item.Author.Name
+	}
+
 	// patch with maybe better data
 	if item.ITunesExt != nil {
 		ed.Summary = stringWithDefault(item.ITunesExt.Summary, ed.Summary)
 		ed.Duration = internal.ConvTimeStringToSeconds(item.ITunesExt.Duration)
+		ed.Author = stringWithDefault(item.ITunesExt.Author, ed.Author)
 	}
 	return ed
 }
 
-// importEnclosureAssetRef returns the first enclosure only !
-func importEnclosureAssetRef(item *gofeed.Item) podops.AssetRef {
+func importEnclosureAssetRef(encl *gofeed.Enclosure, item *gofeed.Item, report *ImportReport) podops.AssetRef {
 	ar := podops.AssetRef{
-		URI:      item.Enclosures[0].URL,
+		URI:      encl.URL,
 		Rel:      podops.ResourceTypeExternal,
-		Type:     item.Enclosures[0].Type,
+		Type:     encl.Type,
 		Duration: convDurationToInt(item.ITunesExt),
-		Size:     internal.ConvStrToInt(item.Enclosures[0].Length),
+		Size:     internal.ConvStrToInt(encl.Length),
+	}
+	if ar.Size < 0 {
+		// e.g. a placeholder or a formatted number, the build uses the real size
+		ar.Size = 0
+		report.repair(item.Title, fmt.Sprintf(podops.MsgImportLength, encl.Length))
 	}
 
 	return ar
 }
 
+// importPodcastTags returns the elements of the Podcasting 2.0 namespace, sorted by name
+func importPodcastTags(extensions ext.Extensions) []podops.PodcastTag {
+	return convExtensions(extensions["podcast"])
+}
+
 // importItemImageAssetRef returns the img url, iTunesExt has priority
 func importItemImageAssetRef(item *gofeed.Item) podops.AssetRef {
 	var url = ""
@@ -294,17 +474,18 @@ func importItemImageAssetRef(item *gofeed.Item) podops.AssetRef {
 	}
 }
 
-func importEpisodeMetadata(item *gofeed.Item) podops.Metadata {
+func importEpisodeMetadata(item *gofeed.Item, report *ImportReport) podops.Metadata {
 	m := podops.Metadata{
-		Name:   formatName(item.Title),
-		GUID:   itemGUID(item),
-		Labels: podops.DefaultEpisodeMetadata(),
+		Name:     formatName(item.Title),
+		GUID:     itemGUID(item),
+		FeedGUID: item.GUID, // verbatim, the GUID above is only used internally
+		Labels:   podops.DefaultEpisodeMetadata(),
 	}
 
 	if item.Published != "" {
-		m.Date = item.Published
+		m.Date = importDate(item.Title, item.Published, item.PublishedParsed, report)
 	} else if item.Updated != "" {
-		m.Date = item.Updated
+		m.Date = importDate(item.Title, item.Updated, item.UpdatedParsed, report)
 	}
 
 	if item.ITunesExt != nil {
@@ -321,3 +502,14 @@ func importEpisodeMetadata(item *gofeed.Item) podops.Metadata {
 
 	return m
 }
+
+// importDate returns a date in the format of the resources. Feeds use all kinds of
+// formats, the parser understands most of them.
+func importDate(episode, date string, parsed *time.Time, report *ImportReport) string {
+	if _, err := time.Parse(time.RFC1123Z, date); err == nil || parsed == nil {
+		return date
+	}
+	converted := parsed.Format(time.RFC1123Z)
+	report.repair(episode, fmt.Sprintf(podops.MsgImportDate, date, converted))
+	return converted
+}
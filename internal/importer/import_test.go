@@ -1,12 +1,17 @@
 package importer
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
 )
 
 var (
@@ -47,3 +52,91 @@ func TestRetrieveFeed(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, show)
 }
+
+func TestImportFidelity(t *testing.T) {
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:podcast="https://podcastindex.org/namespace/1.0">
+<channel>
+	<title>Fidelity Podcast</title>
+	<link>https://example.com/fidelity</link>
+	<description>All the details</description>
+	<podcast:guid>917393e3-1b1e-5cef-ace4-edaa54e1f810</podcast:guid>
+	<podcast:funding url="https://example.com/donate">Support the show</podcast:funding>
+	<item>
+		<title>Episode 1</title>
+		<guid isPermaLink="false">tag:example.com,2021:episode-1</guid>
+		<description>Short</description>
+		<content:encoded><![CDATA[<p>The <b>long</b> version</p>]]></content:encoded>
+		<itunes:author>Guest Author</itunes:author>
+		<pubDate>Mon, 01 Mar 2021 10:00:00 +0000</pubDate>
+		<enclosure url="https://example.com/episode1.mp3" length="1000" type="audio/mpeg"/>
+		<enclosure url="https://example.com/episode1.ogg" length="800" type="audio/ogg"/>
+		<podcast:transcript url="https://example.com/episode1.vtt" type="text/vtt"/>
+		<podcast:person role="host" href="https://example.com/jane">Jane Doe</podcast:person>
+	</item>
+</channel>
+</rss>`
+
+	show, err := ImportPodcastReader(strings.NewReader(feed))
+	assert.NoError(t, err)
+	assert.Equal(t, []podops.PodcastTag{
+		{Name: "funding", Value: "Support the show", Attrs: map[string]string{"url": "https://example.com/donate"}},
+		{Name: "guid", Value: "917393e3-1b1e-5cef-ace4-edaa54e1f810"},
+	}, show.Podcast)
+
+	e := show.Episodes[0]
+	assert.Equal(t, "tag:example.com,2021:episode-1", e.Metadata.FeedGUID)
+	assert.True(t, podops.ValidGUID(e.Metadata.GUID))
+	assert.Equal(t, "<p>The <b>long</b> version</p>", e.Description.EpisodeText)
+	assert.Equal(t, "Guest Author", e.Description.Author)
+	assert.Equal(t, "https://example.com/episode1.mp3", e.Enclosure.URI)
+	assert.Equal(t, []podops.AssetRef{{URI: "https://example.com/episode1.ogg", Rel: podops.ResourceTypeExternal, Type: "audio/ogg", Size: 800}}, e.AlternateEnclosures)
+	assert.Equal(t, 2, len(e.Podcast))
+	assert.Equal(t, "person", e.Podcast[0].Name)
+	assert.Equal(t, "Jane Doe", e.Podcast[0].Value)
+	assert.Equal(t, "transcript", e.Podcast[1].Name)
+	assert.Equal(t, "text/vtt", e.Podcast[1].Attrs["type"])
+}
+
+func TestImportMessyFeeds(t *testing.T) {
+	tests := []struct {
+		feed     string
+		episodes int
+		skipped  int
+		repaired int
+		missing  bool
+	}{
+		{"clean.xml", 2, 0, 0, false},
+		{"no_author.xml", 1, 0, 1, true},           // no language, no owner
+		{"no_enclosures.xml", 0, 2, 1, true},       // no language, nothing to import
+		{"orphan_subcategory.xml", 1, 0, 2, false}, // 'en-us', the category
+		{"messy_items.xml", 2, 1, 6, false},        // a duplicate; 'en', a date, a length, two images and the numbering
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.feed, func(t *testing.T) {
+			path := filepath.Join("testdata", tt.feed)
+			show, report, err := ImportPodcast(path, false)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.episodes, len(show.Episodes))
+				assert.Equal(t, tt.skipped, len(report.Skipped), report.Warnings())
+				assert.Equal(t, tt.repaired, len(report.Repaired), report.Warnings())
+				assert.Equal(t, tt.missing, len(report.Missing) > 0, report.Warnings())
+			}
+
+			show, _, err = ImportPodcast(path, true)
+			if report.Clean() {
+				assert.NoError(t, err)
+				assert.NotNil(t, show)
+			} else {
+				assert.True(t, errors.Is(err, podops.ErrImportNotStrict))
+				assert.Nil(t, show)
+			}
+		})
+	}
+
+	// the subcategory is used as a category
+	show, _, err := ImportPodcast("testdata/orphan_subcategory.xml", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []podops.Category{{Name: "Technology", SubCategory: []string{"Podcasting"}}}, show.Description.Category)
+}
@@ -0,0 +1,397 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal"
+	"github.com/podops/podops/internal/metadata"
+)
+
+const (
+	maxConcurrentDownloads = 4
+)
+
+type (
+	// MediaResult is the outcome of importing one media file
+	MediaResult struct {
+		URI      string `json:"uri"`
+		Name     string `json:"name,omitempty"` // the file in the build location
+		Size     int64  `json:"size"`
+		Duration int    `json:"duration,omitempty"`
+		Reused   bool   `json:"reused,omitempty"` // true if the file was downloaded by an earlier run
+		Error    string `json:"error,omitempty"`
+	}
+
+	// MigrationReport lists the media files that were moved off their original host
+	MigrationReport struct {
+		Assets []*MediaResult `json:"assets"`
+	}
+
+	// importedMedia is what is remembered about a downloaded media file
+	importedMedia struct {
+		ETag     string `json:"etag"`
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Size     int64  `json:"size"`
+		Duration int    `json:"duration,omitempty"`
+	}
+)
+
+// Failed returns the number of media files that could not be imported
+func (r *MigrationReport) Failed() int {
+	n := 0
+	for _, a := range r.Assets {
+		if a.Error != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Bytes returns the size of all imported media files
+func (r *MigrationReport) Bytes() int64 {
+	var n int64
+	for _, a := range r.Assets {
+		n += a.Size
+	}
+	return n
+}
+
+// RewriteAssets downloads the external images and enclosures of a show and its episodes into the build
+// location of the repository at root and switches their references to ResourceTypeImport, with the real
+// size and duration. Files are downloaded concurrently, an interrupted run is resumed and files that were
+// downloaded before are verified against their checksum. References that could not be imported stay external.
+func RewriteAssets(ctx context.Context, root string, show *podops.Show) (*MigrationReport, error) {
+	location := filepath.Join(root, config.BuildLocation)
+	if err := os.MkdirAll(location, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	imported, err := readMediaManifest(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// the same file is often referenced more than once, e.g. the show's image
+	refs := make(map[string][]*podops.AssetRef)
+	sizes := make(map[string]int64) // the size the feed announces, e.g. an enclosure's length
+	uris := make([]string, 0)
+	add := func(ref *podops.AssetRef) {
+		if ref.Rel != podops.ResourceTypeExternal || !isURL(ref.URI) {
+			return
+		}
+		if _, ok := refs[ref.URI]; !ok {
+			uris = append(uris, ref.URI)
+		}
+		refs[ref.URI] = append(refs[ref.URI], ref)
+		if int64(ref.Size) > sizes[ref.URI] {
+			sizes[ref.URI] = int64(ref.Size)
+		}
+	}
+	add(&show.Image)
+	for _, e := range show.Episodes {
+		add(&e.Image)
+		add(&e.Enclosure)
+		for i := range e.AlternateEnclosures {
+			add(&e.AlternateEnclosures[i])
+		}
+	}
+
+	report := MigrationReport{
+		Assets: make([]*MediaResult, len(uris)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentDownloads)
+
+	for i, uri := range uris {
+		wg.Add(1)
+		go func(i int, uri string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			mu.Lock()
+			known := imported[uri]
+			mu.Unlock()
+
+			result := MediaResult{URI: uri}
+			m, reused, err := importMedia(ctx, location, uri, sizes[uri], known)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Name = m.Name
+				result.Size = m.Size
+				result.Duration = m.Duration
+				result.Reused = reused
+
+				// remember the file right away, in case the run is interrupted
+				mu.Lock()
+				imported[uri] = m
+				err = writeMediaManifest(root, imported)
+				mu.Unlock()
+				if err != nil {
+					result.Error = err.Error()
+				}
+			}
+			report.Assets[i] = &result
+		}(i, uri)
+	}
+	wg.Wait()
+
+	// switch the references to the imported files
+	for _, result := range report.Assets {
+		if result.Error != "" {
+			continue
+		}
+		m := imported[result.URI]
+		for _, ref := range refs[result.URI] {
+			ref.Rel = podops.ResourceTypeImport
+			ref.ETag = m.ETag
+			ref.Type = m.Type
+			ref.Size = int(m.Size)
+			if m.Duration > 0 {
+				ref.Duration = m.Duration
+			}
+		}
+	}
+
+	return &report, nil
+}
+
+// importMedia downloads uri into the build location, unless the known file is still intact.
+// size is the expected size of the file, 0 if it is not known.
+func importMedia(ctx context.Context, location, uri string, size int64, known *importedMedia) (*importedMedia, bool, error) {
+	if known != nil {
+		if etag, err := fileETag(filepath.Join(location, known.Name)); err == nil && etag == known.ETag {
+			return known, true, nil
+		}
+	}
+
+	part := filepath.Join(location, internal.CreateShortGUID(uri)+".part")
+	contentType, err := downloadMedia(ctx, uri, part, size)
+	if err != nil {
+		return nil, false, fmt.Errorf(podops.MsgResourceImportError+": %w", uri, err)
+	}
+
+	// media files are named by their content, like all other assets in the build location
+	etag, err := fileETag(part)
+	if err != nil {
+		return nil, false, err
+	}
+	ref := podops.AssetRef{URI: uri, ETag: etag}
+	path := filepath.Join(location, ref.MediaReference())
+	if err := os.Rename(part, path); err != nil {
+		return nil, false, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	m := importedMedia{
+		ETag: etag,
+		Name: ref.MediaReference(),
+		Type: contentType,
+		Size: fi.Size(),
+	}
+
+	meta, err := metadata.ExtractMetadataFromFile(path)
+	if err == nil {
+		if m.Type == "" {
+			m.Type = meta.ContentType
+		}
+		if strings.HasPrefix(m.Type, "audio/") {
+			m.Duration = int(meta.Duration)
+		}
+	}
+	return &m, false, nil
+}
+
+// downloadMedia fetches uri into part. A partial file from an earlier run is resumed, unless the file
+// changed since then, see If-Range. The received file has to match the size the server reports or, if
+// the server does not report one, size. A file that does not match is discarded. It returns the content
+// type of the file.
+func downloadMedia(ctx context.Context, uri, part string, size int64) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", config.UserAgentString)
+
+	// the part is only resumed if it is known which version of the file it is a copy of
+	validator := part + ".validator"
+	var offset int64
+	if fi, err := os.Stat(part); err == nil && fi.Size() > 0 {
+		if v, err := os.ReadFile(validator); err == nil && len(v) > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", fi.Size()))
+			req.Header.Set("If-Range", string(v))
+			offset = fi.Size()
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	total := resp.ContentLength
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// a new file or a different version of it
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		offset = 0
+		if err := writeValidator(validator, resp.Header); err != nil {
+			return "", err
+		}
+	case http.StatusPartialContent:
+		start, t, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if offset == 0 || !ok || start != offset {
+			discardPart(part)
+			return "", podops.ErrVerificationFailed
+		}
+		total = t
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset > 0 {
+			// the last run might have stopped right before the rename
+			if _, t, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && t == offset {
+				os.Remove(validator)
+				return "", nil
+			}
+			// the part is broken, start over
+			discardPart(part)
+			return downloadMedia(ctx, uri, part, size)
+		}
+		fallthrough
+	default:
+		return "", fmt.Errorf(podops.MsgStatus, resp.StatusCode)
+	}
+
+	out, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, resp.Body)
+	if err != nil {
+		return "", err // keep the part, the next run continues from there
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+
+	if total < 0 && size > 0 {
+		total = size
+	}
+	if total >= 0 && offset+n != total {
+		discardPart(part)
+		return "", podops.ErrVerificationFailed
+	}
+
+	os.Remove(validator)
+	return resp.Header.Get("content-type"), nil
+}
+
+// writeValidator remembers the version of the file a part is a copy of. A weak etag can not be used
+// to resume a download, the modification time is used instead. Without either, the part can not be resumed.
+func writeValidator(path string, header http.Header) error {
+	v := header.Get("etag")
+	if v == "" || strings.HasPrefix(v, "W/") {
+		v = header.Get("last-modified")
+	}
+	if v == "" {
+		os.Remove(path)
+		return nil
+	}
+	return os.WriteFile(path, []byte(v), 0644)
+}
+
+// discardPart removes a part and its validator, the next download starts from scratch
+func discardPart(part string) {
+	os.Remove(part)
+	os.Remove(part + ".validator")
+}
+
+// parseContentRange returns the first byte and the total size of a content range,
+// e.g. 'bytes 100-199/200' or 'bytes */200'. The total is -1 if it is not known.
+func parseContentRange(s string) (int64, int64, bool) {
+	if !strings.HasPrefix(s, "bytes ") {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(s, "bytes "), "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	total := int64(-1)
+	if parts[1] != "*" {
+		t, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = t
+	}
+	if parts[0] == "*" {
+		return 0, total, true
+	}
+	start, err := strconv.ParseInt(strings.SplitN(parts[0], "-", 2)[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// fileETag returns the checksum of a file
+func fileETag(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return internal.CreateContentETag(f)
+}
+
+func readMediaManifest(root string) (map[string]*importedMedia, error) {
+	imported := make(map[string]*importedMedia)
+
+	data, err := os.ReadFile(filepath.Join(root, config.DefaultMediaManifestLocation))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return imported, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
+
+func writeMediaManifest(root string, imported map[string]*importedMedia) error {
+	data, err := json.MarshalIndent(imported, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(root, config.DefaultMediaManifestLocation)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
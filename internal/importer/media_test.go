@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal"
+)
+
+func TestRewriteAssets(t *testing.T) {
+	audio := bytes.Repeat([]byte("not really audio "), 1000)
+	image := []byte("not really an image")
+	ogg := bytes.Repeat([]byte("not really ogg "), 500)
+
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	ranges := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		ranges[r.URL.Path] = r.Header.Get("Range")
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/episode1.mp3":
+			w.Header().Set("content-type", "audio/mpeg")
+			w.Header().Set("etag", `"v1"`)
+			http.ServeContent(w, r, "", time.Now(), bytes.NewReader(audio))
+		case "/episode1.ogg":
+			w.Header().Set("content-type", "audio/ogg")
+			http.ServeContent(w, r, "", time.Now(), bytes.NewReader(ogg))
+		case "/cover.png":
+			w.Header().Set("content-type", "image/png")
+			http.ServeContent(w, r, "", time.Now(), bytes.NewReader(image))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	newShow := func() *podops.Show {
+		external := func(uri string) podops.AssetRef {
+			return podops.AssetRef{URI: srv.URL + uri, Rel: podops.ResourceTypeExternal}
+		}
+		e1 := &podops.Episode{Image: external("/cover.png"), Enclosure: external("/episode1.mp3"), AlternateEnclosures: []podops.AssetRef{external("/episode1.ogg")}}
+		e2 := &podops.Episode{Image: external("/cover.png"), Enclosure: external("/episode2.mp3")}
+		return &podops.Show{Image: external("/cover.png"), Episodes: podops.EpisodeList{e1, e2}}
+	}
+
+	root := t.TempDir()
+	location := filepath.Join(root, config.BuildLocation)
+	assert.NoError(t, os.MkdirAll(location, 0755))
+
+	// an interrupted download
+	part := filepath.Join(location, internal.CreateShortGUID(srv.URL+"/episode1.mp3")+".part")
+	assert.NoError(t, os.WriteFile(part, audio[:100], 0644))
+	assert.NoError(t, os.WriteFile(part+".validator", []byte(`"v1"`), 0644))
+
+	show := newShow()
+	report, err := RewriteAssets(context.TODO(), root, show)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(report.Assets))
+	assert.Equal(t, 1, report.Failed())
+	assert.Equal(t, int64(len(audio)+len(ogg)+len(image)), report.Bytes())
+	assert.Equal(t, 1, requests["/cover.png"])
+	assert.Equal(t, "bytes=100-", ranges["/episode1.mp3"])
+
+	// the enclosure was resumed and is complete
+	etag, _ := internal.CreateContentETag(bytes.NewReader(audio))
+	encl := show.Episodes[0].Enclosure
+	assert.Equal(t, podops.ResourceTypeImport, encl.Rel)
+	assert.Equal(t, etag, encl.ETag)
+	assert.Equal(t, len(audio), encl.Size)
+	assert.Equal(t, "audio/mpeg", encl.Type)
+	data, err := os.ReadFile(filepath.Join(location, encl.MediaReference()))
+	assert.NoError(t, err)
+	assert.Equal(t, audio, data)
+	_, err = os.Stat(part)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(part + ".validator")
+	assert.True(t, os.IsNotExist(err))
+
+	// alternate enclosures are imported like the enclosure
+	alternate := show.Episodes[0].AlternateEnclosures[0]
+	assert.Equal(t, podops.ResourceTypeImport, alternate.Rel)
+	assert.Equal(t, len(ogg), alternate.Size)
+	assert.Equal(t, "audio/ogg", alternate.Type)
+
+	// all references to the image are rewritten, the missing enclosure is not
+	assert.Equal(t, podops.ResourceTypeImport, show.Image.Rel)
+	assert.Equal(t, show.Image, show.Episodes[1].Image)
+	assert.Equal(t, podops.ResourceTypeExternal, show.Episodes[1].Enclosure.Rel)
+
+	// a second run verifies the files instead of downloading them again
+	show = newShow()
+	report, err = RewriteAssets(context.TODO(), root, show)
+	assert.NoError(t, err)
+	for _, a := range report.Assets {
+		assert.Equal(t, a.Error == "", a.Reused, a.URI)
+	}
+	assert.Equal(t, 1, requests["/cover.png"])
+
+	// broken files are downloaded again
+	assert.NoError(t, os.WriteFile(filepath.Join(location, show.Image.MediaReference()), []byte("broken"), 0644))
+	show = newShow()
+	_, err = RewriteAssets(context.TODO(), root, show)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requests["/cover.png"])
+	data, err = os.ReadFile(filepath.Join(location, show.Image.MediaReference()))
+	assert.NoError(t, err)
+	assert.Equal(t, image, data)
+}
+
+func TestDownloadMediaChanged(t *testing.T) {
+	audio := bytes.Repeat([]byte("not really audio "), 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("etag", `"v2"`)
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(audio))
+	}))
+	defer srv.Close()
+
+	// the part is a copy of an older version, it is not resumed
+	part := filepath.Join(t.TempDir(), "episode.part")
+	assert.NoError(t, os.WriteFile(part, []byte("an older version"), 0644))
+	assert.NoError(t, os.WriteFile(part+".validator", []byte(`"v1"`), 0644))
+
+	_, err := downloadMedia(context.TODO(), srv.URL, part, 0)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(part)
+	assert.NoError(t, err)
+	assert.Equal(t, audio, data)
+
+	// a part without validator is not resumed either
+	assert.NoError(t, os.WriteFile(part, []byte("unknown"), 0644))
+	os.Remove(part + ".validator")
+	_, err = downloadMedia(context.TODO(), srv.URL, part, int64(len(audio)))
+	assert.NoError(t, err)
+	data, err = os.ReadFile(part)
+	assert.NoError(t, err)
+	assert.Equal(t, audio, data)
+}
+
+func TestDownloadMediaSize(t *testing.T) {
+	audio := bytes.Repeat([]byte("not really audio "), 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // no content-length
+		w.Write(audio)
+	}))
+	defer srv.Close()
+
+	// the enclosure's length is checked if the server does not report one
+	part := filepath.Join(t.TempDir(), "episode.part")
+	_, err := downloadMedia(context.TODO(), srv.URL, part, int64(len(audio)+1))
+	assert.True(t, errors.Is(err, podops.ErrVerificationFailed))
+	_, err = os.Stat(part)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = downloadMedia(context.TODO(), srv.URL, part, int64(len(audio)))
+	assert.NoError(t, err)
+}
+
+func TestParseContentRange(t *testing.T) {
+	start, total, ok := parseContentRange("bytes 100-199/200")
+	assert.True(t, ok)
+	assert.Equal(t, int64(100), start)
+	assert.Equal(t, int64(200), total)
+
+	_, total, ok = parseContentRange("bytes */200")
+	assert.True(t, ok)
+	assert.Equal(t, int64(200), total)
+
+	_, total, ok = parseContentRange("bytes 0-99/*")
+	assert.True(t, ok)
+	assert.Equal(t, int64(-1), total)
+
+	_, _, ok = parseContentRange("items 0-1/2")
+	assert.False(t, ok)
+}
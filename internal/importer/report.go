@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"fmt"
+
+	"github.com/txsvc/stdlib/v2/validate"
+
+	"github.com/podops/podops"
+)
+
+type (
+	// ImportIssue is something in a feed that could not be imported as is
+	ImportIssue struct {
+		Episode string `json:"episode,omitempty"` // the title of the episode, empty if the issue is about the show
+		Reason  string `json:"reason"`
+	}
+
+	// ImportReport lists everything in a feed that could not be imported as is
+	ImportReport struct {
+		Skipped  []*ImportIssue `json:"skipped,omitempty"`  // items that were not imported
+		Repaired []*ImportIssue `json:"repaired,omitempty"` // fields that were missing or invalid and were fixed
+		Missing  []*ImportIssue `json:"missing,omitempty"`  // required fields that are still missing, see Validate
+	}
+)
+
+// String returns the issue as a message
+func (i *ImportIssue) String() string {
+	if i.Episode == "" {
+		return i.Reason
+	}
+	return fmt.Sprintf(podops.MsgImportEpisode, i.Episode, i.Reason)
+}
+
+// Clean returns true if the feed was imported as is
+func (r *ImportReport) Clean() bool {
+	return len(r.Skipped) == 0 && len(r.Repaired) == 0 && len(r.Missing) == 0
+}
+
+// Warnings returns all issues as messages
+func (r *ImportReport) Warnings() []string {
+	warnings := make([]string, 0, len(r.Skipped)+len(r.Repaired)+len(r.Missing))
+	for _, issues := range [][]*ImportIssue{r.Skipped, r.Repaired, r.Missing} {
+		for _, i := range issues {
+			warnings = append(warnings, i.String())
+		}
+	}
+	return warnings
+}
+
+// Err returns ErrImportNotStrict if the feed was not imported as is
+func (r *ImportReport) Err() error {
+	if r.Clean() {
+		return nil
+	}
+	return fmt.Errorf("%w: "+podops.MsgImportStrict, podops.ErrImportNotStrict, len(r.Skipped), len(r.Repaired), len(r.Missing))
+}
+
+func (r *ImportReport) skip(episode, reason string) {
+	r.Skipped = append(r.Skipped, &ImportIssue{Episode: episode, Reason: reason})
+}
+
+func (r *ImportReport) repair(episode, reason string) {
+	r.Repaired = append(r.Repaired, &ImportIssue{Episode: episode, Reason: reason})
+}
+
+func (r *ImportReport) missing(episode, reason string) {
+	r.Missing = append(r.Missing, &ImportIssue{Episode: episode, Reason: reason})
+}
+
+// validateImport adds the errors Validate finds in the show and its episodes
+func (r *ImportReport) validateImport(show *podops.Show) {
+	for _, issue := range show.Validate("show", validate.NewValidator()).Issues {
+		if issue.Type == validate.AssertionError {
+			r.missing("", issue.Txt)
+		}
+	}
+	for _, e := range show.Episodes {
+		for _, issue := range e.Validate("episode", validate.NewValidator()).Issues {
+			if issue.Type == validate.AssertionError {
+				r.missing(e.Description.Title, issue.Txt)
+			}
+		}
+	}
+}
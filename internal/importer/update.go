@@ -0,0 +1,362 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/loader"
+)
+
+type (
+	// UpdateResult reports what an update changed in a local repository
+	UpdateResult struct {
+		Show      bool             // true if show.yaml was updated
+		Added     []string         // paths of the new episodes
+		Updated   []string         // paths of the changed episodes
+		Unchanged int              // number of episodes without changes
+		Removed   []string         // paths of the episodes that are no longer in the upstream feed, they are kept
+		Media     *MigrationReport // the imported media files, with rewrite only
+	}
+
+	// importState is the upstream version of the resources at the time of the last import.
+	// It tells changes upstream apart from the user's local edits.
+	importState struct {
+		Feed     string                 `json:"feed"`
+		Show     interface{}            `json:"show"`
+		Episodes map[string]interface{} `json:"episodes"` // by GUID
+	}
+
+	// localEpisode is an episode in the local repository
+	localEpisode struct {
+		path    string
+		episode *podops.Episode
+	}
+)
+
+// SaveImportState remembers the imported version of a show and its episodes in the repository at root,
+// so that a later UpdateRepository can preserve the user's local edits.
+func SaveImportState(root, feedUrl string, show *podops.Show) error {
+	state := importState{
+		Feed:     feedUrl,
+		Episodes: make(map[string]interface{}),
+	}
+
+	var err error
+	if state.Show, err = toGeneric(showWithoutEpisodes(show)); err != nil {
+		return err
+	}
+	for _, e := range show.Episodes {
+		if state.Episodes[e.GUID()], err = toGeneric(e); err != nil {
+			return err
+		}
+	}
+
+	return writeImportState(root, &state)
+}
+
+// UpdateRepository re-imports the upstream feed into the repository at root. Episodes are matched
+// by their GUID and only new or changed episodes are written. Fields the user changed since the
+// last import keep the local value, as does the GUID and name of the show. Episodes that are no
+// longer in the feed are reported but not deleted. If feedUrl is empty, the feed of the last import is used.
+// If rewrite is true, the media files of new and changed episodes are imported, see RewriteAssets.
+func UpdateRepository(ctx context.Context, feedUrl, root string, rewrite bool) (*UpdateResult, error) {
+	state, err := readImportState(root)
+	if err != nil {
+		return nil, err
+	}
+	if feedUrl == "" {
+		feedUrl = state.Feed
+	}
+	if feedUrl == "" {
+		return nil, podops.ErrInvalidParameters
+	}
+
+	// the local show
+	showPath := loader.ShowPath(root)
+	rsrc, kind, _, err := loader.ReadResource(ctx, showPath)
+	if err != nil {
+		return nil, err
+	}
+	if kind != podops.ResourceShow {
+		return nil, podops.ErrBuildNoShow
+	}
+	show := rsrc.(*podops.Show)
+	if len(show.Episodes) > 0 {
+		return nil, podops.ErrImportSingleFile // only repositories with one file per episode can be updated
+	}
+
+	upstream, err := ImportPodcastFeed(feedUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	// the show's identity never changes, the GUID and name are known to the CDN
+	upstream.Metadata.GUID = show.Metadata.GUID
+	upstream.Metadata.Name = show.Metadata.Name
+	for _, e := range upstream.Episodes {
+		e.Metadata.Parent = show.Metadata.GUID
+	}
+
+	episodes, err := findEpisodes(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	result := UpdateResult{
+		Added:   make([]string, 0),
+		Updated: make([]string, 0),
+		Removed: make([]string, 0),
+	}
+
+	// update the show
+	merged, changed, err := mergeResource(state.Show, show, showWithoutEpisodes(upstream))
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		var s podops.Show
+		if err := fromGeneric(merged, &s); err != nil {
+			return nil, err
+		}
+		if err := loader.WriteResource(ctx, showPath, &s); err != nil {
+			return nil, err
+		}
+		result.Show = true
+	}
+
+	// find the new and changed episodes
+	seen := make(map[string]bool)
+	pending := make(map[string]*podops.Episode) // by path
+	changes := podops.Show{Episodes: make(podops.EpisodeList, 0)}
+	for _, e := range upstream.Episodes {
+		guid := e.GUID()
+		seen[guid] = true
+
+		local, ok := episodes[guid]
+		if !ok {
+			// a copy, the import state keeps the upstream references
+			var episode podops.Episode
+			if err := fromGeneric(e, &episode); err != nil {
+				return nil, err
+			}
+			// the paths of episodes added before in this run are taken as well
+			path := episodePath(root, e, func(path string) bool { return pending[path] != nil || fileExists(path) })
+			pending[path] = &episode
+			changes.Episodes = append(changes.Episodes, &episode)
+			result.Added = append(result.Added, path)
+			continue
+		}
+
+		merged, changed, err := mergeResource(state.Episodes[guid], local.episode, e)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			result.Unchanged++
+			continue
+		}
+		var episode podops.Episode
+		if err := fromGeneric(merged, &episode); err != nil {
+			return nil, err
+		}
+		pending[local.path] = &episode
+		changes.Episodes = append(changes.Episodes, &episode)
+		result.Updated = append(result.Updated, local.path)
+	}
+
+	// the media files are imported before the episodes reference them
+	if rewrite {
+		if result.Media, err = RewriteAssets(ctx, root, &changes); err != nil {
+			return nil, err
+		}
+	}
+	for path, e := range pending {
+		if err := loader.WriteResource(ctx, path, e); err != nil {
+			return nil, err
+		}
+	}
+
+	// episodes that were imported before but are gone upstream. Without a previous
+	// import, all local episodes are compared with the feed.
+	for guid, local := range episodes {
+		_, imported := state.Episodes[guid]
+		if !seen[guid] && (imported || len(state.Episodes) == 0) {
+			result.Removed = append(result.Removed, local.path)
+		}
+	}
+	sort.Strings(result.Removed)
+
+	if err := SaveImportState(root, feedUrl, upstream); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// mergeResource merges the upstream version of a resource into the local one. It returns
+// the result as generic resource and true if it differs from the local version.
+func mergeResource(base, local, upstream interface{}) (interface{}, bool, error) {
+	l, err := toGeneric(local)
+	if err != nil {
+		return nil, false, err
+	}
+	u, err := toGeneric(upstream)
+	if err != nil {
+		return nil, false, err
+	}
+
+	merged := merge(base, l, u)
+	return merged, !reflect.DeepEqual(merged, l), nil
+}
+
+// merge is a three-way merge of generic resources. Values that were changed locally since the
+// last import (base) are kept, everything else is taken from upstream. Maps are merged key by key,
+// all other values are compared as a whole. Without a base, all local values are kept.
+func merge(base, local, upstream interface{}) interface{} {
+	if reflect.DeepEqual(local, base) {
+		return upstream // not edited locally
+	}
+
+	lm, isMap := local.(map[string]interface{})
+	um, isUpstreamMap := upstream.(map[string]interface{})
+	if !isMap || !isUpstreamMap {
+		return local
+	}
+	bm, _ := base.(map[string]interface{})
+
+	merged := make(map[string]interface{})
+	for k := range lm {
+		if v := merge(bm[k], lm[k], um[k]); v != nil {
+			merged[k] = v
+		}
+	}
+	for k := range um {
+		if _, ok := lm[k]; ok {
+			continue
+		}
+		if v := merge(bm[k], nil, um[k]); v != nil {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// toGeneric converts a resource into maps, slices and values as in its JSON representation
+func toGeneric(rsrc interface{}) (interface{}, error) {
+	data, err := json.Marshal(rsrc)
+	if err != nil {
+		return nil, err
+	}
+
+	var g interface{}
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// fromGeneric converts a generic resource back into rsrc
+func fromGeneric(g interface{}, rsrc interface{}) error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, rsrc)
+}
+
+// showWithoutEpisodes returns a copy of the show without its episodes
+func showWithoutEpisodes(show *podops.Show) *podops.Show {
+	s := *show
+	s.Episodes = nil
+	return &s
+}
+
+// findEpisodes returns the episodes in the repository at root, by GUID
+func findEpisodes(ctx context.Context, root string) (map[string]*localEpisode, error) {
+	episodes := make(map[string]*localEpisode)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// skip the assets and config dirs
+			if path != root && (info.Name() == config.BuildLocation || info.Name() == filepath.Dir(config.DefaultConfigFileLocation)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !loader.IsResourceFile(path) {
+			return nil
+		}
+
+		resources, err := loader.ReadResources(ctx, path)
+		if err != nil {
+			return err
+		}
+		for _, r := range resources {
+			if r.Kind != podops.ResourceEpisode {
+				continue
+			}
+			if len(resources) > 1 {
+				return podops.ErrImportSingleFile // episodes are written back, one per file
+			}
+			episodes[r.GUID] = &localEpisode{path: path, episode: r.Resource.(*podops.Episode)}
+		}
+		return nil
+	})
+
+	return episodes, err
+}
+
+// episodePath returns the path of a new episode. The GUID is added if the default name is taken.
+func episodePath(root string, e *podops.Episode, taken func(path string) bool) string {
+	path := filepath.Join(root, fmt.Sprintf("episode-S%dE%d.yaml", e.SeasonAsInt(), e.EpisodeAsInt()))
+	if !taken(path) {
+		return path
+	}
+	return filepath.Join(root, fmt.Sprintf("episode-S%dE%d-%s.yaml", e.SeasonAsInt(), e.EpisodeAsInt(), e.GUID()))
+}
+
+// fileExists returns true if there is a file at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func readImportState(root string) (*importState, error) {
+	state := importState{
+		Episodes: make(map[string]interface{}),
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, config.DefaultImportStateLocation))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &state, nil // never imported
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func writeImportState(root string, state *importState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(root, config.DefaultImportStateLocation)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
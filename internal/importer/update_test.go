@@ -0,0 +1,198 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/internal/loader"
+)
+
+const (
+	testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+<channel>
+	<title>Update Podcast</title>
+	<link>https://example.com/updatepodcast</link>
+	<description>%s</description>
+	<author>jane@example.com (Jane Doe)</author>
+	<itunes:author>Jane Doe</itunes:author>
+	<itunes:owner><itunes:name>Jane Doe</itunes:name><itunes:email>jane@example.com</itunes:email></itunes:owner>
+	<itunes:image href="https://example.com/cover.png"/>
+	%s
+</channel>
+</rss>`
+	testItem = `<item>
+		<title>Episode %d</title>
+		<guid>https://example.com/episode/%d</guid>
+		<description>%s</description>
+		<pubDate>Mon, 0%d Mar 2021 10:00:00 +0000</pubDate>
+		<enclosure url="https://example.com/episode%d.mp3" length="1000" type="audio/mpeg"/>
+		<itunes:episode>%d</itunes:episode>
+	</item>`
+)
+
+func feedWith(description string, items map[int]string) string {
+	xml := ""
+	for i := 1; i <= 9; i++ {
+		if d, ok := items[i]; ok {
+			xml += fmt.Sprintf(testItem, i, i, d, i, i, i)
+		}
+	}
+	return fmt.Sprintf(testFeed, description, xml)
+}
+
+func TestUpdateRepository(t *testing.T) {
+	feed := feedWith("The show", map[int]string{1: "first", 2: "second", 3: "third"})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/rss+xml")
+		fmt.Fprint(w, feed)
+	}))
+	defer srv.Close()
+
+	ctx := context.TODO()
+	root := t.TempDir()
+
+	// the initial import
+	show, err := ImportPodcastFeed(srv.URL)
+	assert.NoError(t, err)
+	assert.NoError(t, SaveImportState(root, srv.URL, show))
+	for _, e := range show.Episodes {
+		assert.NoError(t, loader.WriteResource(ctx, filepath.Join(root, fmt.Sprintf("episode-S1E%d.yaml", e.EpisodeAsInt())), e))
+	}
+	show.Episodes = nil
+	assert.NoError(t, loader.WriteResource(ctx, filepath.Join(root, "show.yaml"), show))
+
+	// nothing changed
+	result, err := UpdateRepository(ctx, "", root, false)
+	assert.NoError(t, err)
+	assert.False(t, result.Show)
+	assert.Empty(t, result.Added)
+	assert.Empty(t, result.Updated)
+	assert.Empty(t, result.Removed)
+	assert.Equal(t, 3, result.Unchanged)
+
+	// edit the local copy of episode 1
+	path1 := filepath.Join(root, "episode-S1E1.yaml")
+	rsrc, _, _, err := loader.ReadResource(ctx, path1)
+	assert.NoError(t, err)
+	e1 := rsrc.(*podops.Episode)
+	e1.Description.Title = "A better title"
+	assert.NoError(t, loader.WriteResource(ctx, path1, e1))
+
+	// upstream changes episode 1 and 2, removes 3 and adds 4
+	feed = feedWith("The new show", map[int]string{1: "first, again", 2: "second, again", 4: "fourth"})
+
+	result, err = UpdateRepository(ctx, "", root, false)
+	assert.NoError(t, err)
+	assert.True(t, result.Show)
+	assert.Equal(t, []string{filepath.Join(root, "episode-S1E4.yaml")}, result.Added)
+	assert.ElementsMatch(t, []string{path1, filepath.Join(root, "episode-S1E2.yaml")}, result.Updated)
+	assert.Equal(t, []string{filepath.Join(root, "episode-S1E3.yaml")}, result.Removed)
+	assert.Equal(t, 0, result.Unchanged)
+
+	// the local edit is kept, the upstream change is applied
+	rsrc, _, _, err = loader.ReadResource(ctx, path1)
+	assert.NoError(t, err)
+	e1 = rsrc.(*podops.Episode)
+	assert.Equal(t, "A better title", e1.Description.Title)
+	assert.Equal(t, "first, again", e1.Description.Summary)
+
+	// the show keeps its identity
+	rsrc, _, _, err = loader.ReadResource(ctx, filepath.Join(root, "show.yaml"))
+	assert.NoError(t, err)
+	s := rsrc.(*podops.Show)
+	assert.Equal(t, show.Metadata.GUID, s.Metadata.GUID)
+	assert.Equal(t, "The new show", s.Description.Summary)
+
+	// removed episodes are kept
+	_, err = os.Stat(filepath.Join(root, "episode-S1E3.yaml"))
+	assert.NoError(t, err)
+}
+
+func TestUpdateRepositoryRewrite(t *testing.T) {
+	var srvURL string
+	feed := feedWith("The show", map[int]string{1: "first"})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed":
+			w.Header().Set("content-type", "application/rss+xml")
+			fmt.Fprint(w, strings.ReplaceAll(feed, "https://example.com", srvURL))
+		case "/cover.png":
+			w.Header().Set("content-type", "image/png")
+			fmt.Fprint(w, "not really an image")
+		default:
+			w.Header().Set("content-type", "audio/mpeg")
+			fmt.Fprint(w, "not really audio")
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	ctx := context.TODO()
+	root := t.TempDir()
+
+	show, err := ImportPodcastFeed(srv.URL + "/feed")
+	assert.NoError(t, err)
+	_, err = WriteRepository(ctx, root, srv.URL+"/feed", show, false, false)
+	assert.NoError(t, err)
+
+	// upstream adds episode 2, only its media files are imported
+	feed = feedWith("The show", map[int]string{1: "first", 2: "second"})
+
+	result, err := UpdateRepository(ctx, "", root, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result.Added))
+	assert.NotNil(t, result.Media)
+	assert.Equal(t, 0, result.Media.Failed())
+	for _, a := range result.Media.Assets {
+		assert.NotEqual(t, srv.URL+"/episode1.mp3", a.URI)
+	}
+
+	rsrc, _, _, err := loader.ReadResource(ctx, result.Added[0])
+	assert.NoError(t, err)
+	assert.Equal(t, podops.ResourceTypeImport, rsrc.(*podops.Episode).Enclosure.Rel)
+
+	// the import state keeps the upstream references
+	state, err := readImportState(root)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(state.Episodes))
+	for _, e := range state.Episodes {
+		enclosure := e.(map[string]interface{})["enclosure"].(map[string]interface{})
+		assert.Equal(t, podops.ResourceTypeExternal, enclosure["rel"])
+	}
+}
+
+func TestUpdateSingleFile(t *testing.T) {
+	ctx := context.TODO()
+	root := t.TempDir()
+
+	show, _, err := ImportPodcast("testdata/clean.xml", false)
+	assert.NoError(t, err)
+	_, err = WriteRepository(ctx, root, "testdata/clean.xml", show, true, false)
+	assert.NoError(t, err)
+
+	_, err = UpdateRepository(ctx, "", root, false)
+	assert.Equal(t, podops.ErrImportSingleFile, err)
+}
+
+func TestMerge(t *testing.T) {
+	base := map[string]interface{}{"a": "1", "b": "2", "c": map[string]interface{}{"d": "3", "e": "4"}}
+	local := map[string]interface{}{"a": "1", "b": "local", "c": map[string]interface{}{"d": "3", "e": "local"}}
+	upstream := map[string]interface{}{"a": "up", "b": "up", "c": map[string]interface{}{"d": "up", "e": "up"}, "f": "up"}
+
+	merged := merge(base, local, upstream)
+	assert.Equal(t, map[string]interface{}{"a": "up", "b": "local", "c": map[string]interface{}{"d": "up", "e": "local"}, "f": "up"}, merged)
+
+	// without a base, local values win
+	merged = merge(nil, local, upstream)
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": "local", "c": map[string]interface{}{"d": "3", "e": "local"}, "f": "up"}, merged)
+}
@@ -1,6 +1,7 @@
 package importer
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/mmcdole/gofeed"
@@ -48,3 +49,50 @@ func stringExpect(s, exp, def string) string {
 func formatName(s string) string {
 	return strings.ToLower(strings.ReplaceAll(strings.Trim(s, " "), " ", "_"))
 }
+
+// convLanguage converts the language of a feed into the form used by resources, e.g. 'en-us' into 'en_US'
+func convLanguage(language string) string {
+	l := strings.ReplaceAll(strings.TrimSpace(language), "-", "_")
+	if l == "" {
+		return "en_US"
+	}
+
+	parts := strings.SplitN(l, "_", 2)
+	if len(parts) == 2 {
+		return strings.ToLower(parts[0]) + "_" + strings.ToUpper(parts[1])
+	}
+	if strings.EqualFold(l, "en") {
+		return "en_US" // there is no 'en_EN'
+	}
+	return strings.ToLower(l)
+}
+
+// isURL returns true if the source of a feed is a URL and not a local file
+func isURL(source string) bool {
+	return strings.Contains(source, "://")
+}
+
+// convExtensions converts the elements of a namespace, sorted by name
+func convExtensions(extensions map[string][]ext.Extension) []podops.PodcastTag {
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tags []podops.PodcastTag
+	for _, name := range names {
+		for _, e := range extensions[name] {
+			tag := podops.PodcastTag{
+				Name:     name,
+				Value:    strings.TrimSpace(e.Value),
+				Children: convExtensions(e.Children),
+			}
+			if len(e.Attrs) > 0 {
+				tag.Attrs = e.Attrs
+			}
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -11,3 +11,11 @@ func TestStringExpect(t *testing.T) {
 	assert.Equal(t, "other", stringExpect("abc", "False", "other"))
 	assert.Equal(t, "other", stringExpect("", "False", "other"))
 }
+
+func TestConvLanguage(t *testing.T) {
+	assert.Equal(t, "en_US", convLanguage(""))
+	assert.Equal(t, "en_US", convLanguage("en"))
+	assert.Equal(t, "en_US", convLanguage("en-us"))
+	assert.Equal(t, "de_DE", convLanguage("de-DE"))
+	assert.Equal(t, "de", convLanguage("DE"))
+}
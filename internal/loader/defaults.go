@@ -0,0 +1,229 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+)
+
+// IsResourceFile returns true if the file at path holds resources. The episode defaults are not a resource.
+func IsResourceFile(path string) bool {
+	if Format(path) == "" {
+		return false
+	}
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name)) != strings.TrimSuffix(config.DefaultEpisodeDefaultsName, filepath.Ext(config.DefaultEpisodeDefaultsName))
+}
+
+// ReadDefaults returns the default values of the episodes in the repository at root. They are taken
+// from the show's 'defaults' and from defaults.yaml (or .json, .toml), which has priority.
+func ReadDefaults(ctx context.Context, root string, show *podops.Show) (*podops.Episode, error) {
+	defaults := &podops.Episode{}
+
+	name := strings.TrimSuffix(config.DefaultEpisodeDefaultsName, filepath.Ext(config.DefaultEpisodeDefaultsName))
+	for _, ext := range []string{".yaml", ".yml", ".json", ".toml"} {
+		path := filepath.Join(root, name+ext)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if defaults, err = decodeDefaults(path, data, Format(path)); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	if show != nil && show.Defaults != nil {
+		if err := fill(reflect.ValueOf(defaults).Elem(), reflect.ValueOf(show.Defaults).Elem(), nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// an episode's kind and identity are never a default
+	defaults.APIVersion = ""
+	defaults.Kind = ""
+	defaults.Metadata.Name = ""
+	defaults.Metadata.GUID = ""
+	defaults.Metadata.FeedGUID = ""
+
+	return defaults, nil
+}
+
+// ApplyDefaults sets all attributes of e that are not set to their default. Labels are set one by one,
+// images, enclosures and links as a whole. Text attributes of the defaults are templates, e.g.
+// 'https://example.com/episodes/{{.Metadata.Name}}', executed with the episode.
+func ApplyDefaults(e, defaults *podops.Episode) error {
+	if defaults == nil {
+		return nil
+	}
+
+	templates := make([]reflect.Value, 0)
+	if err := fill(reflect.ValueOf(e).Elem(), reflect.ValueOf(defaults).Elem(), &templates); err != nil {
+		return err
+	}
+
+	// the templates see the episode with all defaults
+	for _, v := range templates {
+		t, err := template.New(e.Metadata.Name).Option("missingkey=error").Parse(v.String())
+		if err != nil {
+			return fmt.Errorf(podops.MsgResourceInvalidTemplate, e.Metadata.Name, err)
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, e); err != nil {
+			return fmt.Errorf(podops.MsgResourceInvalidTemplate, e.Metadata.Name, err)
+		}
+		v.SetString(buf.String())
+	}
+	return nil
+}
+
+// OmitDefaults clears all attributes of e that are set by defaults, so they keep following the defaults
+// when these change. It is the counterpart of ApplyDefaults, e.g. for new episodes.
+func OmitDefaults(e, defaults *podops.Episode) {
+	if defaults == nil {
+		return
+	}
+	omit(reflect.ValueOf(e).Elem(), reflect.ValueOf(defaults).Elem())
+}
+
+// ReadEpisodes returns all episodes in the repository at root, with their defaults applied
+func ReadEpisodes(ctx context.Context, root string, show *podops.Show) (podops.EpisodeList, error) {
+	defaults, err := ReadDefaults(ctx, root, show)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := make(podops.EpisodeList, 0)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// skip the assets dir and other hidden dirs
+		if info.IsDir() && path != root && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || !IsResourceFile(path) {
+			return nil
+		}
+
+		// a file can hold several resources, e.g. a show and its episodes
+		resources, err := ReadResources(ctx, path)
+		if err != nil {
+			return err
+		}
+		for _, r := range resources {
+			if r.Kind != podops.ResourceEpisode {
+				continue
+			}
+			episode := r.Resource.(*podops.Episode)
+			if err := ApplyDefaults(episode, defaults); err != nil {
+				return err
+			}
+			episodes = append(episodes, episode)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return episodes, nil
+}
+
+// fill sets the zero values in dst to the ones in src. Strings with a template are collected in templates.
+func fill(dst, src reflect.Value, templates *[]reflect.Value) error {
+	switch dst.Kind() {
+	case reflect.Struct:
+		if dst.Type() == reflect.TypeOf(podops.AssetRef{}) {
+			// references are not mixed
+			if dst.FieldByName("URI").String() == "" {
+				dst.Set(src)
+				collect(dst.FieldByName("URI"), templates)
+			}
+			return nil
+		}
+		for i := 0; i < dst.NumField(); i++ {
+			if err := fill(dst.Field(i), src.Field(i), templates); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if src.Len() == 0 {
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			if !dst.MapIndex(iter.Key()).IsValid() {
+				dst.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+
+	case reflect.Slice, reflect.Ptr:
+		if dst.IsNil() && !src.IsNil() {
+			dst.Set(src)
+		}
+
+	case reflect.String:
+		if dst.String() == "" && src.String() != "" {
+			dst.Set(src)
+			collect(dst, templates)
+		}
+
+	default:
+		if dst.IsZero() {
+			dst.Set(src)
+		}
+	}
+	return nil
+}
+
+// omit sets the values in dst to their zero value if they are set in src, see fill
+func omit(dst, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Struct:
+		if dst.Type() == reflect.TypeOf(podops.AssetRef{}) {
+			if src.FieldByName("URI").String() != "" {
+				dst.Set(reflect.Zero(dst.Type()))
+			}
+			return
+		}
+		for i := 0; i < dst.NumField(); i++ {
+			omit(dst.Field(i), src.Field(i))
+		}
+
+	case reflect.Map:
+		if dst.IsNil() {
+			return
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(iter.Key(), reflect.Value{})
+		}
+
+	default:
+		if !src.IsZero() {
+			dst.Set(reflect.Zero(dst.Type()))
+		}
+	}
+}
+
+func collect(v reflect.Value, templates *[]reflect.Value) {
+	if templates != nil && strings.Contains(v.String(), "{{") {
+		*templates = append(*templates, v)
+	}
+}
@@ -0,0 +1,133 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+)
+
+const (
+	defaultsShowYAML = `apiVersion: v1
+kind: show
+metadata:
+  name: defaultspodcast
+  guid: a7c94297acfc
+description:
+  title: Show With Defaults
+defaults:
+  metadata:
+    labels:
+      explicit: "True"
+      season: "2"
+  image:
+    uri: show-episode.png
+    rel: local
+`
+	defaultsYAML = `metadata:
+  labels:
+    explicit: "False"
+description:
+  link:
+    uri: https://example.com/episodes/{{.Metadata.Name}}
+    rel: external
+  summary: "{{.Description.Title}}, season {{index .Metadata.Labels \"season\"}}"
+`
+	defaultsEpisodeYAML = `apiVersion: v1
+kind: episode
+metadata:
+  name: firstepisode
+  guid: 86124f7f9cf4
+  parent: a7c94297acfc
+  labels:
+    episode: "1"
+description:
+  title: First Episode
+---
+apiVersion: v1
+kind: episode
+metadata:
+  name: secondepisode
+  guid: 95235f8f0ad5
+  parent: a7c94297acfc
+  labels:
+    episode: "2"
+    season: "3"
+description:
+  title: Second Episode
+  summary: Not from the defaults
+image:
+  uri: second.png
+`
+)
+
+func TestReadEpisodesWithDefaults(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "show.yaml"), []byte(defaultsShowYAML), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "defaults.yaml"), []byte(defaultsYAML), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "episodes.yaml"), []byte(defaultsEpisodeYAML), 0644))
+	// other tools' files are not resources
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "renovate.json"), []byte(`{"extends": ["config:base"]}`), 0644))
+
+	r, _, _, err := ReadResource(context.TODO(), ShowPath(root))
+	assert.NoError(t, err)
+
+	episodes, err := ReadEpisodes(context.TODO(), root, r.(*podops.Show))
+	assert.NoError(t, err)
+	if !assert.Equal(t, 2, len(episodes)) {
+		return
+	}
+
+	first := episodes[0]
+	assert.Equal(t, "False", first.Metadata.Labels[podops.LabelExplicit]) // defaults.yaml wins over the show
+	assert.Equal(t, "2", first.Metadata.Labels[podops.LabelSeason])
+	assert.Equal(t, "1", first.Metadata.Labels[podops.LabelEpisode])
+	assert.Equal(t, "https://example.com/episodes/firstepisode", first.Description.Link.URI)
+	assert.Equal(t, "First Episode, season 2", first.Description.Summary)
+	assert.Equal(t, "show-episode.png", first.Image.URI)
+
+	second := episodes[1]
+	assert.Equal(t, "3", second.Metadata.Labels[podops.LabelSeason])
+	assert.Equal(t, "Not from the defaults", second.Description.Summary)
+	assert.Equal(t, "second.png", second.Image.URI)
+	assert.Empty(t, second.Image.Rel) // references are not mixed
+}
+
+func TestApplyDefaultsInvalidTemplate(t *testing.T) {
+	e := &podops.Episode{Metadata: podops.Metadata{Name: "firstepisode"}}
+	defaults := &podops.Episode{Description: podops.EpisodeDescription{Summary: "{{.Unknown}}"}}
+
+	assert.Error(t, ApplyDefaults(e, defaults))
+	assert.NoError(t, ApplyDefaults(e, nil))
+}
+
+func TestOmitDefaults(t *testing.T) {
+	e := podops.DefaultEpisode("firstepisode", "minimalpodcast", "a1b2c3d4e5f6", "aaa94297acfc", "https://podops.dev", "https://cdn.podops.dev")
+	defaults := &podops.Episode{
+		Metadata:    podops.Metadata{Labels: map[string]string{podops.LabelSeason: "2"}},
+		Description: podops.EpisodeDescription{Link: podops.AssetRef{URI: "https://example.com/episodes/{{.Metadata.Name}}"}},
+	}
+
+	OmitDefaults(e, defaults)
+	assert.Equal(t, "firstepisode", e.Metadata.Name)
+	assert.NotContains(t, e.Metadata.Labels, podops.LabelSeason)
+	assert.Contains(t, e.Metadata.Labels, podops.LabelEpisode)
+	assert.Empty(t, e.Description.Link.URI)
+	assert.NotEmpty(t, e.Description.Summary)
+
+	// the defaults fill in what was left out
+	assert.NoError(t, ApplyDefaults(e, defaults))
+	assert.Equal(t, "2", e.Metadata.Labels[podops.LabelSeason])
+	assert.Equal(t, "https://example.com/episodes/firstepisode", e.Description.Link.URI)
+}
+
+func TestIsResourceFile(t *testing.T) {
+	assert.True(t, IsResourceFile("show.yaml"))
+	assert.True(t, IsResourceFile("episodes/first.toml"))
+	assert.False(t, IsResourceFile("defaults.json"))
+	assert.False(t, IsResourceFile("cover.png"))
+}
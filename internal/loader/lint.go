@@ -0,0 +1,285 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/txsvc/stdlib/v2/validate"
+
+	"github.com/podops/podops"
+)
+
+const (
+	// LevelError makes a resource invalid
+	LevelError = "error"
+	// LevelWarning is a potential problem
+	LevelWarning = "warning"
+
+	// RuleSyntax the file can not be parsed
+	RuleSyntax = "syntax"
+	// RuleUnknownField an attribute does not exist, e.g. because of a typo
+	RuleUnknownField = "unknown-field"
+	// RuleInvalidType an attribute has a value of the wrong type
+	RuleInvalidType = "invalid-type"
+	// RuleInvalidKind the kind of a resource is missing or unknown
+	RuleInvalidKind = "invalid-kind"
+	// RuleInvalidVersion the apiVersion of a resource is unknown and can not be migrated
+	RuleInvalidVersion = "invalid-version"
+	// RuleInvalidDefault an episode default is not a valid template
+	RuleInvalidDefault = "invalid-default"
+	// RuleValidation a resource is not valid, see Validate
+	RuleValidation = "validation"
+)
+
+type (
+	// Issue is a problem in a resource file
+	Issue struct {
+		File       string `json:"file"`
+		Line       int    `json:"line,omitempty"`   // 0 if unknown, e.g. in TOML
+		Column     int    `json:"column,omitempty"` // 0 if unknown
+		Level      string `json:"level"`
+		Rule       string `json:"rule"`
+		Message    string `json:"message"`
+		Suggestion string `json:"suggestion,omitempty"`
+	}
+
+	// SchemaError is returned for resources that do not match their schema
+	SchemaError struct {
+		Issues []*Issue
+	}
+
+	// LintReport lists the checked files and all issues found in them
+	LintReport struct {
+		Files  []string `json:"files"`
+		Issues []*Issue `json:"issues"`
+	}
+)
+
+var (
+	yamlLine = regexp.MustCompile(`line (\d+)`)
+)
+
+// String returns the issue as 'file:line:column: message'
+func (i *Issue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, i.Line)
+		if i.Column > 0 {
+			location = fmt.Sprintf("%s:%d", location, i.Column)
+		}
+	}
+	msg := i.Message
+	if i.Suggestion != "" {
+		msg = fmt.Sprintf("%s, %s", msg, i.Suggestion)
+	}
+	if location == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", strings.TrimPrefix(location, ":"), msg)
+}
+
+// Error returns all issues, one per line
+func (e *SchemaError) Error() string {
+	lines := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		lines[i] = issue.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Errors returns the number of issues with level error
+func (r *LintReport) Errors() int {
+	return r.count(LevelError)
+}
+
+// Warnings returns the number of issues with level warning
+func (r *LintReport) Warnings() int {
+	return r.count(LevelWarning)
+}
+
+func (r *LintReport) count(level string) int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Level == level {
+			n++
+		}
+	}
+	return n
+}
+
+// Lint checks all resources in the repository at root against their schema and validates them,
+// with the episode defaults applied. Files are reported relative to root, files without resources
+// are not reported.
+func Lint(ctx context.Context, root string) (*LintReport, error) {
+	report := &LintReport{Files: make([]string, 0), Issues: make([]*Issue, 0)}
+	resources := make(map[*Resource]string) // resource -> file
+	order := make([]*Resource, 0)
+	var show *podops.Show
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// skip the assets dir and other hidden dirs
+		if info.IsDir() && path != root && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || Format(path) == "" {
+			return nil
+		}
+
+		file, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if !IsResourceFile(path) {
+			if filepath.Dir(path) != filepath.Clean(root) {
+				return nil // only the defaults at root are used, see ReadDefaults
+			}
+			report.Files = append(report.Files, file)
+			_, err := decodeDefaults(file, data, Format(path))
+			return report.addError(err)
+		}
+
+		rs, err := decodeResources(file, data, Format(path))
+		if err != nil {
+			report.Files = append(report.Files, file)
+			return report.addError(err)
+		}
+		if len(rs) > 0 {
+			report.Files = append(report.Files, file)
+		}
+		for _, r := range rs {
+			if s, ok := r.Resource.(*podops.Show); ok && show == nil {
+				show = s
+			}
+			resources[r] = file
+			order = append(order, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defaults, err := ReadDefaults(ctx, root, show)
+	if err != nil {
+		var se *SchemaError
+		if !errors.As(err, &se) {
+			return nil, err
+		}
+		defaults = nil // reported above
+	}
+
+	for _, r := range order {
+		v := validate.NewValidator()
+		file := resources[r]
+
+		switch resource := r.Resource.(type) {
+		case *podops.Show:
+			resource.Validate("show."+r.GUID, v)
+		case *podops.Episode:
+			if err := ApplyDefaults(resource, defaults); err != nil {
+				report.Issues = append(report.Issues, &Issue{File: file, Line: r.Line, Level: LevelError, Rule: RuleInvalidDefault, Message: err.Error()})
+				continue
+			}
+			resource.Validate("episode."+r.GUID, v)
+
+			if show != nil && resource.Metadata.Parent != "" && resource.Metadata.Parent != show.GUID() {
+				v.AddError(fmt.Sprintf(podops.MsgResourceInvalidReference, resource.Metadata.Parent))
+			}
+		}
+
+		for _, a := range v.Issues {
+			level := LevelWarning
+			if a.Type == validate.AssertionError {
+				level = LevelError
+			}
+			report.Issues = append(report.Issues, &Issue{File: file, Line: r.Line, Level: level, Rule: RuleValidation, Message: a.Txt})
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// addError adds the issues of a SchemaError, all other errors are returned
+func (r *LintReport) addError(err error) error {
+	var se *SchemaError
+	if errors.As(err, &se) {
+		r.Issues = append(r.Issues, se.Issues...)
+		return nil
+	}
+	return err
+}
+
+// decodeDefaults checks the episode defaults in data against their schema before unmarshalling them
+func decodeDefaults(path string, data []byte, format string) (*podops.Episode, error) {
+	var defaults podops.Episode
+
+	docs, err := splitDocuments(data, format)
+	if err != nil {
+		return nil, syntaxError(path, data, err)
+	}
+	if len(docs) == 0 {
+		return &defaults, nil
+	}
+	if issues := checkDocument(path, docs[0], kindDefaults, format); len(issues) > 0 {
+		return nil, &SchemaError{Issues: issues}
+	}
+
+	if err := unmarshalers[format](docs[0].data, &defaults); err != nil {
+		return nil, err
+	}
+	return &defaults, nil
+}
+
+// syntaxError returns a SchemaError with the position of a parser error, if it is known
+func syntaxError(path string, data []byte, err error) error {
+	issue := &Issue{File: path, Level: LevelError, Rule: RuleSyntax, Message: err.Error()}
+
+	var jsonErr *json.SyntaxError
+	var tomlErr toml.ParseError
+	if errors.As(err, &jsonErr) {
+		issue.Line, issue.Column = position(data, int(jsonErr.Offset))
+	} else if errors.As(err, &tomlErr) {
+		issue.Line = tomlErr.Position.Line
+		issue.Message = tomlErr.Message
+	} else if m := yamlLine.FindStringSubmatch(err.Error()); m != nil {
+		issue.Line, _ = strconv.Atoi(m[1])
+	}
+
+	return &SchemaError{Issues: []*Issue{issue}}
+}
+
+// position returns the line and column of offset in data
+func position(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line := strings.Count(string(data[:offset]), "\n") + 1
+	column := offset - strings.LastIndex(string(data[:offset]), "\n")
+	return line, column
+}
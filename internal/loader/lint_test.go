@@ -0,0 +1,121 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+)
+
+const (
+	typoEpisodeYAML = `apiVersion: v1
+kind: episode
+metadata:
+  name: firstepisode
+  guid: 86124f7f9cf4
+description:
+  title: First Episode
+  episodText: The first episode
+  duration: one hour
+enclosre:
+  uri: episode.mp3
+  rel: local
+`
+	typoEpisodeJSON = `{"apiVersion": "v1", "kind": "episode", "metadata": {"name": "firstepisode", "guid": "86124f7f9cf4"}}
+{
+  "apiVersion": "v1",
+  "kind": "episde",
+  "metadata": {"name": "secondepisode", "guid": "95235f8f0ad5", "labels": {"season": 1}}
+}`
+)
+
+func TestStrictResources(t *testing.T) {
+	_, err := UnmarshalResources([]byte(typoEpisodeYAML), FormatYAML)
+
+	var se *SchemaError
+	if assert.True(t, errors.As(err, &se)) && assert.Equal(t, 3, len(se.Issues)) {
+		assert.Equal(t, RuleUnknownField, se.Issues[0].Rule)
+		assert.Equal(t, 8, se.Issues[0].Line)
+		assert.Equal(t, 3, se.Issues[0].Column)
+		assert.Equal(t, "did you mean 'episodeText'?", se.Issues[0].Suggestion)
+
+		assert.Equal(t, RuleInvalidType, se.Issues[1].Rule)
+		assert.Equal(t, "'description.duration' must be a number", se.Issues[1].Message)
+
+		assert.Equal(t, "10:1: unknown field 'enclosre', did you mean 'enclosure'?", se.Issues[2].String())
+	}
+
+	_, err = UnmarshalResources([]byte(typoEpisodeJSON), FormatJSON)
+	if assert.True(t, errors.As(err, &se)) && assert.Equal(t, 1, len(se.Issues)) {
+		assert.Equal(t, RuleInvalidKind, se.Issues[0].Rule)
+		assert.Equal(t, 4, se.Issues[0].Line)
+		assert.Equal(t, "did you mean 'episode'?", se.Issues[0].Suggestion)
+	}
+
+	_, err = UnmarshalResources([]byte(`{"apiVersion": "v1", "kind": "episode", "metadata": {"labels": {"season": 1}}}`), FormatJSON)
+	if assert.True(t, errors.As(err, &se)) && assert.Equal(t, 1, len(se.Issues)) {
+		assert.Equal(t, "'metadata.labels.season' must be a text", se.Issues[0].Message)
+		assert.Equal(t, 1, se.Issues[0].Line)
+		assert.Equal(t, 75, se.Issues[0].Column)
+	}
+
+	_, err = UnmarshalResources([]byte("apiVersion: v1\nkind: episode\n  metadata: [\n"), FormatYAML)
+	if assert.True(t, errors.As(err, &se)) {
+		assert.Equal(t, RuleSyntax, se.Issues[0].Rule)
+		assert.Equal(t, 3, se.Issues[0].Line)
+	}
+}
+
+func TestLint(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "show.yaml"), []byte(defaultsShowYAML), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "defaults.yaml"), []byte("image:\n  url: episode.png\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "first.yaml"), []byte(typoEpisodeYAML), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "renovate.json"), []byte(`{"extends": ["config:base"]}`), 0644))
+
+	report, err := Lint(context.TODO(), root)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"defaults.yaml", "first.yaml", "show.yaml"}, report.Files)
+	if assert.NotEmpty(t, report.Issues) {
+		assert.Equal(t, "defaults.yaml:2:3: unknown field 'image.url', did you mean 'uri'?", report.Issues[0].String())
+	}
+	assert.Equal(t, 4, report.Errors()-countRule(report, RuleValidation))
+
+	data, err := report.SARIF()
+	assert.NoError(t, err)
+	var sarif map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &sarif))
+	assert.Equal(t, "2.1.0", sarif["version"])
+
+	data, err = report.JUnit()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `<testsuite name="po lint" tests="3" failures="3">`)
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema(podops.ResourceEpisode)
+	assert.NoError(t, err)
+	assert.Equal(t, "#/definitions/Episode", schema["$ref"])
+
+	definitions := schema["definitions"].(map[string]interface{})
+	assert.Contains(t, definitions, "PodcastTag")
+
+	_, err = Schema("defaults")
+	assert.Error(t, err)
+}
+
+func countRule(report *LintReport, rule string) int {
+	n := 0
+	for _, i := range report.Issues {
+		if i.Rule == rule && i.Level == LevelError {
+			n++
+		}
+	}
+	return n
+}
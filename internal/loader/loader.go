@@ -1,40 +1,124 @@
 package loader
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 
 	"github.com/podops/podops"
+	"github.com/podops/podops/config"
 )
 
 const (
 	filePerm fs.FileMode = 0644
 	dirPerm  fs.FileMode = 0644
+
+	// FormatYAML is the default format of resources, a file can hold several documents
+	FormatYAML = "yaml"
+	// FormatJSON resources, a file can hold several objects or an array of objects
+	FormatJSON = "json"
+	// FormatTOML resources, a file holds one resource
+	FormatTOML = "toml"
 )
 
 type (
 	// ResourceLoaderFunc implements loading of resources
 	ResourceLoaderFunc func(data []byte) (interface{}, string, error)
+
+	// UnmarshalFunc decodes one document of a format
+	UnmarshalFunc func(data []byte, v interface{}) error
+
+	// Resource is one of the resources in a file
+	Resource struct {
+		Resource interface{}
+		Kind     string
+		GUID     string
+		Line     int // where the resource starts in its file, 0 if unknown
+	}
+
+	// document is one resource in a file
+	document struct {
+		data []byte
+		node *yaml.Node // the document's content, with the position of each value in the file
+	}
 )
 
 var (
-	resourceLoaders map[string]ResourceLoaderFunc
+	resourceLoaders map[string]ResourceLoaderFunc // by kind and format, see RegisterLoader
+	unmarshalers    map[string]UnmarshalFunc
+	extensions      map[string]string // file extension -> format
 )
 
 func init() {
+	unmarshalers = make(map[string]UnmarshalFunc)
+	unmarshalers[FormatYAML] = yaml.Unmarshal
+	unmarshalers[FormatJSON] = json.Unmarshal
+	unmarshalers[FormatTOML] = unmarshalTOML
+
+	extensions = make(map[string]string)
+	extensions[".yaml"] = FormatYAML
+	extensions[".yml"] = FormatYAML
+	extensions[".json"] = FormatJSON
+	extensions[".toml"] = FormatTOML
+
 	resourceLoaders = make(map[string]ResourceLoaderFunc)
-	resourceLoaders[podops.ResourceShow] = loadShowResource
-	resourceLoaders[podops.ResourceEpisode] = loadEpisodeResource
-	//resourceLoaders[podops.ResourceAsset] = loadAssetResource
+	for format, unmarshal := range unmarshalers {
+		RegisterLoader(podops.ResourceShow, format, showLoader(unmarshal))
+		RegisterLoader(podops.ResourceEpisode, format, episodeLoader(unmarshal))
+		//RegisterLoader(podops.ResourceAsset, format, assetLoader(unmarshal))
+	}
+}
+
+// RegisterLoader registers the loader of a resource kind in a format
+func RegisterLoader(kind, format string, loader ResourceLoaderFunc) {
+	resourceLoaders[loaderKey(kind, format)] = loader
+}
+
+func loaderKey(kind, format string) string {
+	return format + "/" + kind
+}
+
+// Format returns the format of a resource file, based on its extension. Files in
+// other formats are not resources and an empty string is returned.
+func Format(path string) string {
+	return extensions[strings.ToLower(filepath.Ext(path))]
 }
 
+// ShowPath returns the location of the show in the repository at root, e.g. show.yaml or show.json
+func ShowPath(root string) string {
+	name := strings.TrimSuffix(config.DefaultShowName, filepath.Ext(config.DefaultShowName))
+	for _, ext := range []string{".yaml", ".yml", ".json", ".toml"} {
+		path := filepath.Join(root, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(root, config.DefaultShowName)
+}
+
+// WriteResource writes a resource in the format given by the extension of path
 func WriteResource(ctx context.Context, path string, rsrc interface{}) error {
-	data, err := yaml.Marshal(rsrc)
+	var data []byte
+	var err error
+
+	switch Format(path) {
+	case FormatJSON:
+		data, err = json.MarshalIndent(rsrc, "", "  ")
+	case FormatTOML:
+		data, err = marshalTOML(rsrc)
+	default:
+		data, err = yaml.Marshal(rsrc)
+	}
 	if err != nil {
 		return err
 	}
@@ -42,18 +126,27 @@ func WriteResource(ctx context.Context, path string, rsrc interface{}) error {
 	return ioutil.WriteFile(path, data, filePerm)
 }
 
+// ReadResource reads the first resource in a file
 func ReadResource(ctx context.Context, path string) (interface{}, string, string, error) {
-	data, err := ioutil.ReadFile(path)
+	resources, err := ReadResources(ctx, path)
 	if err != nil {
 		return nil, "", "", err
 	}
+	if len(resources) == 0 {
+		return nil, "", "", fmt.Errorf(podops.MsgResourceIsInvalid, path)
+	}
 
-	r, kind, guid, err := UnmarshalResource(data)
+	r := resources[0]
+	return r.Resource, r.Kind, r.GUID, nil
+}
+
+// ReadResources reads all resources in a file, see UnmarshalResources
+func ReadResources(ctx context.Context, path string) ([]*Resource, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return nil, "", "", err
+		return nil, err
 	}
-
-	return r, kind, guid, nil
+	return decodeResources(path, data, formatWithDefault(path))
 }
 
 func ReadEnclosure(ctx context.Context, path string) (*podops.AssetRef, error) {
@@ -77,9 +170,110 @@ func DeleteResource(ctx context.Context, path string) error {
 
 // UnmarshalResource takes a byte array and determines its kind before unmarshalling it into its struct form
 func UnmarshalResource(data []byte) (interface{}, string, string, error) {
+	resources, err := decodeResources("", data, FormatYAML)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if len(resources) == 0 {
+		return nil, "", "", fmt.Errorf(podops.MsgResourceIsInvalid, "")
+	}
+
+	r := resources[0]
+	return r.Resource, r.Kind, r.GUID, nil
+}
+
+// UnmarshalResources returns all resources in data. Episodes embedded in a show are returned as
+// resources of their own, following the show, and the show's episode list is cleared.
+func UnmarshalResources(data []byte, format string) ([]*Resource, error) {
+	return decodeResources("", data, format)
+}
+
+// decodeResources converts the resources in data to the latest apiVersion and checks them against
+// their schema before unmarshalling them, see SchemaError. Documents that are not a resource, e.g.
+// a renovate.json next to the resources, are skipped. path is only used in errors.
+func decodeResources(path string, data []byte, format string) ([]*Resource, error) {
+	all, err := splitDocuments(data, format)
+	if err != nil {
+		return nil, syntaxError(path, data, err)
+	}
+	docs := make([]*document, 0, len(all))
+	for _, doc := range all {
+		if isResource(doc.node) {
+			docs = append(docs, doc)
+		}
+	}
+
+	// resources in an old apiVersion are converted to the latest first
+	formats := make([]string, len(docs))
+	for i, doc := range docs {
+		formats[i] = format
+		n, err := migrateDocument(doc.node)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			if doc.data, err = yaml.Marshal(doc.node); err != nil {
+				return nil, err
+			}
+			formats[i] = FormatYAML
+		}
+	}
+
+	if issues := checkDocuments(path, docs, format); len(issues) > 0 {
+		return nil, &SchemaError{Issues: issues}
+	}
+
+	resources := make([]*Resource, 0, len(docs))
+	for i, doc := range docs {
+		r, kind, guid, err := unmarshalDocument(doc.data, formats[i])
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, &Resource{Resource: r, Kind: kind, GUID: guid, Line: doc.node.Line})
 
-	r, _ := LoadGenericResource(data)
-	loader := resourceLoaders[r.Kind]
+		if show, ok := r.(*podops.Show); ok {
+			embedded := fieldNode(doc.node, "episodes")
+			for i, e := range show.Episodes {
+				if e.Metadata.Parent == "" {
+					e.Metadata.Parent = show.GUID()
+				}
+				line := 0
+				if embedded != nil && i < len(embedded.Content) {
+					line = embedded.Content[i].Line
+				}
+				resources = append(resources, &Resource{Resource: e, Kind: podops.ResourceEpisode, GUID: e.GUID(), Line: line})
+			}
+			show.Episodes = nil
+		}
+	}
+	return resources, nil
+}
+
+// LoadGenericResource reads only the metadata of a resource
+func LoadGenericResource(data []byte) (*podops.GenericResource, error) {
+	return loadGenericResource(data, FormatYAML)
+}
+
+func loadGenericResource(data []byte, format string) (*podops.GenericResource, error) {
+	var r podops.GenericResource
+
+	unmarshal := unmarshalers[format]
+	if unmarshal == nil {
+		return nil, fmt.Errorf(podops.MsgResourceUnsupportedFormat, format)
+	}
+	if err := unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// unmarshalDocument determines the kind of one document before unmarshalling it into its struct form
+func unmarshalDocument(data []byte, format string) (interface{}, string, string, error) {
+	r, err := loadGenericResource(data, format)
+	if err != nil {
+		return nil, "", "", err
+	}
+	loader := resourceLoaders[loaderKey(r.Kind, format)]
 	if loader == nil {
 		return nil, "", "", fmt.Errorf(podops.MsgResourceIsInvalid, r.Kind)
 	}
@@ -91,48 +285,230 @@ func UnmarshalResource(data []byte) (interface{}, string, string, error) {
 	return resource, r.Kind, guid, nil
 }
 
-// LoadGenericResource reads only the metadata of a resource
-func LoadGenericResource(data []byte) (*podops.GenericResource, error) {
-	var r podops.GenericResource
+// splitDocuments returns the documents in data. Empty documents are skipped.
+func splitDocuments(data []byte, format string) ([]*document, error) {
+	docs := make([]*document, 0)
+
+	switch format {
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var node yaml.Node
+			if err := dec.Decode(&node); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, err
+			}
+			if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+				continue
+			}
+			doc, err := yaml.Marshal(&node)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, &document{data: doc, node: node.Content[0]})
+		}
+
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, err
+			}
+			node, err := jsonNode(data, int(dec.InputOffset())-len(raw), raw)
+			if err != nil {
+				return nil, err
+			}
+			if node.Kind != yaml.SequenceNode {
+				docs = append(docs, &document{data: raw, node: node})
+				continue
+			}
+
+			var list []json.RawMessage
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, err
+			}
+			for i, doc := range list {
+				docs = append(docs, &document{data: doc, node: node.Content[i]})
+			}
+		}
+
+	case FormatTOML:
+		if len(bytes.TrimSpace(data)) > 0 {
+			var m map[string]interface{}
+			if err := toml.Unmarshal(data, &m); err != nil {
+				return nil, err
+			}
+			js, err := json.Marshal(m)
+			if err != nil {
+				return nil, err
+			}
+			node, err := positionless(js)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, &document{data: data, node: node})
+		}
+
+	default:
+		return nil, fmt.Errorf(podops.MsgResourceUnsupportedFormat, format)
+	}
+
+	return docs, nil
+}
 
-	err := yaml.Unmarshal([]byte(data), &r)
+// jsonNode parses the JSON value raw found at offset in data. Its nodes have their position in data.
+func jsonNode(data []byte, offset int, raw []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil || len(doc.Content) == 0 {
+		// not every JSON is also YAML, e.g. with tabs in the indentation
+		return positionless(raw)
+	}
+
+	line := bytes.Count(data[:offset], []byte("\n"))
+	column := offset - (bytes.LastIndexByte(data[:offset], '\n') + 1)
+	walkNodes(doc.Content[0], func(n *yaml.Node) {
+		if n.Line == 1 {
+			n.Column += column
+		}
+		n.Line += line
+	})
+	return doc.Content[0], nil
+}
+
+// positionless parses JSON into nodes without a position, e.g. if the JSON was converted from TOML
+func positionless(data []byte) (*yaml.Node, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	js, err := json.Marshal(v) // no indentation left, YAML can parse it
 	if err != nil {
 		return nil, err
 	}
-	return &r, nil
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(js, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf(podops.MsgResourceIsInvalid, string(data))
+	}
+	walkNodes(doc.Content[0], func(n *yaml.Node) {
+		n.Line = 0
+		n.Column = 0
+	})
+	return doc.Content[0], nil
 }
 
-func loadShowResource(data []byte) (interface{}, string, error) {
-	var show podops.Show
+// walkNodes calls fn for node and all nodes below it
+func walkNodes(node *yaml.Node, fn func(n *yaml.Node)) {
+	fn(node)
+	for _, n := range node.Content {
+		walkNodes(n, fn)
+	}
+}
 
-	err := yaml.Unmarshal([]byte(data), &show)
-	if err != nil {
-		return nil, "", err
+// isResource returns true if node has a kind or an apiVersion. A resource that lacks one of them
+// is reported by checkDocument.
+func isResource(node *yaml.Node) bool {
+	return fieldNode(node, "kind") != nil || fieldNode(node, "apiVersion") != nil
+}
+
+// fieldNode returns the value of key in a mapping node, or nil
+func fieldNode(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
 	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
 
-	return &show, show.GUID(), nil
+// formatWithDefault returns the format of a file, resources without a known extension are YAML
+func formatWithDefault(path string) string {
+	if format := Format(path); format != "" {
+		return format
+	}
+	return FormatYAML
 }
 
-func loadEpisodeResource(data []byte) (interface{}, string, error) {
-	var episode podops.Episode
+// unmarshalTOML decodes TOML using the JSON names of the resources' attributes
+func unmarshalTOML(data []byte, v interface{}) error {
+	var m map[string]interface{}
+	if err := toml.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	js, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(js, v)
+}
 
-	err := yaml.Unmarshal([]byte(data), &episode)
+// marshalTOML encodes TOML using the JSON names of the resources' attributes
+func marshalTOML(v interface{}) ([]byte, error) {
+	js, err := json.Marshal(v)
 	if err != nil {
-		return nil, "", err
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(js, &m); err != nil {
+		return nil, err
 	}
 
-	return &episode, episode.GUID(), nil
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
-/*
-func loadAssetResource(data []byte) (interface{}, string, error) {
-	var asset podops.Asset
+func showLoader(unmarshal UnmarshalFunc) ResourceLoaderFunc {
+	return func(data []byte) (interface{}, string, error) {
+		var show podops.Show
 
-	err := yaml.Unmarshal([]byte(data), &asset)
-	if err != nil {
-		return nil, "", err
+		err := unmarshal(data, &show)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return &show, show.GUID(), nil
+	}
+}
+
+func episodeLoader(unmarshal UnmarshalFunc) ResourceLoaderFunc {
+	return func(data []byte) (interface{}, string, error) {
+		var episode podops.Episode
+
+		err := unmarshal(data, &episode)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return &episode, episode.GUID(), nil
 	}
+}
 
-	return &asset, asset.Metadata.GUID, nil
+/*
+func assetLoader(unmarshal UnmarshalFunc) ResourceLoaderFunc {
+	return func(data []byte) (interface{}, string, error) {
+		var asset podops.Asset
+
+		err := unmarshal(data, &asset)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return &asset, asset.Metadata.GUID, nil
+	}
 }
 */
@@ -0,0 +1,112 @@
+package loader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+)
+
+const (
+	multiDocYAML = `apiVersion: v1
+kind: show
+metadata:
+  name: multidocshow
+  guid: a7c94297acfc
+description:
+  title: Multi Document Show
+episodes:
+  - apiVersion: v1
+    kind: episode
+    metadata:
+      name: firstepisode
+      guid: 86124f7f9cf4
+---
+---
+apiVersion: v1
+kind: episode
+metadata:
+  name: secondepisode
+  guid: 95235f8f0ad5
+  parent: a7c94297acfc
+`
+	episodesJSON = `[
+	{"apiVersion": "v1", "kind": "episode", "metadata": {"name": "firstepisode", "guid": "86124f7f9cf4"}},
+	{"apiVersion": "v1", "kind": "episode", "metadata": {"name": "secondepisode", "guid": "95235f8f0ad5"}}
+]
+{"apiVersion": "v1", "kind": "episode", "metadata": {"name": "thirdepisode", "guid": "1b2c3d4e5f60"}}`
+
+	showTOML = `apiVersion = "v1"
+kind = "show"
+
+[metadata]
+name = "tomlpodcast"
+guid = "a7c94297acfc"
+
+[metadata.labels]
+language = "en_US"
+
+[description]
+title = "TOML Show"
+
+[[episodes]]
+apiVersion = "v1"
+kind = "episode"
+
+[episodes.metadata]
+name = "firstepisode"
+guid = "86124f7f9cf4"
+`
+)
+
+func TestUnmarshalResources(t *testing.T) {
+	resources, err := UnmarshalResources([]byte(multiDocYAML), FormatYAML)
+	assert.NoError(t, err)
+	if assert.Equal(t, 3, len(resources)) {
+		assert.Equal(t, podops.ResourceShow, resources[0].Kind)
+		assert.Empty(t, resources[0].Resource.(*podops.Show).Episodes)
+		assert.Equal(t, "86124f7f9cf4", resources[1].GUID)
+		assert.Equal(t, "a7c94297acfc", resources[1].Resource.(*podops.Episode).Parent()) // embedded episodes belong to the show
+		assert.Equal(t, "95235f8f0ad5", resources[2].GUID)
+	}
+
+	resources, err = UnmarshalResources([]byte(episodesJSON), FormatJSON)
+	assert.NoError(t, err)
+	if assert.Equal(t, 3, len(resources)) {
+		assert.Equal(t, "thirdepisode", resources[2].Resource.(*podops.Episode).Metadata.Name)
+	}
+
+	resources, err = UnmarshalResources([]byte(showTOML), FormatTOML)
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(resources)) {
+		show := resources[0].Resource.(*podops.Show)
+		assert.Equal(t, "TOML Show", show.Description.Title)
+		assert.Equal(t, "en_US", show.Metadata.Labels[podops.LabelLanguage])
+		assert.Equal(t, podops.ResourceEpisode, resources[1].Kind)
+	}
+
+	_, err = UnmarshalResources([]byte(`{"kind": "unknown"}`), FormatJSON)
+	assert.Error(t, err)
+}
+
+func TestWriteResourceFormats(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+	show := podops.DefaultShow("formatpodcast", "FORMAT PODCAST", "SUMMARY", "a7c94297acfc", "https://example.com", "https://cdn.example.com")
+
+	for _, name := range []string{"show.yaml", "show.json", "show.toml"} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, WriteResource(ctx, path, show))
+
+		rsrc, kind, guid, err := ReadResource(ctx, path)
+		assert.NoError(t, err, name)
+		assert.Equal(t, podops.ResourceShow, kind)
+		assert.Equal(t, show.GUID(), guid)
+		assert.Equal(t, show, rsrc.(*podops.Show), name)
+	}
+
+	assert.Equal(t, filepath.Join(dir, "show.yaml"), ShowPath(dir))
+}
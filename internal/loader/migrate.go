@@ -0,0 +1,403 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+)
+
+type (
+	// MigrateFunc converts a resource into the next apiVersion. It changes the resource's nodes
+	// in place, e.g. renames a key or sets a value, so comments and formatting are kept.
+	// The new apiVersion is set after it returned.
+	MigrateFunc func(node *yaml.Node) error
+
+	// MigratedFile is a file with resources in an old apiVersion
+	MigratedFile struct {
+		File        string `json:"file"`
+		Resources   int    `json:"resources"`   // the number of migrated resources
+		Reformatted bool   `json:"reformatted"` // the file was written anew, its formatting was not kept
+	}
+
+	migration struct {
+		to      string
+		migrate MigrateFunc
+	}
+
+	// nodeValue is the value of a node before a migration
+	nodeValue struct {
+		node  *yaml.Node
+		value string
+	}
+)
+
+var (
+	migrations map[string]*migration // by kind and the apiVersion it converts from, see RegisterMigration
+
+	plainScalar = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+)
+
+func init() {
+	migrations = make(map[string]*migration)
+
+	// the first resource templates documented v1.0 as the default apiVersion
+	RegisterMigration(podops.ResourceShow, "v1.0", "v1", func(node *yaml.Node) error { return nil })
+	RegisterMigration(podops.ResourceEpisode, "v1.0", "v1", func(node *yaml.Node) error { return nil })
+}
+
+// RegisterMigration registers the conversion of a resource kind from one apiVersion into the next.
+// Resources are converted until they have the latest apiVersion, config.Version.
+func RegisterMigration(kind, from, to string, fn MigrateFunc) {
+	migrations[loaderKey(kind, from)] = &migration{to: to, migrate: fn}
+}
+
+// Migrate rewrites all resources in the repository at root that have an old apiVersion, see MigrateFile
+func Migrate(ctx context.Context, root string, dryRun bool) ([]*MigratedFile, error) {
+	files := make([]*MigratedFile, 0)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// skip the assets dir and other hidden dirs
+		if info.IsDir() && path != root && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || Format(path) == "" {
+			return nil
+		}
+
+		f, err := MigrateFile(ctx, path, dryRun)
+		if err != nil {
+			return err
+		}
+		if f != nil {
+			files = append(files, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// MigrateFile rewrites the resources in a file that have an old apiVersion. Only the changed values
+// are replaced, if that is not possible the file is written anew and its comments are kept
+// as far as the format allows. Returns nil if nothing had to be migrated.
+func MigrateFile(ctx context.Context, path string, dryRun bool) (*MigratedFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := formatWithDefault(path)
+
+	docs, err := splitDocuments(data, format)
+	if err != nil {
+		return nil, syntaxError(path, data, err)
+	}
+
+	before := make([][]*nodeValue, len(docs))
+	migrated := 0
+	for i, doc := range docs {
+		before[i] = nodeValues(doc.node)
+		n, err := migrateDocument(doc.node)
+		if err != nil {
+			return nil, err
+		}
+		migrated += n
+	}
+	if migrated == 0 {
+		return nil, nil
+	}
+
+	f := &MigratedFile{File: path, Resources: migrated}
+	out, reformatted, err := renderDocuments(data, docs, before, format)
+	if err != nil {
+		return nil, err
+	}
+	f.Reformatted = reformatted
+
+	if dryRun {
+		return f, nil
+	}
+	return f, ioutil.WriteFile(path, out, info.Mode().Perm())
+}
+
+// EditResource changes the first resource of kind in the file at path with fn and writes the file back,
+// see MigrateFile. fn changes the resource's nodes in place.
+func EditResource(ctx context.Context, path, kind string, fn MigrateFunc) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	format := formatWithDefault(path)
+
+	docs, err := splitDocuments(data, format)
+	if err != nil {
+		return syntaxError(path, data, err)
+	}
+
+	before := make([][]*nodeValue, len(docs))
+	for i, doc := range docs {
+		before[i] = nodeValues(doc.node)
+	}
+	edited := false
+	for _, doc := range docs {
+		if k := fieldNode(doc.node, "kind"); k != nil && k.Value == kind {
+			if err := fn(doc.node); err != nil {
+				return err
+			}
+			edited = true
+			break
+		}
+	}
+	if !edited {
+		return fmt.Errorf(podops.MsgResourceIsInvalid, path)
+	}
+
+	out, _, err := renderDocuments(data, docs, before, format)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out, info.Mode().Perm())
+}
+
+// SetNewFeedLink sets the newFeedLink of the show in the file at path, or removes it if uri is empty
+func SetNewFeedLink(ctx context.Context, path, uri string) error {
+	return EditResource(ctx, path, podops.ResourceShow, func(node *yaml.Node) error {
+		if uri == "" {
+			setField(node, "newFeedLink", nil)
+			return nil
+		}
+		if u := fieldNode(fieldNode(node, "newFeedLink"), "uri"); u != nil {
+			u.Value = uri
+			return nil
+		}
+		link := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setField(link, "uri", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: uri})
+		setField(node, "newFeedLink", link)
+		return nil
+	})
+}
+
+// setField sets key in a mapping node to value, or removes it if value is nil
+func setField(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			if value == nil {
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			} else {
+				node.Content[i+1] = value
+			}
+			return
+		}
+	}
+	if value != nil {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	}
+}
+
+// renderDocuments returns the changed documents. Only the changed values are replaced in data,
+// if that is not possible the documents are written anew and reformatted is true.
+func renderDocuments(data []byte, docs []*document, before [][]*nodeValue, format string) ([]byte, bool, error) {
+	if out, ok := patchScalars(data, docs, before); ok {
+		return out, false, nil
+	}
+	out, err := encodeDocuments(docs, format)
+	return out, true, err
+}
+
+// migrateDocument converts a resource and the episodes embedded in it to the latest apiVersion.
+// Returns the number of converted resources.
+func migrateDocument(node *yaml.Node) (int, error) {
+	n := 0
+
+	kind := fieldNode(node, "kind")
+	version := fieldNode(node, "apiVersion")
+	if kind != nil && version != nil {
+		// a version without a path to the latest is reported by checkDocument
+		for steps := 0; version.Value != config.Version && steps <= len(migrations); steps++ {
+			m, ok := migrations[loaderKey(kind.Value, version.Value)]
+			if !ok {
+				break
+			}
+			if err := m.migrate(node); err != nil {
+				return 0, err
+			}
+			version.Value = m.to
+			if steps == 0 {
+				n++
+			}
+		}
+	}
+
+	if episodes := fieldNode(node, "episodes"); episodes != nil && episodes.Kind == yaml.SequenceNode {
+		for _, e := range episodes.Content {
+			m, err := migrateDocument(e)
+			if err != nil {
+				return 0, err
+			}
+			n += m
+		}
+	}
+	return n, nil
+}
+
+// nodeValues returns node and all nodes below it, with their values
+func nodeValues(node *yaml.Node) []*nodeValue {
+	values := make([]*nodeValue, 0)
+	walkNodes(node, func(n *yaml.Node) {
+		values = append(values, &nodeValue{node: n, value: n.Value})
+	})
+	return values
+}
+
+// patchScalars replaces the scalars that were changed by a migration in data. It fails if the
+// migration changed the structure of a document or a scalar can not be replaced as is.
+func patchScalars(data []byte, docs []*document, before [][]*nodeValue) ([]byte, bool) {
+	type patch struct {
+		offset   int
+		from, to string
+	}
+	patches := make([]*patch, 0)
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	lineOffsets := make([]int, len(lines)+1)
+	for i, l := range lines {
+		lineOffsets[i+1] = lineOffsets[i] + len(l)
+	}
+
+	for i, doc := range docs {
+		after := nodeValues(doc.node)
+		if len(after) != len(before[i]) {
+			return nil, false
+		}
+		for j, s := range before[i] {
+			n := after[j].node
+			if n != s.node {
+				return nil, false
+			}
+			if n.Value == s.value {
+				continue
+			}
+			if n.Kind != yaml.ScalarNode || n.Line == 0 || n.Line > len(lines) {
+				return nil, false
+			}
+
+			from, to, ok := renderScalar(n, s.value)
+			if !ok {
+				return nil, false
+			}
+
+			// columns count characters, not bytes
+			line := lines[n.Line-1]
+			offset := 0
+			for c := 1; c < n.Column && offset < len(line); c++ {
+				_, size := utf8.DecodeRune(line[offset:])
+				offset += size
+			}
+			offset += lineOffsets[n.Line-1]
+
+			if !bytes.HasPrefix(data[offset:], []byte(from)) {
+				return nil, false
+			}
+			patches = append(patches, &patch{offset: offset, from: from, to: to})
+		}
+	}
+
+	// from the end, so the offsets stay valid
+	sort.Slice(patches, func(i, j int) bool { return patches[i].offset > patches[j].offset })
+
+	out := append([]byte{}, data...)
+	for _, p := range patches {
+		out = append(out[:p.offset], append([]byte(p.to), out[p.offset+len(p.from):]...)...)
+	}
+	return out, true
+}
+
+// renderScalar returns how the old and the new value of a scalar are written in its style
+func renderScalar(n *yaml.Node, from string) (string, string, bool) {
+	to := n.Value
+
+	switch n.Style {
+	case 0:
+		if !plainScalar.MatchString(from) || !plainScalar.MatchString(to) {
+			return "", "", false
+		}
+		// e.g. 'true' or '1.0' would change the type of a text
+		var v yaml.Node
+		if err := yaml.Unmarshal([]byte(to), &v); err != nil || len(v.Content) == 0 || v.Content[0].Tag != n.Tag {
+			return "", "", false
+		}
+		return from, to, true
+	case yaml.DoubleQuotedStyle:
+		ok := !strings.ContainsAny(from+to, "\"\\\n")
+		return `"` + from + `"`, `"` + to + `"`, ok
+	case yaml.SingleQuotedStyle:
+		ok := !strings.ContainsAny(from+to, "'\n")
+		return "'" + from + "'", "'" + to + "'", ok
+	}
+	return "", "", false
+}
+
+// encodeDocuments writes all documents anew
+func encodeDocuments(docs []*document, format string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case FormatYAML:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		for _, doc := range docs {
+			if err := enc.Encode(doc.node); err != nil {
+				return nil, err
+			}
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case FormatJSON:
+		values := make([]interface{}, len(docs))
+		for i, doc := range docs {
+			if err := doc.node.Decode(&values[i]); err != nil {
+				return nil, err
+			}
+		}
+		if len(values) == 1 {
+			return json.MarshalIndent(values[0], "", "  ")
+		}
+		return json.MarshalIndent(values, "", "  ")
+
+	case FormatTOML:
+		var v map[string]interface{}
+		if err := docs[0].node.Decode(&v); err != nil {
+			return nil, err
+		}
+		return marshalTOML(v)
+	}
+	return nil, fmt.Errorf(podops.MsgResourceUnsupportedFormat, format)
+}
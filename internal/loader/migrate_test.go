@@ -0,0 +1,141 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+)
+
+const (
+	oldEpisodeYAML = `# the first episode
+apiVersion: v1.0 # as in the first templates
+kind:    episode
+metadata:
+  name: firstepisode
+  guid: 86124f7f9cf4
+---
+apiVersion: "v0-test"
+kind: episode
+metadata:
+    name: secondepisode
+    guid: 95235f8f0ad5
+description:
+    text: Second # renamed to summary
+`
+	oldEpisodeJSON = `{"apiVersion": "v1.0", "kind": "episode", "metadata": {"name": "firstepisode", "guid": "86124f7f9cf4"}}`
+)
+
+func init() {
+	// an older version that calls the summary 'text'
+	RegisterMigration(podops.ResourceEpisode, "v0-test", "v1.0", func(node *yaml.Node) error {
+		if description := fieldNode(node, "description"); description != nil {
+			for i := 0; i < len(description.Content); i += 2 {
+				if description.Content[i].Value == "text" {
+					description.Content[i].Value = "summary"
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func TestMigrateResources(t *testing.T) {
+	resources, err := UnmarshalResources([]byte(oldEpisodeYAML), FormatYAML)
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(resources)) {
+		assert.Equal(t, config.Version, resources[0].Resource.(*podops.Episode).APIVersion)
+		second := resources[1].Resource.(*podops.Episode)
+		assert.Equal(t, config.Version, second.APIVersion)
+		assert.Equal(t, "Second", second.Description.Summary)
+	}
+
+	_, err = UnmarshalResources([]byte("apiVersion: v9\nkind: show\n"), FormatYAML)
+	var se *SchemaError
+	if assert.True(t, errors.As(err, &se)) {
+		assert.Equal(t, RuleInvalidVersion, se.Issues[0].Rule)
+		assert.Equal(t, 1, se.Issues[0].Line)
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "episodes.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(oldEpisodeYAML), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "first.json"), []byte(oldEpisodeJSON), 0644))
+
+	files, err := Migrate(context.TODO(), root, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, oldEpisodeYAML, string(data)) // a dry run
+
+	f, err := MigrateFile(context.TODO(), path, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, f.Resources)
+	assert.False(t, f.Reformatted)
+
+	// only the versions and the key change
+	expected := strings.NewReplacer("v1.0 #", "v1 #", `"v0-test"`, `"v1"`, "text: Second", "summary: Second").Replace(oldEpisodeYAML)
+	data, _ = os.ReadFile(path)
+	assert.Equal(t, expected, string(data))
+
+	f, err = MigrateFile(context.TODO(), filepath.Join(root, "first.json"), false)
+	assert.NoError(t, err)
+	assert.False(t, f.Reformatted)
+	data, _ = os.ReadFile(filepath.Join(root, "first.json"))
+	assert.Equal(t, strings.Replace(oldEpisodeJSON, "v1.0", "v1", 1), string(data))
+
+	files, err = Migrate(context.TODO(), root, false)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestMigrateFileReformatted(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "show.toml")
+	assert.NoError(t, os.WriteFile(path, []byte("apiVersion = \"v1.0\"\nkind = \"show\"\n"), 0644))
+
+	f, err := MigrateFile(context.TODO(), path, false)
+	assert.NoError(t, err)
+	assert.True(t, f.Reformatted) // TOML has no positions
+
+	r, _, _, err := ReadResource(context.TODO(), path)
+	assert.NoError(t, err)
+	assert.Equal(t, config.Version, r.(*podops.Show).APIVersion)
+}
+
+func TestSetNewFeedLink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "show.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(defaultsShowYAML), 0644))
+
+	assert.NoError(t, SetNewFeedLink(context.TODO(), path, "https://example.com/feed.xml"))
+	r, _, _, err := ReadResource(context.TODO(), path)
+	assert.NoError(t, err)
+	if assert.NotNil(t, r.(*podops.Show).NewFeedLink) {
+		assert.Equal(t, "https://example.com/feed.xml", r.(*podops.Show).NewFeedLink.URI)
+	}
+
+	assert.NoError(t, SetNewFeedLink(context.TODO(), path, "https://example.org/feed.xml"))
+	r, _, _, err = ReadResource(context.TODO(), path)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org/feed.xml", r.(*podops.Show).NewFeedLink.URI)
+
+	assert.NoError(t, SetNewFeedLink(context.TODO(), path, ""))
+	r, _, _, err = ReadResource(context.TODO(), path)
+	assert.NoError(t, err)
+	assert.Nil(t, r.(*podops.Show).NewFeedLink)
+
+	// only shows have a newFeedLink
+	episode := filepath.Join(t.TempDir(), "episode.yaml")
+	assert.NoError(t, os.WriteFile(episode, []byte(oldEpisodeJSON), 0644))
+	assert.Error(t, SetNewFeedLink(context.TODO(), episode, "https://example.com/feed.xml"))
+}
@@ -0,0 +1,177 @@
+package loader
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "po lint"
+	toolURI      = "https://github.com/podops/podops"
+)
+
+var (
+	ruleDescriptions = map[string]string{
+		RuleSyntax:         "The file can not be parsed",
+		RuleUnknownField:   "The attribute does not exist, e.g. because of a typo",
+		RuleInvalidType:    "The attribute has a value of the wrong type",
+		RuleInvalidKind:    "The kind of the resource is missing or unknown",
+		RuleInvalidVersion: "The apiVersion of the resource is unknown",
+		RuleInvalidDefault: "The episode default is not a valid template",
+		RuleValidation:     "The resource is not valid",
+	}
+	rules = []string{RuleSyntax, RuleUnknownField, RuleInvalidType, RuleInvalidKind, RuleInvalidVersion, RuleInvalidDefault, RuleValidation}
+)
+
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifact `json:"artifactLocation"`
+		Region           *sarifRegion  `json:"region,omitempty"`
+	}
+
+	sarifArtifact struct {
+		URI string `json:"uri"`
+	}
+
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+
+	junitSuites struct {
+		XMLName xml.Name     `xml:"testsuites"`
+		Suites  []junitSuite `xml:"testsuite"`
+	}
+
+	junitSuite struct {
+		Name     string      `xml:"name,attr"`
+		Tests    int         `xml:"tests,attr"`
+		Failures int         `xml:"failures,attr"`
+		Cases    []junitCase `xml:"testcase"`
+	}
+
+	junitCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// SARIF returns the report in the Static Analysis Results Interchange Format, e.g. for code scanning in CI
+func (r *LintReport) SARIF() ([]byte, error) {
+	driver := sarifDriver{Name: toolName, InformationURI: toolURI, Rules: make([]sarifRule, len(rules))}
+	for i, id := range rules {
+		driver.Rules[i] = sarifRule{ID: id, ShortDescription: sarifMessage{Text: ruleDescriptions[id]}}
+	}
+
+	results := make([]sarifResult, len(r.Issues))
+	for i, issue := range r.Issues {
+		msg := issue.Message
+		if issue.Suggestion != "" {
+			msg = fmt.Sprintf("%s, %s", msg, issue.Suggestion)
+		}
+		location := sarifPhysicalLocation{ArtifactLocation: sarifArtifact{URI: issue.File}}
+		if issue.Line > 0 {
+			location.Region = &sarifRegion{StartLine: issue.Line, StartColumn: issue.Column}
+		}
+		results[i] = sarifResult{
+			RuleID:    issue.Rule,
+			Level:     issue.Level,
+			Message:   sarifMessage{Text: msg},
+			Locations: []sarifLocation{{PhysicalLocation: location}},
+		}
+	}
+
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	return json.MarshalIndent(&log, "", "  ")
+}
+
+// JUnit returns the report as JUnit XML, with a test case for every file. Errors fail a test case, warnings don't.
+func (r *LintReport) JUnit() ([]byte, error) {
+	byFile := make(map[string][]*Issue)
+	for _, issue := range r.Issues {
+		byFile[issue.File] = append(byFile[issue.File], issue)
+	}
+
+	suite := junitSuite{Name: toolName, Tests: len(r.Files), Cases: make([]junitCase, len(r.Files))}
+	for i, file := range r.Files {
+		errors := make([]string, 0)
+		warnings := make([]string, 0)
+		for _, issue := range byFile[file] {
+			if issue.Level == LevelError {
+				errors = append(errors, issue.String())
+			} else {
+				warnings = append(warnings, issue.String())
+			}
+		}
+
+		c := junitCase{Name: file, ClassName: "lint", SystemOut: strings.Join(warnings, "\n")}
+		if len(errors) > 0 {
+			c.Failure = &junitFailure{Message: errors[0], Type: LevelError, Text: strings.Join(errors, "\n")}
+			suite.Failures++
+		}
+		suite.Cases[i] = c
+	}
+
+	data, err := xml.MarshalIndent(&junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
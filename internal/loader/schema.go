@@ -0,0 +1,295 @@
+package loader
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+)
+
+const (
+	jsonSchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+	// kindDefaults checks the episode defaults, they are an episode without apiVersion and kind
+	kindDefaults = "defaults"
+)
+
+var (
+	resourceTypes map[string]reflect.Type // kind -> type of the resource, see Schema
+)
+
+func init() {
+	resourceTypes = make(map[string]reflect.Type)
+	resourceTypes[podops.ResourceShow] = reflect.TypeOf(podops.Show{})
+	resourceTypes[podops.ResourceEpisode] = reflect.TypeOf(podops.Episode{})
+	resourceTypes[kindDefaults] = reflect.TypeOf(podops.Episode{})
+}
+
+// Schema returns the JSON Schema of a resource kind. Required values are not part of the schema,
+// episodes can take them from their defaults. Validate checks them.
+func Schema(kind string) (map[string]interface{}, error) {
+	t, ok := resourceTypes[kind]
+	if !ok || kind == kindDefaults {
+		return nil, fmt.Errorf(podops.MsgResourceUnsupportedKind, kind)
+	}
+
+	definitions := make(map[string]interface{})
+	root := typeSchema(t, definitions)
+	root["$schema"] = jsonSchemaVersion
+	root["title"] = fmt.Sprintf("podops %s", kind)
+	root["definitions"] = definitions
+
+	return root, nil
+}
+
+// typeSchema returns the schema of t. Structs are added to definitions and referenced.
+func typeSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), definitions)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), definitions)}
+	case reflect.Struct:
+		if _, ok := definitions[t.Name()]; !ok {
+			definitions[t.Name()] = nil // the type is recursive, e.g. PodcastTag
+
+			properties := make(map[string]interface{})
+			names, fields := structFields(t)
+			for _, name := range names {
+				properties[name] = typeSchema(fields[name].Type, definitions)
+			}
+			definitions[t.Name()] = map[string]interface{}{
+				"type":                 "object",
+				"properties":           properties,
+				"additionalProperties": false,
+			}
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+// checkDocuments checks all documents against the schema of their kind
+func checkDocuments(path string, docs []*document, format string) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, doc := range docs {
+		issues = append(issues, checkDocument(path, doc, "", format)...)
+	}
+	return issues
+}
+
+// checkDocument checks a document against the schema of kind. If kind is empty, the document's kind is used.
+func checkDocument(path string, doc *document, kind, format string) []*Issue {
+	c := &checker{path: path, format: format, issues: make([]*Issue, 0)}
+
+	if kind == "" {
+		node := fieldNode(doc.node, "kind")
+		if node == nil {
+			c.add(doc.node, RuleInvalidKind, podops.MsgLintNoKind, "")
+			return c.issues
+		}
+		kind = node.Value
+		if _, ok := resourceTypes[kind]; !ok || kind == kindDefaults {
+			suggestion := ""
+			if name := closest(kind, []string{podops.ResourceShow, podops.ResourceEpisode}); name != "" {
+				suggestion = fmt.Sprintf(podops.MsgLintSuggestion, name)
+			}
+			c.add(node, RuleInvalidKind, fmt.Sprintf(podops.MsgResourceUnsupportedKind, kind), suggestion)
+			return c.issues
+		}
+	}
+
+	// resources in an old apiVersion were migrated, see migrateDocument
+	if version := fieldNode(doc.node, "apiVersion"); version != nil && version.Value != config.Version {
+		c.add(version, RuleInvalidVersion, fmt.Sprintf(podops.MsgResourceUnsupportedVersion, version.Value), "")
+	}
+
+	c.check(doc.node, resourceTypes[kind], "")
+	return c.issues
+}
+
+// checker collects the issues of one document
+type checker struct {
+	path   string
+	format string
+	issues []*Issue
+}
+
+func (c *checker) add(node *yaml.Node, rule, msg, suggestion string) {
+	c.issues = append(c.issues, &Issue{
+		File:       c.path,
+		Line:       node.Line,
+		Column:     node.Column,
+		Level:      LevelError,
+		Rule:       rule,
+		Message:    msg,
+		Suggestion: suggestion,
+	})
+}
+
+// check verifies that node can be decoded into a value of type t without losing anything
+func (c *checker) check(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		c.check(node, t.Elem(), path)
+
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			c.add(node, RuleInvalidType, fmt.Sprintf(podops.MsgLintType, pathWithDefault(path), "an object"), "")
+			return
+		}
+		names, fields := structFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				suggestion := ""
+				if name := closest(key.Value, names); name != "" {
+					suggestion = fmt.Sprintf(podops.MsgLintSuggestion, name)
+				}
+				c.add(key, RuleUnknownField, fmt.Sprintf(podops.MsgLintUnknownField, join(path, key.Value)), suggestion)
+				continue
+			}
+			c.check(value, field.Type, join(path, key.Value))
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			c.add(node, RuleInvalidType, fmt.Sprintf(podops.MsgLintType, pathWithDefault(path), "an object"), "")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			c.check(node.Content[i+1], t.Elem(), join(path, node.Content[i].Value))
+		}
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			c.add(node, RuleInvalidType, fmt.Sprintf(podops.MsgLintType, pathWithDefault(path), "a list"), "")
+			return
+		}
+		for i, n := range node.Content {
+			c.check(n, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+
+	case reflect.String:
+		// YAML takes any scalar as text, JSON only strings
+		if node.Kind != yaml.ScalarNode || (c.format == FormatJSON && node.Tag != "!!str") {
+			c.add(node, RuleInvalidType, fmt.Sprintf(podops.MsgLintType, pathWithDefault(path), "a text"), "")
+		}
+
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			c.add(node, RuleInvalidType, fmt.Sprintf(podops.MsgLintType, pathWithDefault(path), "a number"), "")
+		}
+
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			c.add(node, RuleInvalidType, fmt.Sprintf(podops.MsgLintType, pathWithDefault(path), "true or false"), "")
+		}
+	}
+}
+
+// structFields returns the attribute names of a struct, sorted, and their fields
+func structFields(t reflect.Type) ([]string, map[string]reflect.StructField) {
+	names := make([]string, 0, t.NumField())
+	fields := make(map[string]reflect.StructField)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+		fields[name] = f
+	}
+	sort.Strings(names)
+	return names, fields
+}
+
+// closest returns the candidate that is most likely meant by s, or an empty string
+func closest(s string, candidates []string) string {
+	best, bestDistance := "", len(s)/3+1 // at most one typo in every three letters
+	if bestDistance < 2 {
+		bestDistance = 2
+	}
+
+	for _, c := range candidates {
+		if strings.EqualFold(s, c) {
+			return c
+		}
+		if d := distance(strings.ToLower(s), strings.ToLower(c)); d <= bestDistance {
+			best, bestDistance = c, d-1
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance of a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func pathWithDefault(path string) string {
+	if path == "" {
+		return "resource"
+	}
+	return path
+}
@@ -28,6 +28,8 @@ type (
 		ContentType string `json:"type" yaml:"type"`
 		Timestamp   int64  `json:"timestamp" yaml:"timestamp"`
 		ETag        string `json:"etag" yaml:"etag"`
+		// ContentETag is the etag the content endpoint serves the resource with, if it is known
+		ContentETag string `json:"content_etag,omitempty" yaml:"content_etag,omitempty"`
 	}
 )
 
@@ -0,0 +1,202 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+)
+
+const (
+	maxAttempts     = 5
+	initialBackoff  = 2 * time.Second
+	deliveryTimeout = 15 * time.Second
+	deliveryWorkers = 4
+	queueSize       = 256
+)
+
+type (
+	delivery struct {
+		method  string
+		url     string
+		body    []byte
+		header  http.Header
+		trusted bool // the url is configured by the operator, e.g. Podping, and may be internal
+		attempt int
+	}
+)
+
+var (
+	queue chan *delivery
+
+	// errForbiddenAddress indicates a notification to the internal network
+	errForbiddenAddress = errors.New("forbidden address")
+
+	// networks users can not send notifications to, e.g. a cloud's metadata endpoint
+	forbiddenNetworks []*net.IPNet
+)
+
+func init() {
+	for _, cidr := range []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.168.0.0/16",
+		"::/128", "::1/128", "fc00::/7", "fe80::/10",
+	} {
+		_, network, _ := net.ParseCIDR(cidr)
+		forbiddenNetworks = append(forbiddenNetworks, network)
+	}
+
+	queue = make(chan *delivery, queueSize)
+	for i := 0; i < deliveryWorkers; i++ {
+		go worker()
+	}
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the payload
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func enqueue(method, url string, body []byte, header http.Header, trusted bool) {
+	d := &delivery{
+		method:  method,
+		url:     url,
+		body:    body,
+		header:  header,
+		trusted: trusted,
+	}
+	select {
+	case queue <- d:
+	default:
+		log.Printf("notify: queue is full, dropping notification to '%s'", url)
+	}
+}
+
+func worker() {
+	client := newClient()
+	trusted := &http.Client{Timeout: deliveryTimeout}
+
+	for d := range queue {
+		c := client
+		if d.trusted {
+			c = trusted
+		}
+		retry, err := d.send(c)
+		if err == nil {
+			continue
+		}
+
+		d.attempt++
+		if !retry || d.attempt >= maxAttempts {
+			log.Printf("notify: giving up on '%s' after %d attempt(s): %v", d.url, d.attempt, err)
+			continue
+		}
+
+		// exponential backoff, without blocking the worker
+		backoff := initialBackoff << (d.attempt - 1)
+		next := d
+		time.AfterFunc(backoff, func() { requeue(queue, next) })
+	}
+}
+
+// requeue schedules another attempt of a delivery, it is dropped if the queue is full
+func requeue(q chan<- *delivery, d *delivery) {
+	select {
+	case q <- d:
+	default:
+		log.Printf("notify: queue is full, dropping attempt %d of the notification to '%s'", d.attempt+1, d.url)
+	}
+}
+
+// newClient returns the client for urls users configured. It only connects to public addresses, the
+// address is checked after the DNS lookup. Proxies are not used, they would connect on the client's behalf.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: deliveryTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicIP(net.ParseIP(host)) {
+				return fmt.Errorf("%w '%s'", errForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: deliveryTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: deliveryTimeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w '%s'", errForbiddenAddress, req.URL)
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// isPublicIP returns false for loopback, private, link-local and other addresses that are not routed on the internet
+func isPublicIP(ip net.IP) bool {
+	if ip == nil || ip.IsUnspecified() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range forbiddenNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// send delivers the notification. It returns true if a failed delivery should be retried.
+func (d *delivery) send(client *http.Client) (bool, error) {
+	if u, err := url.Parse(d.url); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false, fmt.Errorf("%w '%s'", errForbiddenAddress, d.url)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, d.method, d.url, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range d.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("User-Agent", config.UserAgentString)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return !errors.Is(err, errForbiddenAddress), err
+	}
+	resp.Body.Close()
+
+	// anything other than OK, Created, Accepted, NoContent is treated as an error
+	if resp.StatusCode > http.StatusNoContent {
+		retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf(podops.MsgStatus, resp.StatusCode)
+	}
+	return false, nil
+}
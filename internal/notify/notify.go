@@ -0,0 +1,231 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/mmcdole/gofeed"
+	"gopkg.in/yaml.v3"
+
+	"github.com/txsvc/stdlib/v2/timestamp"
+
+	"github.com/podops/podops/config"
+	"github.com/podops/podops/internal/storage"
+)
+
+const (
+	// EventPublish is sent after a show's feed was updated
+	EventPublish = "publish"
+
+	// HeaderSignature is the HMAC-SHA256 signature of a payload, e.g. 'sha256=<hex>'
+	HeaderSignature = "X-Podops-Signature"
+	// HeaderEvent is the type of event the payload describes
+	HeaderEvent = "X-Podops-Event"
+)
+
+type (
+	// Subscription is a user-configured webhook that is called after publishing new episodes.
+	// If a secret is set, the payload is signed with it.
+	Subscription struct {
+		URL    string `json:"url" yaml:"url"`
+		Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+	}
+
+	// Event is the payload that is sent to subscriptions
+	Event struct {
+		Event     string    `json:"event"`
+		Parent    string    `json:"parent"`
+		Title     string    `json:"title"`
+		Feed      string    `json:"feed"`
+		Episodes  []Episode `json:"episodes"`
+		Timestamp int64     `json:"timestamp"`
+	}
+
+	// Episode describes a newly published episode
+	Episode struct {
+		GUID      string `json:"guid"`
+		Title     string `json:"title"`
+		Link      string `json:"link,omitempty"`
+		Published string `json:"published,omitempty"`
+		Enclosure string `json:"enclosure,omitempty"`
+	}
+)
+
+var (
+	published map[string]map[string]bool // parent -> GUIDs of the episodes known so far
+	mu        sync.Mutex                 // used to protect the above map
+)
+
+func init() {
+	published = make(map[string]map[string]bool)
+}
+
+// Prepare remembers the episodes in a show's current feed, unless they are known already.
+// Call Prepare before replacing the feed, so that Publish can tell the new episodes apart.
+func Prepare(ctx context.Context, parent string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := published[parent]; ok {
+		return
+	}
+
+	known := make(map[string]bool)
+	if feed, err := parseFeed(ctx, parent); err == nil {
+		for _, item := range feed.Items {
+			known[item.GUID] = true
+		}
+	}
+	published[parent] = known
+}
+
+// Publish notifies the WebSub hubs the feed advertises, Podping and the show's subscriptions after
+// its feed was updated. Notifications are delivered in the background.
+func Publish(ctx context.Context, parent string) error {
+	feed, err := parseFeed(ctx, parent)
+	if err != nil {
+		return err
+	}
+
+	event := Event{
+		Event:     EventPublish,
+		Parent:    parent,
+		Title:     feed.Title,
+		Feed:      feedURL(feed),
+		Episodes:  make([]Episode, 0),
+		Timestamp: timestamp.Now(),
+	}
+
+	mu.Lock()
+	known, ok := published[parent]
+	if !ok {
+		known = make(map[string]bool)
+		published[parent] = known
+	}
+	for _, item := range feed.Items {
+		if known[item.GUID] {
+			continue
+		}
+		known[item.GUID] = true
+
+		e := Episode{
+			GUID:      item.GUID,
+			Title:     item.Title,
+			Link:      item.Link,
+			Published: item.Published,
+		}
+		if len(item.Enclosures) > 0 {
+			e.Enclosure = item.Enclosures[0].URL
+		}
+		event.Episodes = append(event.Episodes, e)
+	}
+	mu.Unlock()
+
+	// the feed changed, tell the world
+	for _, hub := range hubLinks(feed) {
+		form := url.Values{}
+		form.Set("hub.mode", "publish")
+		form.Set("hub.url", event.Feed)
+
+		h := http.Header{}
+		h.Set("Content-Type", "application/x-www-form-urlencoded")
+		enqueue(http.MethodPost, hub, []byte(form.Encode()), h, false)
+	}
+	if config.PodpingEndpoint != "" {
+		q := url.Values{}
+		q.Set("url", event.Feed)
+		q.Set("reason", "update")
+		q.Set("medium", "podcast")
+
+		h := http.Header{}
+		h.Set("Authorization", config.PodpingToken)
+		enqueue(http.MethodGet, fmt.Sprintf("%s?%s", config.PodpingEndpoint, q.Encode()), nil, h, true)
+	}
+
+	// subscriptions are only interested in new episodes
+	if len(event.Episodes) == 0 {
+		return nil
+	}
+
+	subs, err := LoadSubscriptions(ctx, parent)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(&event)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range subs {
+		h := http.Header{}
+		h.Set("Content-Type", "application/json; charset=utf-8")
+		h.Set(HeaderEvent, EventPublish)
+		if s.Secret != "" {
+			h.Set(HeaderSignature, "sha256="+Sign([]byte(s.Secret), payload))
+		}
+		enqueue(http.MethodPost, s.URL, payload, h, false)
+	}
+
+	return nil
+}
+
+// LoadSubscriptions reads the subscriptions of a show from the storage
+func LoadSubscriptions(ctx context.Context, parent string) ([]Subscription, error) {
+	var subs []Subscription
+
+	data, err := storage.ReadFile(ctx, storage.Default(), storage.Key(parent, config.DefaultNotifyFileLocation))
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return subs, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// WriteSubscriptions replaces the subscriptions of a show in the storage
+func WriteSubscriptions(ctx context.Context, parent string, subs []Subscription) error {
+	data, err := yaml.Marshal(subs)
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(ctx, storage.Default(), storage.Key(parent, config.DefaultNotifyFileLocation), data)
+}
+
+func parseFeed(ctx context.Context, parent string) (*gofeed.Feed, error) {
+	file, err := storage.Default().Get(ctx, storage.Key(parent, config.DefaultFeedName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return gofeed.NewParser().Parse(file)
+}
+
+// hubLinks returns the WebSub hubs the feed advertises, see the show's hubLink
+func hubLinks(feed *gofeed.Feed) []string {
+	hubs := make([]string, 0)
+	for _, link := range feed.Extensions["atom"]["link"] {
+		if link.Attrs["rel"] == "hub" && link.Attrs["href"] != "" {
+			hubs = append(hubs, link.Attrs["href"])
+		}
+	}
+	return hubs
+}
+
+// feedURL returns the feed's self link or derives it from the show's link
+func feedURL(feed *gofeed.Feed) string {
+	if feed.FeedLink != "" {
+		return feed.FeedLink
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(feed.Link, "/"), config.DefaultFeedName)
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops/internal/storage"
+)
+
+const parent = "aaa94297acfc"
+
+func TestSign(t *testing.T) {
+	// RFC 4231, test case 2
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", Sign([]byte("Jefe"), []byte("what do ya want for nothing?")))
+}
+
+func TestFeedURL(t *testing.T) {
+	assert.Equal(t, "https://podops.dev/minimalpodcast/feed.xml", feedURL(&gofeed.Feed{Link: "https://podops.dev/minimalpodcast/"}))
+	assert.Equal(t, "https://example.com/rss", feedURL(&gofeed.Feed{Link: "https://podops.dev/minimalpodcast", FeedLink: "https://example.com/rss"}))
+}
+
+func TestHubLinks(t *testing.T) {
+	feed, err := gofeed.NewParser().ParseString(`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+	<atom:link href="https://podops.dev/minimalpodcast/feed.xml" rel="self" type="application/rss+xml"></atom:link>
+	<atom:link href="https://pubsubhubbub.appspot.com/" rel="hub"></atom:link>
+	</channel></rss>`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://pubsubhubbub.appspot.com/"}, hubLinks(feed))
+
+	assert.Empty(t, hubLinks(&gofeed.Feed{}))
+}
+
+func TestSubscriptions(t *testing.T) {
+	storage.SetDefault(storage.NewLocal(t.TempDir()))
+
+	subs, err := LoadSubscriptions(context.TODO(), parent)
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+
+	err = WriteSubscriptions(context.TODO(), parent, []Subscription{{URL: "https://example.com/hook", Secret: "secret"}})
+	assert.NoError(t, err)
+
+	subs, err = LoadSubscriptions(context.TODO(), parent)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(subs))
+	assert.Equal(t, "secret", subs[0].Secret)
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1", "::ffff:127.0.0.1"} {
+		assert.False(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "172.32.0.1", "2001:4860:4860::8888"} {
+		assert.True(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestSendForbidden(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	// users can not reach the internal network, not even after a lookup
+	for _, url := range []string{srv.URL, "http://localhost:1/hook", "file:///etc/passwd"} {
+		retry, err := (&delivery{method: http.MethodPost, url: url}).send(newClient())
+		assert.True(t, errors.Is(err, errForbiddenAddress), url)
+		assert.False(t, retry, url)
+	}
+	assert.False(t, called)
+
+	// the operator's endpoints can
+	_, err := (&delivery{method: http.MethodPost, url: srv.URL}).send(&http.Client{})
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestRequeueFull(t *testing.T) {
+	// a full queue drops the retry instead of blocking
+	full := make(chan *delivery, 1)
+	full <- &delivery{}
+
+	done := make(chan bool)
+	go func() {
+		requeue(full, &delivery{url: "https://example.com/hook", attempt: 1})
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("requeue blocked")
+	}
+	assert.Equal(t, 1, len(full))
+}
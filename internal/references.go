@@ -1,7 +1,9 @@
 package internal
 
 import (
+	"crypto/md5"
 	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 
@@ -42,3 +44,12 @@ func CreateSimpleToken() string {
 func CreateShortGUID(s string) string {
 	return id.Fingerprint(s)[:12]
 }
+
+// CreateContentETag calculates an etag based on the actual content of a file
+func CreateContentETag(r io.Reader) (string, error) {
+	hash := md5.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
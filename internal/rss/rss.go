@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"time"
 	"unicode/utf8"
@@ -75,11 +76,23 @@ func (p *Channel) AddAtomLink(href string) {
 	if len(href) == 0 {
 		return
 	}
-	p.AtomLink = &AtomLink{
+	p.AtomLinks = append(p.AtomLinks, &AtomLink{
 		HREF: href,
 		Rel:  "self",
 		Type: "application/rss+xml",
+	})
+}
+
+// AddHubLink adds a FQDN reference to a WebSub hub that
+// notifies subscribers when the feed is updated.
+func (p *Channel) AddHubLink(href string) {
+	if len(href) == 0 {
+		return
 	}
+	p.AtomLinks = append(p.AtomLinks, &AtomLink{
+		HREF: href,
+		Rel:  "hub",
+	})
 }
 
 // AddCategory adds the category to the podcast.
@@ -303,14 +316,29 @@ func (p *Channel) Encode(w io.Writer) error {
 	}
 
 	atomLink := ""
-	if p.AtomLink != nil {
+	if len(p.AtomLinks) > 0 {
 		atomLink = "http://www.w3.org/2005/Atom"
 	}
+	// the other namespaces are only declared if they are used
+	usesContent, usesPodcast := false, len(p.Podcast) > 0
+	for _, i := range p.Items {
+		usesContent = usesContent || i.ContentEncoded != nil
+		usesPodcast = usesPodcast || len(i.Podcast) > 0
+	}
+	contentNS, podcastNS := "", ""
+	if usesContent {
+		contentNS = "http://purl.org/rss/1.0/modules/content/"
+	}
+	if usesPodcast {
+		podcastNS = "https://podcastindex.org/namespace/1.0"
+	}
 	wrapped := channelWrapper{
-		ITUNESNS: "http://www.itunes.com/dtds/podcast-1.0.dtd",
-		ATOMNS:   atomLink,
-		Version:  "2.0",
-		Channel:  p,
+		ITUNESNS:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		ATOMNS:    atomLink,
+		CONTENTNS: contentNS,
+		PODCASTNS: podcastNS,
+		Version:   "2.0",
+		Channel:   p,
 	}
 	return p.encode(w, wrapped)
 }
@@ -374,6 +402,15 @@ func (i *Item) AddSummary(summary string) {
 	}
 }
 
+// AddContent adds the full text of the item as content:encoded.
+func (i *Item) AddContent(content string) {
+	if len(content) > 0 {
+		i.ContentEncoded = &ContentEncoded{
+			Text: content,
+		}
+	}
+}
+
 // AddDuration adds the duration to the iTunes duration field.
 func (i *Item) AddDuration(durationInSeconds int64) {
 	if durationInSeconds <= 0 {
@@ -382,6 +419,26 @@ func (i *Item) AddDuration(durationInSeconds int64) {
 	i.IDuration = internal.ParseDuration(durationInSeconds)
 }
 
+// NewExtension returns an element of another namespace, name includes the prefix, e.g. podcast:transcript.
+// The attributes are sorted by name.
+func NewExtension(name, value string, attrs map[string]string) *Extension {
+	e := Extension{
+		XMLName: xml.Name{Local: name},
+		Value:   value,
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e.Attrs = append(e.Attrs, xml.Attr{Name: xml.Name{Local: k}, Value: attrs[k]})
+	}
+
+	return &e
+}
+
 // String returns the MIME type encoding of the specified EnclosureType.
 func (et EnclosureType) String() string {
 	// https://help.apple.com/itc/podcasts_connect/#/itcb54353390
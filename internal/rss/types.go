@@ -29,7 +29,7 @@ type (
 		WebMaster      string   `xml:"webMaster,omitempty"`
 		Image          *Image
 		TextInput      *TextInput
-		AtomLink       *AtomLink
+		AtomLinks      []*AtomLink // e.g. self and hub
 
 		// https://help.apple.com/itc/podcasts_connect/#/itcb54353390
 		IAuthor     string `xml:"itunes:author,omitempty"`
@@ -47,17 +47,22 @@ type (
 		ITitle string `xml:"itunes:title,omitempty"`
 		IType  string `xml:"itunes:type,omitempty"`
 
+		// https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md
+		Podcast []*Extension
+
 		Items []*Item
 
 		encode func(w io.Writer, o interface{}) error
 	}
 
 	channelWrapper struct {
-		XMLName  xml.Name `xml:"rss"`
-		Version  string   `xml:"version,attr"`
-		ATOMNS   string   `xml:"xmlns:atom,attr,omitempty"`
-		ITUNESNS string   `xml:"xmlns:itunes,attr"`
-		Channel  *Channel
+		XMLName   xml.Name `xml:"rss"`
+		Version   string   `xml:"version,attr"`
+		ATOMNS    string   `xml:"xmlns:atom,attr,omitempty"`
+		ITUNESNS  string   `xml:"xmlns:itunes,attr"`
+		CONTENTNS string   `xml:"xmlns:content,attr,omitempty"`
+		PODCASTNS string   `xml:"xmlns:podcast,attr,omitempty"`
+		Channel   *Channel
 	}
 
 	// Item represents a single entry in a podcast.
@@ -92,6 +97,7 @@ type (
 		PubDate          *time.Time `xml:"-"`
 		PubDateFormatted string     `xml:"pubDate,omitempty"`
 		Enclosure        *Enclosure
+		ContentEncoded   *ContentEncoded
 
 		// https://help.apple.com/itc/podcasts_connect/#/itcb54353390
 		IAuthor   string `xml:"itunes:author,omitempty"`
@@ -106,6 +112,9 @@ type (
 		IEpisodeType string `xml:"itunes:episodeType,omitempty"`
 		IBlock       string `xml:"itunes:block,omitempty"`
 
+		// https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md
+		Podcast []*Extension
+
 		// REMOVE IIsClosedCaptioned string `xml:"itunes:isClosedCaptioned,omitempty"`
 		// REMOVE IOrder string `xml:"itunes:order,omitempty"`
 	}
@@ -115,7 +124,7 @@ type (
 		XMLName xml.Name `xml:"atom:link"`
 		HREF    string   `xml:"href,attr"`
 		Rel     string   `xml:"rel,attr"`
-		Type    string   `xml:"type,attr"`
+		Type    string   `xml:"type,attr,omitempty"`
 	}
 
 	// Image represents an image.
@@ -179,6 +188,23 @@ type (
 		Text    string   `xml:",cdata"`
 	}
 
+	// ContentEncoded is the full text of an item, content:encoded.
+	//
+	// This is rendered as CDATA which allows for HTML tags.
+	ContentEncoded struct {
+		XMLName xml.Name `xml:"content:encoded"`
+		Text    string   `xml:",cdata"`
+	}
+
+	// Extension is an element of another namespace, e.g. podcast:transcript.
+	// The namespace prefix is part of the name.
+	Extension struct {
+		XMLName  xml.Name
+		Attrs    []xml.Attr `xml:",any,attr"`
+		Value    string     `xml:",chardata"`
+		Children []*Extension
+	}
+
 	// EnclosureType specifies the type of the enclosure.
 	EnclosureType int
 
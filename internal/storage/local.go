@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type (
+	// Local keeps the objects as files below a root directory
+	Local struct {
+		root string
+	}
+
+	// sectionReadCloser closes the file behind a section of it
+	sectionReadCloser struct {
+		io.Reader
+		f *os.File
+	}
+)
+
+// NewLocal returns a storage backend that keeps its objects below root
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+// Root returns the root directory of the storage
+func (l *Local) Root() string {
+	return l.root
+}
+
+// Put writes the object to a temporary file first and then renames it,
+// so that readers never see a partially written object. Objects are only
+// readable by the server as they include the shows' credentials.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	path := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after the rename
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the object
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(l.path(key))
+}
+
+// GetRange opens the object and returns length bytes, starting at offset
+func (l *Local) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(l.path(key))
+	if err != nil {
+		return nil, err
+	}
+	return &sectionReadCloser{Reader: io.NewSectionReader(f, offset, length), f: f}, nil
+}
+
+// Stat returns the metadata of the object
+func (l *Local) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := os.Stat(l.path(key))
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "stat", Path: key, Err: fs.ErrNotExist}
+	}
+	return l.objectInfo(key, info), nil
+}
+
+// List walks the directory tree below the prefix
+func (l *Local) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	start := l.root
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		start = l.path(prefix[:i])
+	}
+
+	objects := make([]ObjectInfo, 0)
+	err := filepath.Walk(start, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, *l.objectInfo(key, info))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete removes the object and any directories that become empty
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path := l.path(key)
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+
+	for dir := filepath.Dir(path); dir != l.root && strings.HasPrefix(dir, l.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break // not empty
+		}
+	}
+	return nil
+}
+
+func (l *Local) path(key string) string {
+	// Clean with a leading '/' keeps keys like '../x' inside the root
+	return filepath.Join(l.root, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+func (l *Local) objectInfo(key string, info os.FileInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:         key,
+		Size:        info.Size(),
+		Modified:    info.ModTime(),
+		ContentType: contentType(key),
+	}
+}
+
+func (r *sectionReadCloser) Close() error {
+	return r.f.Close()
+}
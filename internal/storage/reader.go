@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+type (
+	// objectReader reads an object with range requests, so that e.g. http.ServeContent
+	// can serve partial content without downloading the whole object first
+	objectReader struct {
+		ctx    context.Context
+		s      Storage
+		key    string
+		size   int64
+		offset int64
+		body   io.ReadCloser
+	}
+)
+
+var errInvalidOffset = errors.New("invalid offset")
+
+// NewReadSeeker returns a reader for an object of the given size that supports seeking
+func NewReadSeeker(ctx context.Context, s Storage, key string, size int64) io.ReadSeekCloser {
+	return &objectReader{ctx: ctx, s: s, key: key, size: size}
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.s.GetRange(r.ctx, r.key, r.offset, r.size-r.offset)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *objectReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.offset + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, errInvalidOffset
+	}
+	if pos < 0 {
+		return 0, errInvalidOffset
+	}
+
+	if pos != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = pos
+	return pos, nil
+}
+
+func (r *objectReader) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/podops/podops"
+	"github.com/podops/podops/config"
+)
+
+const (
+	// BackendLocal stores the content in a directory on the local file system
+	BackendLocal = "local"
+	// BackendS3 stores the content in a bucket of an S3 compatible object storage
+	BackendS3 = "s3"
+
+	defaultContentType = "application/octet-stream"
+)
+
+type (
+	// Storage is a flat object store for the content of the CDN. Keys are slash separated
+	// paths relative to the root of the storage, e.g. a7c94297acfc/feed.xml. Missing objects
+	// are reported with an error that matches fs.ErrNotExist.
+	Storage interface {
+		// Put stores an object. size is -1 if it is not known in advance.
+		Put(ctx context.Context, key string, r io.Reader, size int64) error
+		// Get returns the content of an object
+		Get(ctx context.Context, key string) (io.ReadCloser, error)
+		// GetRange returns length bytes of an object, starting at offset
+		GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+		// Stat returns the metadata of an object
+		Stat(ctx context.Context, key string) (*ObjectInfo, error)
+		// List returns all objects whose key starts with prefix, ordered by key
+		List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+		// Delete removes an object
+		Delete(ctx context.Context, key string) error
+	}
+
+	// ObjectInfo describes an object in the storage
+	ObjectInfo struct {
+		Key         string
+		Size        int64
+		Modified    time.Time
+		ContentType string
+	}
+
+	// BackendFunc creates a storage backend from the configuration. location overrides
+	// the configured root directory or bucket, if it is not empty.
+	BackendFunc func(location string) (Storage, error)
+)
+
+var (
+	backends = map[string]BackendFunc{
+		BackendLocal: func(location string) (Storage, error) {
+			if location == "" {
+				location = config.StorageLocation
+			}
+			return NewLocal(location), nil
+		},
+		BackendS3: func(location string) (Storage, error) {
+			if location == "" {
+				location = config.S3Bucket
+			}
+			return NewS3(config.S3Endpoint, location, config.S3Region, config.S3AccessKey, config.S3SecretKey)
+		},
+	}
+
+	defaultStorage Storage
+	once           sync.Once
+	mu             sync.Mutex
+)
+
+// Open creates the storage backend with the given name. location is the root directory
+// or bucket of the storage, an empty location selects the one in the configuration.
+func Open(backend, location string) (Storage, error) {
+	if fn, ok := backends[backend]; ok {
+		return fn(location)
+	}
+	return nil, podops.ErrUnknownStorageBackend
+}
+
+// Default returns the storage backend selected by config.StorageBackend
+func Default() Storage {
+	once.Do(func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if defaultStorage != nil {
+			return // SetDefault was first
+		}
+		s, err := Open(config.StorageBackend, "")
+		if err != nil {
+			log.Fatalf("storage backend '%s': %v", config.StorageBackend, err)
+		}
+		defaultStorage = s
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	return defaultStorage
+}
+
+// SetDefault replaces the default storage backend, e.g. in tests
+func SetDefault(s Storage) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultStorage = s
+}
+
+// Key joins the parts of a key, e.g. Key(parent, "feed.xml")
+func Key(parts ...string) string {
+	return path.Join(parts...)
+}
+
+// IsNotExist returns true if err reports a missing object
+func IsNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// Exists returns true if the object exists
+func Exists(ctx context.Context, s Storage, key string) bool {
+	_, err := s.Stat(ctx, key)
+	return err == nil
+}
+
+// ReadFile returns the content of a (small) object
+func ReadFile(ctx context.Context, s Storage, key string) ([]byte, error) {
+	r, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// WriteFile stores a (small) object
+func WriteFile(ctx context.Context, s Storage, key string, data []byte) error {
+	return s.Put(ctx, key, bytes.NewReader(data), int64(len(data)))
+}
+
+// DeleteAll removes all objects whose key starts with prefix
+func DeleteAll(ctx context.Context, s Storage, prefix string) error {
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		if err := s.Delete(ctx, o.Key); err != nil && !IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentType guesses the media type of an object from its name
+func contentType(key string) string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return defaultContentType
+}
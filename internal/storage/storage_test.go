@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/podops/podops"
+)
+
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.TODO()
+
+	err := WriteFile(ctx, s, "aaa94297acfc/feed.xml", []byte("<rss></rss>"))
+	assert.NoError(t, err)
+	err = s.Put(ctx, "aaa94297acfc/e1a3c1d1a6d1.mp3", strings.NewReader("0123456789"), -1)
+	assert.NoError(t, err)
+	err = WriteFile(ctx, s, "bbb94297acfc/feed.xml", []byte("<rss></rss>"))
+	assert.NoError(t, err)
+
+	data, err := ReadFile(ctx, s, "aaa94297acfc/feed.xml")
+	assert.NoError(t, err)
+	assert.Equal(t, "<rss></rss>", string(data))
+
+	info, err := s.Stat(ctx, "aaa94297acfc/e1a3c1d1a6d1.mp3")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(10), info.Size)
+		assert.Equal(t, "audio/mpeg", info.ContentType)
+	}
+
+	r, err := s.GetRange(ctx, "aaa94297acfc/e1a3c1d1a6d1.mp3", 2, 3)
+	if assert.NoError(t, err) {
+		data, _ = io.ReadAll(r)
+		r.Close()
+		assert.Equal(t, "234", string(data))
+	}
+
+	rs := NewReadSeeker(ctx, s, "aaa94297acfc/e1a3c1d1a6d1.mp3", 10)
+	rs.Seek(-4, io.SeekEnd)
+	data, _ = io.ReadAll(rs)
+	rs.Close()
+	assert.Equal(t, "6789", string(data))
+
+	objects, err := s.List(ctx, "aaa94297acfc/")
+	assert.NoError(t, err)
+	if assert.Len(t, objects, 2) {
+		assert.Equal(t, "aaa94297acfc/e1a3c1d1a6d1.mp3", objects[0].Key)
+		assert.Equal(t, "aaa94297acfc/feed.xml", objects[1].Key)
+	}
+
+	err = DeleteAll(ctx, s, "aaa94297acfc/")
+	assert.NoError(t, err)
+	assert.False(t, Exists(ctx, s, "aaa94297acfc/feed.xml"))
+	assert.True(t, Exists(ctx, s, "bbb94297acfc/feed.xml"))
+
+	_, err = s.Get(ctx, "aaa94297acfc/feed.xml")
+	assert.True(t, IsNotExist(err))
+	_, err = s.Stat(ctx, "aaa94297acfc")
+	assert.True(t, IsNotExist(err))
+}
+
+func TestLocal(t *testing.T) {
+	testStorage(t, NewLocal(t.TempDir()))
+}
+
+func TestLocalKeysStayInRoot(t *testing.T) {
+	l := NewLocal("/data/storage")
+	assert.Equal(t, "/data/storage/etc/passwd", l.path("../../etc/passwd"))
+}
+
+func TestOpen(t *testing.T) {
+	s, err := Open(BackendLocal, "/data/other")
+	assert.NoError(t, err)
+	assert.Equal(t, "/data/other", s.(*Local).Root())
+
+	_, err = Open("unknown", "")
+	assert.Equal(t, podops.ErrUnknownStorageBackend, err)
+}
@@ -80,12 +80,13 @@ type (
 
 	// Metadata contains information describing a resource
 	Metadata struct {
-		Name   string            `json:"name" yaml:"name" binding:"required"`       // REQUIRED
-		GUID   string            `json:"guid" yaml:"guid" binding:"required"`       // REQUIRED
-		Parent string            `json:"parent,omitempty" yaml:"parent,omitempty" ` // OPTIONAL
-		Date   string            `json:"date,omitempty" yaml:"date,omitempty" `     // RECOMMENDED
-		Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`  // REQUIRED
-		Tags   string            `json:"tags,omitempty" yaml:"tags,omitempty" `     // OPTIONAL
+		Name     string            `json:"name" yaml:"name" binding:"required"`          // REQUIRED
+		GUID     string            `json:"guid" yaml:"guid" binding:"required"`          // REQUIRED
+		Parent   string            `json:"parent,omitempty" yaml:"parent,omitempty" `    // OPTIONAL
+		Date     string            `json:"date,omitempty" yaml:"date,omitempty" `        // RECOMMENDED
+		Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`     // REQUIRED
+		Tags     string            `json:"tags,omitempty" yaml:"tags,omitempty" `        // OPTIONAL
+		FeedGUID string            `json:"feedGuid,omitempty" yaml:"feedGuid,omitempty"` // OPTIONAL 'item.guid' of imported episodes, published instead of GUID
 	}
 
 	// GenericResource holds only the kind and metadata of a resource
@@ -104,17 +105,22 @@ type (
 		Image       AssetRef        `json:"image" yaml:"image" binding:"required"`
 		FeedLink    *AssetRef       `json:"feedLink,omitempty" yaml:"feedLink,omitempty"`       // OPTIONAL only used in imports
 		NewFeedLink *AssetRef       `json:"newFeedLink,omitempty" yaml:"newFeedLink,omitempty"` // OPTIONAL channel.itunes.new-feed-url -> move to label             // REQUIRED 'channel.itunes.image'
-		Episodes    EpisodeList     `json:"episodes,omitempty" yaml:"episodes,omitempty"`       // OPTIONAL used only to import a feed
+		HubLink     *AssetRef       `json:"hubLink,omitempty" yaml:"hubLink,omitempty"`         // OPTIONAL 'channel.atom:link rel=hub' the WebSub hub that is notified after publishing
+		Podcast     []PodcastTag    `json:"podcast,omitempty" yaml:"podcast,omitempty"`         // OPTIONAL 'channel.podcast.*'
+		Defaults    *Episode        `json:"defaults,omitempty" yaml:"defaults,omitempty"`       // OPTIONAL default values of all episodes
+		Episodes    EpisodeList     `json:"episodes,omitempty" yaml:"episodes,omitempty"`       // OPTIONAL episodes kept in the show's file
 	}
 
 	// Episode holds all metadata related to a podcast episode
 	Episode struct {
-		APIVersion  string             `json:"apiVersion" yaml:"apiVersion" binding:"required"`   // REQUIRED default: v1.0
-		Kind        string             `json:"kind" yaml:"kind" binding:"required"`               // REQUIRED default: episode
-		Metadata    Metadata           `json:"metadata" yaml:"metadata" binding:"required"`       // REQUIRED
-		Description EpisodeDescription `json:"description" yaml:"description" binding:"required"` // REQUIRED
-		Image       AssetRef           `json:"image" yaml:"image" binding:"required"`             // REQUIRED 'item.itunes.image'
-		Enclosure   AssetRef           `json:"enclosure" yaml:"enclosure" binding:"required"`     // REQUIRED
+		APIVersion          string             `json:"apiVersion" yaml:"apiVersion" binding:"required"`                    // REQUIRED default: v1.0
+		Kind                string             `json:"kind" yaml:"kind" binding:"required"`                                // REQUIRED default: episode
+		Metadata            Metadata           `json:"metadata" yaml:"metadata" binding:"required"`                        // REQUIRED
+		Description         EpisodeDescription `json:"description" yaml:"description" binding:"required"`                  // REQUIRED
+		Image               AssetRef           `json:"image" yaml:"image" binding:"required"`                              // REQUIRED 'item.itunes.image'
+		Enclosure           AssetRef           `json:"enclosure" yaml:"enclosure" binding:"required"`                      // REQUIRED
+		AlternateEnclosures []AssetRef         `json:"alternateEnclosures,omitempty" yaml:"alternateEnclosures,omitempty"` // OPTIONAL 'item.podcast.alternateEnclosure'
+		Podcast             []PodcastTag       `json:"podcast,omitempty" yaml:"podcast,omitempty"`                         // OPTIONAL 'item.podcast.*'
 	}
 
 	// EpisodeList holds the list of valid episodes that can be added to a podcast
@@ -138,6 +144,7 @@ type (
 		EpisodeText string   `json:"episodeText,omitempty" yaml:"episodeText,omitempty" binding:"required"` // REQUIRED 'item.itunes.summary'
 		Link        AssetRef `json:"link" yaml:"link"`                                                      // RECOMMENDED 'item.link'
 		Duration    int      `json:"duration" yaml:"duration" binding:"required"`                           // REQUIRED 'item.itunes.duration'
+		Author      string   `json:"author,omitempty" yaml:"author,omitempty"`                              // OPTIONAL 'item.itunes.author'
 	}
 
 	// Owner describes the owner of the show/podcast
@@ -152,6 +159,15 @@ type (
 		SubCategory []string `json:"subcategory" yaml:"subcategory,omitempty"` // OPTIONAL
 	}
 
+	// PodcastTag is an element of the Podcasting 2.0 namespace, e.g. podcast:transcript.
+	// See https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md
+	PodcastTag struct {
+		Name     string            `json:"name" yaml:"name" binding:"required"`          // REQUIRED e.g. 'transcript'
+		Value    string            `json:"value,omitempty" yaml:"value,omitempty"`       // OPTIONAL
+		Attrs    map[string]string `json:"attrs,omitempty" yaml:"attrs,omitempty"`       // OPTIONAL
+		Children []PodcastTag      `json:"children,omitempty" yaml:"children,omitempty"` // OPTIONAL
+	}
+
 	AssetRef struct {
 		URI       string `json:"uri" yaml:"uri" binding:"required"`              // REQUIRED
 		Rel       string `json:"rel" yaml:"rel" binding:"required"`              // REQUIRED